@@ -4,20 +4,20 @@ import (
 	"go-marathon-team-3/internal/app/cli-metrics"
 	"log"
 	"os"
-	"path/filepath"
-	"runtime"
+
+	// База часовых поясов для --timezone, если в системе ее нет (например, в Windows)
+	_ "time/tzdata"
 )
 
 func main() {
-	_, b, _, _ := runtime.Caller(0)
-	basepath := filepath.Dir(b)
-	for i := 0; i < 2; i++ {
-		basepath = filepath.Dir(basepath)
+	configDir, err := cli_metrics.DefaultConfigDir()
+	if err != nil {
+		// Каталог пользователя не определен (например, не задан $HOME) - храним настройки в текущем каталоге
+		configDir = "configs"
 	}
-	app := cli_metrics.CreateMetricsApp(&basepath)
-	err := app.Run(os.Args)
+	app := cli_metrics.CreateMetricsApp(&configDir)
+	err = app.Run(os.Args)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
-
@@ -0,0 +1,49 @@
+package cli_metrics
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/aggregate"
+	"math"
+)
+
+// aggregateColumns возвращает колонки вывода группировки: признаки, число коммитов и авторов,
+// статистика добавленных и удаленных строк
+func aggregateColumns(spec aggregate.Spec) []string {
+	columns := []string{}
+	for _, dimension := range spec.By {
+		columns = append(columns, string(dimension))
+	}
+	columns = append(columns, "commits", "authors")
+	for _, value := range []string{"added_rows", "deleted_rows"} {
+		columns = append(columns, value+"_sum", value+"_min", value+"_max", value+"_mean")
+		for _, p := range spec.Percentiles {
+			columns = append(columns, value+"_"+aggregate.PercentileName(p))
+		}
+	}
+	return columns
+}
+
+// renderAggregate выводит группы строками в порядке колонок aggregateColumns
+func renderAggregate(out renderer, res *aggregate.Result) error {
+	for _, group := range res.Groups {
+		row := []interface{}{}
+		for _, key := range group.Keys {
+			row = append(row, key)
+		}
+		row = append(row, group.Commits, group.Authors)
+		for _, stats := range []aggregate.Stats{group.AddedRows, group.DeletedRows} {
+			row = append(row, stats.Sum, stats.Min, stats.Max, round(stats.Mean))
+			for _, p := range stats.Percentiles {
+				row = append(row, round(p))
+			}
+		}
+		if err := out.Row(row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// round округляет дробные значения до сотых для вывода
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
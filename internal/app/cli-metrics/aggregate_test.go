@@ -0,0 +1,35 @@
+package cli_metrics
+
+import (
+	"bytes"
+	"go-marathon-team-3/pkg/tfsmetrics/aggregate"
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderAggregate(t *testing.T) {
+	date := time.Date(2021, 10, 6, 12, 0, 0, 0, time.UTC)
+	commits := []*repointerface.Commit{
+		{Author: "Ivan", AddedRows: 1, DeletedRows: 2, Date: date},
+		{Author: "Ivan", AddedRows: 2, Date: date},
+		{Author: "Petr", AddedRows: 10, Date: date.AddDate(0, 1, 0)},
+	}
+	spec := aggregate.Spec{By: []aggregate.Dimension{aggregate.Month}, Percentiles: []float64{50}, Location: time.UTC}
+	res, err := aggregate.Aggregate(spec, "prj", combinator.FromSlice(commits))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	out := newCsvRenderer(&buf)
+	require.NoError(t, out.Header(aggregateColumns(spec)...))
+	require.NoError(t, renderAggregate(out, res))
+	require.NoError(t, out.Flush())
+	assert.Equal(t, "month,commits,authors,added_rows_sum,added_rows_min,added_rows_max,added_rows_mean,added_rows_p50,"+
+		"deleted_rows_sum,deleted_rows_min,deleted_rows_max,deleted_rows_mean,deleted_rows_p50\n"+
+		"2021-10,2,1,3,1,2,1.5,1.5,2,0,2,1,1\n"+
+		"2021-11,1,1,10,10,10,10,10,0,0,0,0,0\n", buf.String())
+}
@@ -0,0 +1,43 @@
+package cli_metrics
+
+import (
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics"
+	"io"
+	"unicode/utf8"
+)
+
+// Ширина колонки с автором в текстовом выводе annotate
+const annotateAuthorWidth = 20
+
+func truncate(value string, width int) string {
+	if utf8.RuneCountInString(value) <= width {
+		return value
+	}
+	runes := []rune(value)
+	return string(runes[:width-1]) + "…"
+}
+
+func printAnnotation(w io.Writer, lines []tfsmetrics.AnnotatedLine) {
+	for _, line := range lines {
+		fmt.Fprintf(w, "%6d %-*s %s %5d| %s\n",
+			line.Version.ChangesetId,
+			annotateAuthorWidth, truncate(line.Version.Author, annotateAuthorWidth),
+			line.Version.Date.Format("2006-01-02"),
+			line.Number, line.Text)
+	}
+}
+
+func renderAnnotation(out renderer, lines []tfsmetrics.AnnotatedLine) error {
+	err := out.Header("line", "changeset", "author", "email", "date", "text")
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		err = out.Row(line.Number, line.Version.ChangesetId, line.Version.Author, line.Version.Email, line.Version.Date, line.Text)
+		if err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
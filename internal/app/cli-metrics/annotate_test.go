@@ -0,0 +1,35 @@
+package cli_metrics
+
+import (
+	"bytes"
+	"go-marathon-team-3/pkg/tfsmetrics"
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintAnnotation(t *testing.T) {
+	date := time.Date(2021, 11, 5, 10, 0, 0, 0, time.UTC)
+	first := &azure.ItemVersion{ChangesetId: 1, Author: "Ivan", Email: "ivan@mail.ru", Date: date}
+	second := &azure.ItemVersion{ChangesetId: 12, Author: "Очень длинное имя автора", Date: date}
+	lines := []tfsmetrics.AnnotatedLine{
+		{Number: 1, Text: "package main", Version: first},
+		{Number: 2, Text: "", Version: second},
+	}
+
+	buf := bytes.Buffer{}
+	printAnnotation(&buf, lines)
+	assert.Equal(t, ""+
+		"     1 Ivan                 2021-11-05     1| package main\n"+
+		"    12 Очень длинное имя а… 2021-11-05     2| \n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, renderAnnotation(newCsvRenderer(&buf), lines))
+	assert.Equal(t, ""+
+		"line,changeset,author,email,date,text\n"+
+		"1,1,Ivan,ivan@mail.ru,2021-11-05T10:00:00Z,package main\n"+
+		"2,12,Очень длинное имя автора,,2021-11-05T10:00:00Z,\n", buf.String())
+}
@@ -5,27 +5,72 @@ import (
 	"errors"
 	"fmt"
 	"go-marathon-team-3/pkg/tfsmetrics"
+	"go-marathon-team-3/pkg/tfsmetrics/aggregate"
 	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/exclude"
 	"go-marathon-team-3/pkg/tfsmetrics/exporter"
+	"go-marathon-team-3/pkg/tfsmetrics/filter"
+	"go-marathon-team-3/pkg/tfsmetrics/identity"
 	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
 	"go-marathon-team-3/pkg/tfsmetrics/store"
+	"go-marathon-team-3/pkg/tfsmetrics/worktime"
+	"net"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/urfave/cli/v2"
 
+	"io"
 	"io/ioutil"
 	"os"
-	"path"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 )
 
 type cliSettings struct {
-	CacheEnabled bool `json:"cache-enabled"`
-	ExporterPort int  `json:"exporter-port"`
+	ExporterPort int `json:"exporter-port"`
+	// Адрес, на котором экспортер принимает подключения (пустой - все интерфейсы)
+	ExporterAddress     string `json:"exporter-address"`
+	ExporterTLSCert     string `json:"exporter-tls-cert"`
+	ExporterTLSKey      string `json:"exporter-tls-key"`
+	ExporterClientCA    string `json:"exporter-client-ca"`
+	ExporterUsername    string `json:"exporter-username"`
+	ExporterPassword    string `json:"exporter-password"`
+	ExporterBearerToken string `json:"exporter-bearer-token"`
 }
 
-func CreateMetricsApp(prjPath *string) *cli.App {
+func (s *cliSettings) serverConfig() exporter.ServerConfig {
+	return exporter.ServerConfig{
+		CertFile:     s.ExporterTLSCert,
+		KeyFile:      s.ExporterTLSKey,
+		ClientCAFile: s.ExporterClientCA,
+		Username:     s.ExporterUsername,
+		Password:     s.ExporterPassword,
+		BearerToken:  s.ExporterBearerToken,
+	}
+}
+
+// exporterAddr возвращает адрес для запуска экспортера и адрес, по которому доступны метрики
+func (s *cliSettings) exporterAddr() (string, string) {
+	addr := net.JoinHostPort(s.ExporterAddress, strconv.Itoa(s.ExporterPort))
+	host := s.ExporterAddress
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	scheme := "http"
+	if s.ExporterTLSCert != "" {
+		scheme = "https"
+	}
+	return addr, fmt.Sprintf("%s://%s/metrics", scheme, net.JoinHostPort(host, strconv.Itoa(s.ExporterPort)))
+}
+
+// CreateMetricsApp создает CLI. Файлы настроек по умолчанию хранятся в каталоге configDir
+func CreateMetricsApp(configDir *string) *cli.App {
 	app := cli.NewApp()
 	app.Name = "cli-metrics"
 	app.Usage = "CLI для взаимодействия с библиотекой"
@@ -36,12 +81,134 @@ func CreateMetricsApp(prjPath *string) *cli.App {
 		{Name: "Артем Богданов"},
 		{Name: "Алексей Вологдин"},
 	}
-	settingsPath := path.Join(*prjPath, "configs/cli-settings.json")
-	settings, _ := ReadSettingsFile(&settingsPath)
-	localStore, _ := store.NewStore()
+	var configPath, settingsPath string
+	var authorFiles authorFiles
+	// Настройки экспортера с учетом переменных окружения
+	var settings *cliSettings
+	var localStore store.Store
+	var storeErr error
+	// Выбранный профиль подключения (activeProfile == nil, если профиль еще не создан)
+	var profileName string
+	var activeProfile *profile
+	var defaultProject string
 	var url, token, cache string
+	var tokenFile, tokenEnv string
+	var tokenStdin, encrypt bool
+	var authType, username string
+	var headers cli.StringSlice
 	var author, project string
 	var port int
+	var output string
+	var format string
+	var groupBy, percentiles string
+	var filters filterFlags
+	var parallel parallelFlags
+	var trend trendFlags
+	var work worktimeFlags
+	var limit int
+	var reverse bool
+	var suggest bool
+	var show showOptions
+	var noColor bool
+	var pushUrl, pushJob string
+	var pushRetries int
+	// Кеш используется, только если файл кеша удалось открыть (см. cacheAvailable)
+	useCache := func() bool {
+		return cacheAvailable(activeProfile, localStore, storeErr, os.Stderr)
+	}
+	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config",
+			Usage:       "файл профилей подключения (рядом с ним хранится cli-settings.json)",
+			EnvVars:     []string{envConfig},
+			Value:       filepath.Join(*configDir, "config.json"),
+			Destination: &configPath,
+		},
+		&cli.StringFlag{
+			Name:        "profile",
+			Aliases:     []string{"P"},
+			Usage:       "профиль подключения (по умолчанию текущий, см. cli-metrics config list)",
+			EnvVars:     []string{envProfile},
+			Destination: &profileName,
+		},
+		&cli.StringFlag{
+			Name:        "aliases",
+			Usage:       "файл псевдонимов авторов (по умолчанию aliases.json рядом с файлом профилей, см. cli-metrics authors --help)",
+			EnvVars:     []string{envAliases},
+			Destination: &authorFiles.aliases,
+		},
+		&cli.StringFlag{
+			Name:        "exclude-rules",
+			Usage:       "файл правил исключения коммитов ботов и служебных учетных записей (по умолчанию exclude.json рядом с файлом профилей, см. README)",
+			EnvVars:     []string{envExcludeRules},
+			Destination: &authorFiles.exclude,
+		},
+		&cli.StringFlag{
+			Name:        "roster",
+			Usage:       "файл состава команд в формате YAML или CSV (по умолчанию roster.yaml рядом с файлом профилей, см. README)",
+			EnvVars:     []string{envRoster},
+			Destination: &authorFiles.roster,
+		},
+	}
+	// Профиль выбирается до запуска команды: от него зависят параметры подключения и файл кеша
+	app.Before = func(c *cli.Context) error {
+		err := os.MkdirAll(filepath.Dir(configPath), 0700)
+		if err != nil {
+			return err
+		}
+		settingsPath = settingsFilePath(configPath)
+		if authorFiles.aliases == "" {
+			authorFiles.aliases = aliasesFilePath(configPath)
+		}
+		authorFiles.excludeRequired = authorFiles.exclude != ""
+		if authorFiles.exclude == "" {
+			authorFiles.exclude = excludeFilePath(configPath)
+		}
+		authorFiles.rosterRequired = authorFiles.roster != ""
+		if authorFiles.roster == "" {
+			authorFiles.roster = rosterFilePath(configPath)
+		}
+		settings, err = ReadSettingsFile(&settingsPath)
+		if err != nil {
+			return err
+		}
+		if err = settings.applyEnv(os.LookupEnv); err != nil {
+			return err
+		}
+		profiles, err := readProfiles(configPath)
+		if err != nil {
+			return err
+		}
+		profileName, activeProfile, _ = profiles.Get(profileName)
+		if err = validateProfileName(profileName); err != nil {
+			return err
+		}
+		// Параметры подключения можно задать одними переменными окружения, без сохраненного профиля
+		envProfile := activeProfile
+		if envProfile == nil {
+			envProfile = newProfile()
+		}
+		applied, err := envProfile.applyEnv(os.LookupEnv)
+		if err != nil {
+			return err
+		}
+		if applied {
+			activeProfile = envProfile
+		}
+		// Для еще не созданного профиля (кроме профиля по умолчанию) файл кеша не заводим
+		// Кеш хранится рядом с файлом профилей, а не в текущем каталоге
+		if activeProfile != nil || profileName == defaultProfile {
+			cacheDir := filepath.Dir(configPath)
+			moved, err := migrateCacheFile(cacheDir, namespace(profileName))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Внимание: не удалось перенести файл кеша в %s: %v\n", cacheDir, err)
+			} else if moved != "" {
+				fmt.Fprintf(os.Stderr, "Файл кеша %s перенесен в %s\n", moved, cacheDir)
+			}
+			localStore, storeErr = store.NewNamespacedStore(cacheDir, namespace(profileName))
+		}
+		return nil
+	}
 	app.Commands = []*cli.Command{
 		{
 			Name:    "config",
@@ -60,6 +227,73 @@ func CreateMetricsApp(prjPath *string) *cli.App {
 					Usage:       "personal access token для подключения к Azure",
 					Destination: &token,
 				},
+				&cli.StringFlag{
+					Name:        "token-file",
+					Usage:       "прочитать personal access token из файла",
+					Destination: &tokenFile,
+				},
+				&cli.StringFlag{
+					Name:        "token-env",
+					Usage:       "прочитать personal access token из переменной окружения с заданным именем",
+					Destination: &tokenEnv,
+				},
+				&cli.BoolFlag{
+					Name:        "token-stdin",
+					Usage:       "прочитать personal access token из stdin (токен не попадет в историю команд)",
+					Destination: &tokenStdin,
+				},
+				&cli.StringFlag{
+					Name:        "auth",
+					Usage:       "способ аутентификации: pat (по умолчанию), basic, bearer, none",
+					Destination: &authType,
+				},
+				&cli.StringFlag{
+					Name:        "username",
+					Usage:       "имя пользователя для basic-аутентификации (пароль задается как токен)",
+					Destination: &username,
+				},
+				&cli.StringSliceFlag{
+					Name:        "header",
+					Usage:       "дополнительный заголовок запросов в виде 'Name: value' (можно указать несколько раз, пустое значение удаляет заголовки)",
+					Destination: &headers,
+				},
+				&cli.StringFlag{
+					Name:  "proxy",
+					Usage: "адрес HTTP-прокси для подключения к серверу (по умолчанию из HTTP_PROXY/HTTPS_PROXY)",
+				},
+				&cli.StringFlag{
+					Name:  "ca-file",
+					Usage: "файл с дополнительными сертификатами CA в формате PEM (например, корпоративного CA)",
+				},
+				&cli.StringFlag{
+					Name:  "client-cert",
+					Usage: "файл клиентского сертификата в формате PEM",
+				},
+				&cli.StringFlag{
+					Name:  "client-key",
+					Usage: "файл ключа клиентского сертификата в формате PEM",
+				},
+				&cli.BoolFlag{
+					Name:  "insecure-skip-verify",
+					Usage: "отключить проверку сертификата сервера (небезопасно, только для отладки)",
+				},
+				&cli.IntFlag{
+					Name:  "max-idle-conns",
+					Usage: "максимальное число простаивающих соединений (0 - по умолчанию)",
+				},
+				&cli.IntFlag{
+					Name:  "max-idle-conns-per-host",
+					Usage: "максимальное число простаивающих соединений с сервером (0 - по умолчанию)",
+				},
+				&cli.IntFlag{
+					Name:  "max-conns-per-host",
+					Usage: "максимальное число одновременных соединений с сервером (0 - без ограничений)",
+				},
+				&cli.BoolFlag{
+					Name:        "encrypt",
+					Usage:       "хранить токен зашифрованным паролем (пароль берется из " + envPassphrase + " или запрашивается)",
+					Destination: &encrypt,
+				},
 				&cli.StringFlag{
 					Name:        "cache-enabled",
 					Aliases:     []string{"cache", "c"},
@@ -73,202 +307,1081 @@ func CreateMetricsApp(prjPath *string) *cli.App {
 					Value:       8080,
 					Destination: &port,
 				},
+				&cli.StringFlag{
+					Name:        "default-project",
+					Usage:       "проект, который используется командами, если проект не указан",
+					Destination: &defaultProject,
+				},
+				&cli.StringFlag{
+					Name:    "exporter-address",
+					Aliases: []string{"address"},
+					Usage:   "адрес, на котором экспортер принимает подключения (по умолчанию все интерфейсы)",
+				},
+				&cli.StringFlag{
+					Name:  "tls-cert",
+					Usage: "файл сертификата для запуска экспортера по HTTPS",
+				},
+				&cli.StringFlag{
+					Name:  "tls-key",
+					Usage: "файл закрытого ключа для запуска экспортера по HTTPS",
+				},
+				&cli.StringFlag{
+					Name:  "tls-client-ca",
+					Usage: "файл сертификатов CA для проверки клиентских сертификатов (mutual TLS)",
+				},
+				&cli.StringFlag{
+					Name:  "metrics-user",
+					Usage: "имя пользователя для basic auth на /metrics",
+				},
+				&cli.StringFlag{
+					Name:  "metrics-password",
+					Usage: "пароль для basic auth на /metrics",
+				},
+				&cli.StringFlag{
+					Name:  "metrics-token",
+					Usage: "bearer-токен для доступа к /metrics",
+				},
+			},
+			Subcommands: []*cli.Command{
+				{
+					Name:  "list",
+					Usage: "вывод списка профилей подключения",
+					Action: func(c *cli.Context) error {
+						profiles, err := readProfiles(configPath)
+						if err != nil {
+							return err
+						}
+						if len(profiles.Profiles) == 0 {
+							fmt.Println("Профили не созданы (cli-metrics config --url URL --token TOKEN)")
+							return nil
+						}
+						for _, name := range profiles.Names() {
+							mark := " "
+							if name == profiles.Current {
+								mark = "*"
+							}
+							fmt.Printf("%s %-15s %s\n", mark, name, profiles.Profiles[name].OrganizationUrl)
+						}
+						return nil
+					},
+				},
+				{
+					Name:      "use",
+					Usage:     "выбор текущего профиля подключения",
+					ArgsUsage: "<profile>",
+					Action: func(c *cli.Context) error {
+						name := c.Args().Get(0)
+						if name == "" {
+							return errors.New("укажите название профиля (cli-metrics config use <profile>)")
+						}
+						profiles, err := readProfiles(configPath)
+						if err != nil {
+							return err
+						}
+						if err = profiles.Use(name); err != nil {
+							return err
+						}
+						err = writeProfiles(configPath, profiles)
+						if err != nil {
+							return err
+						}
+						fmt.Printf("Текущий профиль: %s\n", name)
+						return nil
+					},
+				},
+				{
+					Name:      "remove",
+					Usage:     "удаление профиля подключения (кеш профиля не удаляется)",
+					ArgsUsage: "<profile>",
+					Action: func(c *cli.Context) error {
+						name := c.Args().Get(0)
+						if name == "" {
+							return errors.New("укажите название профиля (cli-metrics config remove <profile>)")
+						}
+						profiles, err := readProfiles(configPath)
+						if err != nil {
+							return err
+						}
+						if err = profiles.Remove(name); err != nil {
+							return err
+						}
+						err = writeProfiles(configPath, profiles)
+						if err != nil {
+							return err
+						}
+						fmt.Printf("Профиль %s удален, текущий профиль: %s\n", name, profiles.Current)
+						return nil
+					},
+				},
+			},
+			Action: func(c *cli.Context) error {
+				profiles, err := readProfiles(configPath)
+				if err != nil {
+					return err
+				}
+				first := len(profiles.Profiles) == 0
+				name, config, err := profiles.GetOrCreate(profileName)
+				if err != nil {
+					return err
+				}
+				// Первый созданный профиль становится текущим
+				if first {
+					profiles.Current = name
+				}
+				if url != "" {
+					config.OrganizationUrl = url
+				}
+				source := tokenSource{value: token, file: tokenFile, env: tokenEnv, stdin: tokenStdin}
+				newToken, err := source.read()
+				if err != nil {
+					return err
+				}
+				if newToken != "" {
+					config.Token = newToken
+					config.EncryptedToken = ""
+				}
+				if encrypt && config.Token != "" {
+					passphrase, err := readPassphrase()
+					if err != nil {
+						return err
+					}
+					config.EncryptedToken, err = encryptToken(config.Token, passphrase)
+					if err != nil {
+						return err
+					}
+					config.Token = ""
+				} else if encrypt && config.EncryptedToken == "" {
+					return errors.New("нет токена для шифрования (cli-metrics config --token)")
+				}
+				if c.IsSet("auth") {
+					if err = validateAuthType(authType); err != nil {
+						return err
+					}
+					config.AuthType = azure.AuthType(authType)
+				}
+				if c.IsSet("username") {
+					config.Username = username
+				}
+				if c.IsSet("header") {
+					config.Headers, err = parseHeaders(headers.Value())
+					if err != nil {
+						return err
+					}
+				}
+				for flag, setting := range map[string]*string{
+					"proxy":       &config.Transport.ProxyUrl,
+					"ca-file":     &config.Transport.CAFile,
+					"client-cert": &config.Transport.CertFile,
+					"client-key":  &config.Transport.KeyFile,
+				} {
+					if c.IsSet(flag) {
+						*setting = c.String(flag)
+					}
+				}
+				for flag, setting := range map[string]*int{
+					"max-idle-conns":          &config.Transport.MaxIdleConns,
+					"max-idle-conns-per-host": &config.Transport.MaxIdleConnsPerHost,
+					"max-conns-per-host":      &config.Transport.MaxConnsPerHost,
+				} {
+					if c.IsSet(flag) {
+						*setting = c.Int(flag)
+					}
+				}
+				if c.IsSet("insecure-skip-verify") {
+					config.Transport.InsecureSkipVerify = c.Bool("insecure-skip-verify")
+					if config.Transport.InsecureSkipVerify {
+						fmt.Fprintln(os.Stderr, "ВНИМАНИЕ: проверка сертификата сервера отключена, соединение не защищено от перехвата!")
+					}
+				}
+				if c.IsSet("default-project") {
+					config.Project = defaultProject
+				}
+				if cache == "true" {
+					config.CacheEnabled = true
+				} else if cache != "" {
+					config.CacheEnabled = false
+				}
+				// В файл записываются сохраненные настройки, без переопределений из окружения
+				fileSettings, err := ReadSettingsFile(&settingsPath)
+				if err != nil {
+					return err
+				}
+				if port != fileSettings.ExporterPort {
+					if port < 1024 || port > 65535 {
+						return errors.New("Введите порт в диапазоне от 1024 до 65535!")
+					} else {
+						fileSettings.ExporterPort = port
+					}
+				}
+				for flag, setting := range map[string]*string{
+					"exporter-address": &fileSettings.ExporterAddress,
+					"tls-cert":         &fileSettings.ExporterTLSCert,
+					"tls-key":          &fileSettings.ExporterTLSKey,
+					"tls-client-ca":    &fileSettings.ExporterClientCA,
+					"metrics-user":     &fileSettings.ExporterUsername,
+					"metrics-password": &fileSettings.ExporterPassword,
+					"metrics-token":    &fileSettings.ExporterBearerToken,
+				} {
+					if c.IsSet(flag) {
+						*setting = c.String(flag)
+					}
+				}
+				err = writeProfiles(configPath, profiles)
+				if err != nil {
+					return err
+				}
+				err = WriteSettingsFile(&settingsPath, fileSettings)
+				fmt.Printf("Текущая конфигурация:\nProfile: %s\nURL: %s\nAuth: %s\nToken: %s\nProject: %s\nCacheEnabled: %t\nExporterPort: %d\n",
+					name, config.OrganizationUrl, config.authDescription(), config.displayToken(), config.Project, config.CacheEnabled, fileSettings.ExporterPort)
+				fmt.Printf("ExporterAddress: %s\nTLS: %t\nClientCA: %s\nBasicAuth: %t\nBearerToken: %t\n",
+					fileSettings.ExporterAddress, fileSettings.ExporterTLSCert != "", fileSettings.ExporterClientCA,
+					fileSettings.ExporterUsername != "", fileSettings.ExporterBearerToken != "")
+				fmt.Printf("Proxy: %s\nCAFile: %s\nClientCert: %s\nInsecureSkipVerify: %t\n",
+					config.Transport.ProxyUrl, config.Transport.CAFile, config.Transport.CertFile, config.Transport.InsecureSkipVerify)
+				return err
+			},
+		},
+		{
+			Name:  "doctor",
+			Usage: "проверка настроек, доступности сервера, аутентификации, прав токена, кеша и порта экспортера",
+			Action: func(c *cli.Context) error {
+				results := runDoctor(&doctorInput{
+					profileName: profileName,
+					profile:     activeProfile,
+					settings:    settings,
+					localStore:  localStore,
+					storeErr:    storeErr,
+				})
+				failures := printChecklist(os.Stdout, results)
+				if failures > 0 {
+					return fmt.Errorf("обнаружено проблем: %d", failures)
+				}
+				fmt.Println("Проблем не обнаружено")
+				return nil
+			},
+		},
+		{
+			Name:    "getmetrics",
+			Aliases: []string{"gm"},
+			Usage:   "вывод на экран данных метрики по конкретному автору или по проекту",
+			Flags: append([]cli.Flag{
+				&cli.StringFlag{
+					Name:        "author",
+					Aliases:     []string{"a"},
+					Usage:       "данные метрики по конкретному автору",
+					Destination: &author,
+				},
+				&cli.StringFlag{
+					Name:        "project",
+					Aliases:     []string{"p"},
+					Usage:       "данные метрики по конкретному проекту",
+					Destination: &project,
+				},
+				&cli.StringFlag{
+					Name: "group-by",
+					Usage: "группировка данных по проекту: author или team (по составу команд, см. --roster), " +
+						"либо признаки через запятую для таблицы со статистикой строк: " + aggregate.DimensionNames(),
+					Value:       groupByAuthor,
+					Destination: &groupBy,
+				},
+				&cli.StringFlag{
+					Name:        "percentiles",
+					Usage:       "процентили строк для группировки по признакам, например 50,90,99",
+					Value:       "50,90",
+					Destination: &percentiles,
+				},
+				newFormatFlag(&format),
+			}, append(filters.flags(false), parallel.flags()...)...),
+			Action: func(c *cli.Context) error {
+				project = activeProfile.projectOrDefault(project)
+				if author == "" && project == "" {
+					return errors.New("Пожалуйста, укажите автора или название проекта.")
+				}
+				// Кроме группировок author и team, --group-by задает признаки таблицы со статистикой (см. пакет aggregate)
+				var spec aggregate.Spec
+				if groupBy != groupByAuthor && groupBy != groupByTeam {
+					dimensions, err := aggregate.ParseDimensions(groupBy)
+					if err != nil {
+						return err
+					}
+					spec.By = dimensions
+					spec.Percentiles, err = aggregate.ParsePercentiles(percentiles)
+					if err != nil {
+						return err
+					}
+				}
+				if groupBy == groupByTeam && project == "" {
+					return errors.New("группировка по командам доступна только для данных по проекту (--project)")
+				}
+				out, err := newRenderer(format, os.Stdout)
+				if err != nil {
+					return err
+				}
+				filterOptions, err := filters.options()
+				if err != nil {
+					return err
+				}
+				if len(spec.By) > 0 {
+					if out == nil {
+						out = newTableRenderer(os.Stdout)
+					}
+					err = out.Header(aggregateColumns(spec)...)
+				} else if out != nil && groupBy == groupByTeam {
+					err = out.Header("project", "team", "authors", "commits", "added_rows", "deleted_rows")
+				} else if out != nil {
+					err = out.Header("project", "author", "commits", "added_rows", "deleted_rows")
+				}
+				if err != nil {
+					return err
+				}
+				authors, err := authorFiles.load(filters.noExclude)
+				if err != nil {
+					return err
+				}
+				azureClient, err := connect(profileName, activeProfile)
+				if err != nil {
+					return err
+				}
+				exp := exporter.NewExporter()
+				loader := &projectLoader{azure: azureClient, cache: useCache(), store: localStore, authors: authors, opts: &parallel}
+				opts := &logOptions{filter: filterOptions}
+				if len(spec.By) > 0 {
+					projectNames := []string{project}
+					if project == "" {
+						projectNames, err = listProjects(azureClient)
+						if err != nil {
+							return err
+						}
+					}
+					aggregator := aggregate.New(spec)
+					errs := runProjects(projectNames, &parallel, func(prj string) (projectResult, error) {
+						return loader.load(prj, opts, func(iter repointerface.CommitIterator) error {
+							if author != "" {
+								iter = combinator.Filter(iter, func(commit *repointerface.Commit) bool {
+									return commit.Author == author
+								})
+							}
+							return aggregator.Add(prj, iter)
+						})
+					})
+					if err = renderAggregate(out, aggregator.Result()); err != nil {
+						return err
+					}
+					if err = out.Flush(); err != nil {
+						return err
+					}
+					authors.printExcluded(os.Stderr, filters.reportExcluded)
+					return errs.report(os.Stderr)
+				}
+				errs := &projectErrors{}
+				if project != "" {
+					errs.merge(runProjects([]string{project}, &parallel, func(prj string) (projectResult, error) {
+						return loader.load(prj, opts, func(iter repointerface.CommitIterator) error {
+							// Коммиты уже загружены, поэтому ошибки здесь быть не может
+							if groupBy == groupByTeam {
+								data, _ := exp.GetDataByTeam(iter)
+								if out != nil {
+									return renderByTeam(out, prj, data)
+								}
+								fmt.Printf("Данные метрики по командам проекта '%s':\n", prj)
+								printByTeam(os.Stdout, data)
+								fmt.Println()
+								return nil
+							}
+							data, _ := exp.GetDataByProject(iter)
+							if out != nil {
+								return renderByProject(out, prj, data)
+							}
+							fmt.Printf("Данные метрики по проекту '%s':\n", prj)
+							printByProject(&data)
+							fmt.Println()
+							return nil
+						})
+					}))
+				}
+				if author != "" {
+					data := make(map[string]*exporter.ByAuthor)
+					projectNames, err := listProjects(azureClient)
+					if err != nil {
+						return err
+					}
+					errs.merge(runProjects(projectNames, &parallel, func(prj string) (projectResult, error) {
+						return loader.load(prj, opts, func(iter repointerface.CommitIterator) error {
+							if byAuthor, _ := exp.GetDataByAuthor(iter, author); byAuthor != nil {
+								data[prj] = byAuthor
+							}
+							return nil
+						})
+					}))
+					if out != nil {
+						err = renderByAuthor(out, author, data)
+						if err != nil {
+							return err
+						}
+					} else {
+						fmt.Printf("Данные метрики по автору '%s':\n", author)
+						printByAuthor(&data)
+						fmt.Println()
+					}
+				}
+				if out != nil {
+					err = out.Flush()
+					if err != nil {
+						return err
+					}
+				}
+				authors.printExcluded(os.Stderr, filters.reportExcluded)
+				return errs.report(os.Stderr)
+			},
+		},
+		{
+			Name:      "trend",
+			Usage:     "вывод коммитов и измененных строк по дням, неделям или месяцам",
+			ArgsUsage: "[ProjectName...]",
+			Description: "Раскладывает коммиты проектов (без аргументов - всех проектов) по периодам в заданном часовом поясе.\n" +
+				"Периоды без коммитов выводятся с нулевыми значениями. Текстовый вывод рисует график значения --metric,\n" +
+				"а --format table, csv и другие форматы выводят строку на каждый период, например:\n\n" +
+				"   cli-metrics trend --period week --last 52 --by team --format csv",
+			Flags: append(append([]cli.Flag{
+				newFormatFlag(&format),
+			}, trend.flags()...), append(filters.flags(true), parallel.flags()...)...),
+			Action: func(context *cli.Context) error {
+				out, err := newRenderer(format, os.Stdout)
+				if err != nil {
+					return err
+				}
+				filterOptions, err := filters.options()
+				if err != nil {
+					return err
+				}
+				spec, err := trend.spec(filterOptions, time.Now())
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					if err = out.Header(trendColumns(spec)...); err != nil {
+						return err
+					}
+				}
+				authors, err := authorFiles.load(filters.noExclude)
+				if err != nil {
+					return err
+				}
+				azureClient, err := connect(profileName, activeProfile)
+				if err != nil {
+					return err
+				}
+				projectNames := context.Args().Slice()
+				if len(projectNames) == 0 {
+					projectNames, err = listProjects(azureClient)
+					if err != nil {
+						return err
+					}
+				}
+				series := aggregate.NewTrend(spec)
+				loader := &projectLoader{azure: azureClient, cache: useCache(), store: localStore, authors: authors, opts: &parallel}
+				opts := &logOptions{filter: filterOptions}
+				errs := runProjects(projectNames, &parallel, func(project string) (projectResult, error) {
+					return loader.load(project, opts, func(iter repointerface.CommitIterator) error {
+						return series.Add(project, iter)
+					})
+				})
+				if out != nil {
+					if err = renderTrend(out, series.Result()); err != nil {
+						return err
+					}
+					if err = out.Flush(); err != nil {
+						return err
+					}
+				} else {
+					printTrend(os.Stdout, series.Result(), trend.metric)
+				}
+				authors.printExcluded(os.Stderr, filters.reportExcluded)
+				return errs.report(os.Stderr)
+			},
+		},
+		{
+			Name:      "worktime",
+			Usage:     "тепловая карта коммитов по дням недели и часам и доля коммитов вне рабочего времени",
+			ArgsUsage: "[ProjectName...]",
+			Description: "Строит для каждого автора (команды с --by team) карту коммитов по дням недели и часам в часовом поясе\n" +
+				"рабочего календаря и отмечает тех, у кого доля коммитов вне рабочего времени больше --threshold.\n" +
+				"Рабочий календарь задается файлом (--calendar, по умолчанию calendar.yaml рядом с файлом профилей):\n\n" +
+				"   timezone: Europe/Moscow\n" +
+				"   weekdays: [mon, tue, wed, thu, fri]\n" +
+				"   hours: 09-18\n" +
+				"   holidays: [2021-11-04, 2021-12-31]\n" +
+				"   workdays: [2021-02-20]\n\n" +
+				"Без файла рабочее время - с понедельника по пятницу с 9 до 18 в локальном часовом поясе.",
+			Flags: append(append([]cli.Flag{
+				newFormatFlag(&format),
+			}, work.flags()...), append(filters.flags(true), parallel.flags()...)...),
+			Action: func(context *cli.Context) error {
+				out, err := newRenderer(format, os.Stdout)
+				if err != nil {
+					return err
+				}
+				filterOptions, err := filters.options()
+				if err != nil {
+					return err
+				}
+				spec, err := work.spec(configPath)
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					if err = out.Header(worktimeColumns(spec, work.summary)...); err != nil {
+						return err
+					}
+				}
+				authors, err := authorFiles.load(filters.noExclude)
+				if err != nil {
+					return err
+				}
+				azureClient, err := connect(profileName, activeProfile)
+				if err != nil {
+					return err
+				}
+				projectNames := context.Args().Slice()
+				if len(projectNames) == 0 {
+					projectNames, err = listProjects(azureClient)
+					if err != nil {
+						return err
+					}
+				}
+				analyzer := worktime.NewAnalyzer(spec)
+				loader := &projectLoader{azure: azureClient, cache: useCache(), store: localStore, authors: authors, opts: &parallel}
+				opts := &logOptions{filter: filterOptions}
+				errs := runProjects(projectNames, &parallel, func(project string) (projectResult, error) {
+					return loader.load(project, opts, func(iter repointerface.CommitIterator) error {
+						return analyzer.Add(project, iter)
+					})
+				})
+				if out != nil {
+					if err = renderWorktime(out, analyzer.Result(), &work); err != nil {
+						return err
+					}
+					if err = out.Flush(); err != nil {
+						return err
+					}
+				} else {
+					printWorktime(os.Stdout, spec, analyzer.Result(), &work)
+				}
+				authors.printExcluded(os.Stderr, filters.reportExcluded)
+				return errs.report(os.Stderr)
+			},
+		},
+		{
+			Name:    "list",
+			Aliases: []string{"ls"},
+			Usage:   "вывод на экран названий всех проектов в репозитории",
+			Flags: []cli.Flag{
+				newFormatFlag(&format),
+			},
+			Action: func(context *cli.Context) error {
+				out, err := newRenderer(format, os.Stdout)
+				if err != nil {
+					return err
+				}
+				azureClient, err := connect(profileName, activeProfile)
+				if err != nil {
+					return err
+				}
+				projectNames, err := azureClient.ListOfProjects()
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					err = out.Header("project")
+					if err != nil {
+						return err
+					}
+					for _, project := range projectNames {
+						err = out.Row(*project)
+						if err != nil {
+							return err
+						}
+					}
+					return out.Flush()
+				}
+				fmt.Println("Доступны следующие проекты:")
+				for ind, project := range projectNames {
+					fmt.Printf("%d) %s\n", ind+1, *project)
+				}
+				return nil
+			},
+		},
+		{
+			Name:    "log",
+			Aliases: []string{"l"},
+			Usage:   "получение информации обо всех коммитах",
+			Flags: append([]cli.Flag{
+				newFormatFlag(&format),
+				&cli.IntFlag{
+					Name:        "limit",
+					Aliases:     []string{"n"},
+					Usage:       "вывести не больше заданного числа коммитов по каждому проекту",
+					Destination: &limit,
+				},
+				&cli.BoolFlag{
+					Name:        "reverse",
+					Usage:       "вывести коммиты каждого проекта в обратном порядке",
+					Destination: &reverse,
+				},
+			}, append(filters.flags(true), parallel.flags()...)...),
+			Action: func(context *cli.Context) error {
+				out, err := newRenderer(format, os.Stdout)
+				if err != nil {
+					return err
+				}
+				filterOptions, err := filters.options()
+				if err != nil {
+					return err
+				}
+				opts := &logOptions{filter: filterOptions, limit: limit, reverse: reverse}
+				prjName := activeProfile.projectOrDefault(context.Args().Get(0))
+				authors, err := authorFiles.load(filters.noExclude)
+				if err != nil {
+					return err
+				}
+				azureClient, err := connect(profileName, activeProfile)
+				if err != nil {
+					return err
+				}
+				projectNames, err := listProjects(azureClient)
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					err = out.Header("project", "id", "author", "email", "date", "added_rows", "deleted_rows", "message")
+					if err != nil {
+						return err
+					}
+				}
+				if prjName == "" {
+					if out == nil {
+						fmt.Println("Название проекта не было указано, информация по коммитам будет выведена по всем проектам:")
+					}
+				} else {
+					selected := []string{}
+					for _, project := range projectNames {
+						if project == prjName {
+							selected = append(selected, project)
+						}
+					}
+					projectNames = selected
+				}
+				loader := &projectLoader{azure: azureClient, cache: useCache(), store: localStore, authors: authors, opts: &parallel}
+				errs := runProjects(projectNames, &parallel, func(project string) (projectResult, error) {
+					return loader.load(project, opts, func(iter repointerface.CommitIterator) error {
+						return printProject(project, iter, out)
+					})
+				})
+				if out != nil {
+					err = out.Flush()
+					if err != nil {
+						return err
+					}
+				}
+				authors.printExcluded(os.Stderr, filters.reportExcluded)
+				return errs.report(os.Stderr)
 			},
-			Action: func(c *cli.Context) error {
-				configPath := path.Join(*prjPath, "configs/config.json")
-				config, err := ReadConfigFile(&configPath)
+		},
+		{
+			Name:      "authors",
+			Usage:     "вывод авторов коммитов с учетом псевдонимов и предложения объединить учетные записи одного человека",
+			ArgsUsage: "[ProjectName...]",
+			Description: "Коммиты одного автора определяются по идентификатору учетной записи, поэтому переименование учетной записи\n" +
+				"не разделяет автора, а разные люди с одинаковым именем не объединяются. Несколько учетных записей одного человека\n" +
+				"объединяются файлом псевдонимов (--aliases, по умолчанию aliases.json рядом с файлом профилей):\n\n" +
+				"   {\"authors\": [{\"name\": \"Иван Иванов\", \"email\": \"ivanov@corp.ru\",\n" +
+				"                 \"aliases\": [\"DOMAIN\\\\iivanov\", \"i.ivanov@corp.ru\", \"<идентификатор учетной записи>\"]}]}\n\n" +
+				"Псевдоним сравнивается с идентификатором, почтой и именем автора без учета регистра. Флаг --suggest находит\n" +
+				"авторов с похожими почтами и выводит для них готовый фрагмент файла.",
+			Flags: append([]cli.Flag{
+				&cli.BoolFlag{
+					Name:        "suggest",
+					Usage:       "предложить объединить авторов с похожими почтами",
+					Destination: &suggest,
+				},
+				newFormatFlag(&format),
+			}, parallel.flags()...),
+			Action: func(context *cli.Context) error {
+				out, err := newRenderer(format, os.Stdout)
 				if err != nil {
 					return err
 				}
-				if url != "" {
-					config.OrganizationUrl = url
+				authors, err := authorFiles.load(false)
+				if err != nil {
+					return err
 				}
-				if token != "" {
-					config.Token = token
+				azureClient, err := connect(profileName, activeProfile)
+				if err != nil {
+					return err
 				}
-				if cache == "true" {
-					settings.CacheEnabled = true
-				} else if cache != "" {
-					settings.CacheEnabled = false
+				projectNames := context.Args().Slice()
+				if len(projectNames) == 0 {
+					projectNames, err = listProjects(azureClient)
+					if err != nil {
+						return err
+					}
 				}
-				if port != settings.ExporterPort {
-					if port < 1024 || port > 65535 {
-						return errors.New("Введите порт в диапазоне от 1024 до 65535!")
+				index := identity.NewIndex()
+				loader := &projectLoader{azure: azureClient, cache: useCache(), store: localStore, authors: authors, opts: &parallel}
+				opts := &logOptions{filter: &filter.Options{}}
+				errs := runProjects(projectNames, &parallel, func(project string) (projectResult, error) {
+					return loader.load(project, opts, func(iter repointerface.CommitIterator) error {
+						for commit, err := iter.Next(); err == nil; commit, err = iter.Next() {
+							index.Add(commit)
+						}
+						return nil
+					})
+				})
+				if suggest {
+					suggestions := identity.Suggest(index.Identities())
+					if out != nil {
+						err = renderSuggestions(out, suggestions)
 					} else {
-						settings.ExporterPort = port
+						err = printSuggestions(os.Stdout, suggestions, authorFiles.aliases)
 					}
+				} else if out != nil {
+					err = renderAuthors(out, index.Identities())
+				} else {
+					printAuthors(os.Stdout, index.Identities())
 				}
-				err = WriteConfigFile(&configPath, config)
 				if err != nil {
 					return err
 				}
-				err = WriteSettingsFile(&settingsPath, settings)
-				fmt.Printf("Текущая конфигурация:\nURL: %s\nToken: %s\nCacheEnabled: %t\nExporterPort: %d\n",
-					config.OrganizationUrl, config.Token, settings.CacheEnabled, settings.ExporterPort)
-				return err
+				authors.printExcluded(os.Stderr, false)
+				return errs.report(os.Stderr)
 			},
 		},
 		{
-			Name:    "getmetrics",
-			Aliases: []string{"gm"},
-			Usage:   "вывод на экран данных метрики по конкретному автору или по проекту",
+			Name:      "show",
+			Usage:     "вывод информации о ченджсете: автор, сообщение, измененные файлы и diff",
+			ArgsUsage: "<changeset-id>",
 			Flags: []cli.Flag{
-				&cli.StringFlag{
-					Name:        "author",
-					Aliases:     []string{"a"},
-					Usage:       "данные метрики по конкретному автору",
-					Destination: &author,
-				},
 				&cli.StringFlag{
 					Name:        "project",
 					Aliases:     []string{"p"},
-					Usage:       "данные метрики по конкретному проекту",
+					Usage:       "проект, к которому относится ченджсет",
 					Destination: &project,
 				},
+				&cli.BoolFlag{
+					Name:        "diff",
+					Aliases:     []string{"d"},
+					Usage:       "вывести unified diff измененных файлов",
+					Destination: &show.diff,
+				},
+				&cli.IntFlag{
+					Name:        "context",
+					Aliases:     []string{"U"},
+					Usage:       "количество строк контекста в diff",
+					Value:       3,
+					Destination: &show.context,
+				},
+				&cli.BoolFlag{
+					Name:        "no-color",
+					Usage:       "не раскрашивать diff",
+					Destination: &noColor,
+				},
+				newFormatFlag(&format),
 			},
-			Action: func(c *cli.Context) error {
-				if author == "" && project == "" {
-					return errors.New("Пожалуйста, укажите автора или название проекта.")
+			Action: func(context *cli.Context) error {
+				id, err := strconv.Atoi(context.Args().Get(0))
+				if err != nil {
+					return errors.New("укажите номер ченджсета (cli-metrics show <changeset-id>)")
 				}
-				var err error
-				azureClient, err := connect(prjPath)
+				out, err := newRenderer(format, os.Stdout)
 				if err != nil {
 					return err
 				}
-				settings, _ := ReadSettingsFile(&settingsPath)
-				exp := exporter.NewExporter()
-				if project != "" {
-					commits := tfsmetrics.NewCommitCollection(project, azureClient, settings.CacheEnabled, localStore)
-					err = commits.Open()
+				azureClient, err := connect(profileName, activeProfile)
+				if err != nil {
+					return err
+				}
+				details, err := azureClient.GetChangesetDetails(&id, activeProfile.projectOrDefault(project))
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					return renderChangeset(out, details, &show)
+				}
+				show.color = !noColor && isTerminal(os.Stdout)
+				printChangeset(os.Stdout, details, &show)
+				return nil
+			},
+		},
+		{
+			Name:      "annotate",
+			Aliases:   []string{"blame"},
+			Usage:     "вывод автора и ченджсета последнего изменения каждой строки файла",
+			ArgsUsage: "<server-path>",
+			Flags: []cli.Flag{
+				newFormatFlag(&format),
+			},
+			Action: func(context *cli.Context) error {
+				path := context.Args().Get(0)
+				if path == "" {
+					return errors.New("укажите путь к файлу на сервере (cli-metrics annotate $/project/file)")
+				}
+				out, err := newRenderer(format, os.Stdout)
+				if err != nil {
+					return err
+				}
+				azureClient, err := connect(profileName, activeProfile)
+				if err != nil {
+					return err
+				}
+				lines, err := tfsmetrics.Annotate(path, azureClient, useCache(), localStore)
+				if err != nil {
+					return err
+				}
+				if out != nil {
+					return renderAnnotation(out, lines)
+				}
+				printAnnotation(os.Stdout, lines)
+				return nil
+			},
+		},
+		{
+			Name:      "sync",
+			Usage:     "загрузка истории проектов в кеш с выводом прогресса (прерванная загрузка продолжается с места остановки)",
+			ArgsUsage: "[ProjectName...]",
+			Action: func(context *cli.Context) error {
+				if localStore == nil {
+					return cacheUnavailable(profileName, storeErr)
+				}
+				azureClient, err := connect(profileName, activeProfile)
+				if err != nil {
+					return err
+				}
+				if !activeProfile.CacheEnabled {
+					fmt.Fprintln(os.Stderr, "Внимание: кеш отключен в профиле, другие команды не будут использовать загруженные данные (cli-metrics config --cache true)")
+				}
+				projectNames := context.Args().Slice()
+				if len(projectNames) == 0 {
+					projects, err := azureClient.ListOfProjects()
 					if err != nil {
 						return err
 					}
-					iter, err := commits.GetCommitIterator()
-					if err != nil {
-						return err
+					for _, project := range projects {
+						projectNames = append(projectNames, *project)
 					}
-					data := exp.GetDataByProject(iter)
-					fmt.Printf("Данные метрики по проекту '%s':\n", project)
-					printByProject(&data)
-					fmt.Println()
 				}
-				if author != "" {
-					data := make(map[string]*exporter.ByAuthor)
-					projectNames, err := azureClient.ListOfProjects()
+				printer := newProgressPrinter(os.Stderr, isTerminal(os.Stderr))
+				syncer := tfsmetrics.NewSyncer(azureClient, localStore, printer.update)
+				failures := 0
+				for _, project := range projectNames {
+					progress, err := syncer.Sync(project)
+					printer.finish(progress, err)
 					if err != nil {
-						return err
-					}
-					for _, prj := range projectNames {
-						commits := tfsmetrics.NewCommitCollection(*prj, azureClient, settings.CacheEnabled, localStore)
-						err = commits.Open()
-						if err != nil {
-							return err
-						}
-						iter, err := commits.GetCommitIterator()
-						if err != nil {
-							return err
-						}
-						data = exp.GetDataByAuthor(iter, author, *prj)
+						failures++
 					}
-					fmt.Printf("Данные метрики по автору '%s':\n", author)
-					printByAuthor(&data)
-					fmt.Println()
+				}
+				if failures > 0 {
+					return fmt.Errorf("не удалось синхронизировать проектов: %d", failures)
 				}
 				return nil
 			},
 		},
 		{
-			Name:    "list",
-			Aliases: []string{"ls"},
-			Usage:   "вывод на экран названий всех проектов в репозитории",
+			Name:      "backfill",
+			Aliases:   []string{"bf"},
+			Usage:     "выгрузка закешированной истории коммитов в формате OpenMetrics для promtool tsdb create-blocks-from openmetrics",
+			ArgsUsage: "[ProjectName...]",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:        "output",
+					Aliases:     []string{"o"},
+					Usage:       "файл для записи истории (по умолчанию вывод на экран)",
+					Destination: &output,
+				},
+			},
 			Action: func(context *cli.Context) error {
-				var err error
-				azureClient, err := connect(prjPath)
+				if localStore == nil {
+					return cacheUnavailable(profileName, storeErr)
+				}
+				authors, err := authorFiles.load(false)
 				if err != nil {
 					return err
 				}
-				projectNames, err := azureClient.ListOfProjects()
+				projectNames := context.Args().Slice()
+				if len(projectNames) == 0 {
+					projectNames, err = localStore.Projects()
+					if err != nil {
+						return err
+					}
+				}
+				bf := exporter.NewBackfill()
+				for _, project := range projectNames {
+					iter, err := localStore.CommitIterator(project)
+					if err != nil {
+						return fmt.Errorf("в кеше нет данных по проекту '%s'", project)
+					}
+					if err = bf.Add(authors.exclude(project)(authors.Apply(iter)), project); err != nil {
+						return fmt.Errorf("проект '%s': %w", project, err)
+					}
+				}
+				authors.printExcluded(os.Stderr, false)
+				if output == "" {
+					return bf.WriteOpenMetrics(os.Stdout)
+				}
+				file, err := os.Create(output)
 				if err != nil {
 					return err
 				}
-				fmt.Println("Доступны следующие проекты:")
-				for ind, project := range projectNames {
-					fmt.Printf("%d) %s\n", ind+1, *project)
+				defer file.Close()
+				err = bf.WriteOpenMetrics(file)
+				if err != nil {
+					return err
 				}
+				fmt.Printf("История метрик записана в %s\nДля загрузки в Prometheus выполните:\n\tpromtool tsdb create-blocks-from openmetrics %s <data-dir>\n",
+					output, output)
 				return nil
 			},
 		},
 		{
-			Name:    "log",
-			Aliases: []string{"l"},
-			Usage:   "получение информации обо всех коммитах",
+			Name:    "push",
+			Aliases: []string{"ps"},
+			Usage:   "однократная отправка метрик в Pushgateway (для окружений, которые нельзя опрашивать)",
+			Flags: append([]cli.Flag{
+				&cli.StringFlag{
+					Name:        "pushgateway-url",
+					Aliases:     []string{"url", "u"},
+					Usage:       "адрес Pushgateway, например http://localhost:9091",
+					Required:    true,
+					Destination: &pushUrl,
+				},
+				&cli.StringFlag{
+					Name:        "job",
+					Aliases:     []string{"j"},
+					Usage:       "имя задания (job), под которым отправляются метрики",
+					Value:       "cli-metrics",
+					Destination: &pushJob,
+				},
+				&cli.IntFlag{
+					Name:        "retries",
+					Aliases:     []string{"r"},
+					Usage:       "количество повторных попыток при ошибке отправки",
+					Value:       3,
+					Destination: &pushRetries,
+				},
+			}, append(filters.flags(true), parallel.flags()...)...),
 			Action: func(context *cli.Context) error {
-				var err error
-				prjName := context.Args().Get(0)
-				azureClient, err := connect(prjPath)
-				settings, _ := ReadSettingsFile(&settingsPath)
+				filterOptions, err := filters.options()
 				if err != nil {
 					return err
 				}
-				projectNames, err := azureClient.ListOfProjects()
+				authors, err := authorFiles.load(filters.noExclude)
 				if err != nil {
 					return err
 				}
-				if prjName == "" {
-					fmt.Println("Название проекта не было указано, информация по коммитам будет выведена по всем проектам:")
-					for _, project := range projectNames {
-						_ = processProject(project, &azureClient, settings.CacheEnabled, &localStore)
-					}
-				} else {
-					for _, project := range projectNames {
-						if *project == prjName {
-							err = processProject(project, &azureClient, settings.CacheEnabled, &localStore)
-							if err != nil {
-								return err
-							}
-						}
+				azureClient, err := connect(profileName, activeProfile)
+				if err != nil {
+					return err
+				}
+				loader := &projectLoader{azure: azureClient, cache: useCache(), store: localStore, authors: authors, opts: &parallel}
+				errs, err := collectPrometheusMetrics(loader, filterOptions, filters.reportExcluded)
+				if err != nil {
+					return err
+				}
+				authors.printExcluded(os.Stderr, filters.reportExcluded)
+				if parallel.failFast {
+					if err = errs.report(os.Stderr); err != nil {
+						return err
 					}
 				}
-				return nil
+				pusher := exporter.NewPrometheusPusher(pushUrl, pushJob, pushRetries, time.Second*5)
+				err = pusher.Push()
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Метрики отправлены в %s\n", pushUrl)
+				// Метрики проектов без ошибок отправлены, но неполные данные должны быть заметны (например, в CI)
+				return errs.report(os.Stderr)
 			},
 		},
 		{
 			Name:    "start-exporter",
 			Aliases: []string{"s"},
 			Usage:   "запуск экспортера (для запуска в фоне введите: nohup cli-metrics start-exporter &)",
+			Flags:   append(filters.flags(true), parallel.flags()...),
 			Action: func(context *cli.Context) error {
-				var err error
-				azureClient, err := connect(prjPath)
+				filterOptions, err := filters.options()
 				if err != nil {
 					return err
 				}
-				settings, _ := ReadSettingsFile(&settingsPath)
-				projectNames, err := azureClient.ListOfProjects()
+				authors, err := authorFiles.load(filters.noExclude)
 				if err != nil {
 					return err
 				}
-				exp := exporter.NewExporter()
-				for _, project := range projectNames {
-					commits := tfsmetrics.NewCommitCollection(*project, azureClient, settings.CacheEnabled, localStore)
-					err = commits.Open()
-					if err != nil {
+				azureClient, err := connect(profileName, activeProfile)
+				if err != nil {
+					return err
+				}
+				loader := &projectLoader{azure: azureClient, cache: useCache(), store: localStore, authors: authors, opts: &parallel}
+				errs, err := collectPrometheusMetrics(loader, filterOptions, filters.reportExcluded)
+				if err != nil {
+					return err
+				}
+				authors.printExcluded(os.Stderr, filters.reportExcluded)
+				if parallel.failFast {
+					if err = errs.report(os.Stderr); err != nil {
 						return err
 					}
+				} else if errs.report(os.Stderr) != nil {
+					fmt.Fprintln(os.Stderr, "Метрики этих проектов неполные или отсутствуют")
+				}
+				addr, metricsUrl := settings.exporterAddr()
+				fmt.Printf("Метрики доступны по адресу %s\n", metricsUrl)
+				fmt.Printf("Метрики отдельного проекта: %s?project=ProjectName\n", strings.TrimSuffix(metricsUrl, "/metrics")+"/probe")
+				wg := sync.WaitGroup{}
+				serv := exporter.NewPrometheusServer(&wg, time.Second*5, settings.serverConfig())
+				serv.EnableProbe(func(project string) (repointerface.CommitIterator, error) {
+					commits := tfsmetrics.NewCommitCollection(project, azureClient, loader.cache, localStore)
+					err := commits.Open()
+					if err != nil {
+						return nil, err
+					}
 					iter, err := commits.GetCommitIterator()
 					if err != nil {
-						return err
+						return nil, err
 					}
-					exp.PrometheusMetrics(iter, *project)
+					if parallel.skipFailed {
+						iter = filter.SkipFailed(iter, func(err *repointerface.ChangesetError) {
+							fmt.Fprintf(os.Stderr, "Внимание: /probe?project=%s: пропущен %v\n", project, err)
+						})
+					}
+					return exclude.Filter(filterOptions.Apply(authors.Apply(iter)), authors.rules, nil), nil
+				})
+				err = serv.Start(addr)
+				if err != nil {
+					return err
 				}
-				fmt.Printf("Метрики доступны по адресу http://localhost:%d/metrics\n", settings.ExporterPort)
-				wg := sync.WaitGroup{}
-				serv := exporter.NewPrometheusServer(&wg, time.Second*5)
-				serv.Start(":" + strconv.Itoa(settings.ExporterPort))
+				stop := make(chan os.Signal, 1)
+				signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+				defer signal.Stop(stop)
+				select {
+				case <-stop:
+				case err = <-serv.Errors():
+					wg.Wait()
+					return fmt.Errorf("экспортер остановлен из-за ошибки: %w", err)
+				}
+				fmt.Println("Остановка экспортера...")
+				err = serv.Stop()
 				wg.Wait()
-				return nil
+				return err
 			},
 		},
 	}
+	// Закрываем хранилище после любой команды, чтобы bolt сбросил данные на диск и снял блокировку файла
+	app.After = func(c *cli.Context) error {
+		if localStore == nil {
+			return nil
+		}
+		return localStore.Close()
+	}
 	azure.NewConfig()
 	return app
 }
@@ -284,55 +1397,51 @@ func exists(path string) (bool, error) {
 	return false, err
 }
 
+// ReadConfigFile возвращает параметры подключения текущего профиля
 func ReadConfigFile(filePath *string) (config *azure.Config, err error) {
-	config = azure.NewConfig()
-	ex, _ := exists(*filePath)
-	if !ex {
-		output, _ := os.Create(*filePath)
-		defer output.Close()
-		jsonEncoder := json.NewEncoder(output)
-		err = jsonEncoder.Encode(config)
-	}
-	data, err := ioutil.ReadFile(*filePath)
+	profiles, err := readProfiles(*filePath)
 	if err != nil {
-		return
+		return nil, err
 	}
-	err = json.Unmarshal(data, &config)
-	return
+	_, p, ok := profiles.Get("")
+	if !ok {
+		return azure.NewConfig(), nil
+	}
+	return p.azureConfig(), nil
 }
 
+// WriteConfigFile сохраняет параметры подключения в текущий профиль
 func WriteConfigFile(filePath *string, config *azure.Config) error {
-	output, err := os.Create(*filePath)
+	profiles, err := readProfiles(*filePath)
 	if err != nil {
 		return err
 	}
-	defer output.Close()
-	jsonEncoder := json.NewEncoder(output)
-	err = jsonEncoder.Encode(config)
-	return err
+	_, p, err := profiles.GetOrCreate("")
+	if err != nil {
+		return err
+	}
+	p.OrganizationUrl = config.OrganizationUrl
+	p.Token = config.Token
+	return writeProfiles(*filePath, profiles)
 }
 
 func WriteSettingsFile(filePath *string, settings *cliSettings) error {
-	output, err := os.Create(*filePath)
+	data, err := json.Marshal(settings)
 	if err != nil {
 		return err
 	}
-	defer output.Close()
-	jsonEncoder := json.NewEncoder(output)
-	err = jsonEncoder.Encode(settings)
-	return err
+	// В настройках хранятся пароль и токен доступа к /metrics
+	return writeSecureFile(*filePath, append(data, '\n'))
 }
 
 func ReadSettingsFile(filePath *string) (settings *cliSettings, err error) {
-	settings = &cliSettings{CacheEnabled: true, ExporterPort: 8080}
+	settings = &cliSettings{ExporterPort: 8080}
 	ex, _ := exists(*filePath)
 	if !ex {
-		output, _ := os.Create(*filePath)
-		defer output.Close()
-		jsonEncoder := json.NewEncoder(output)
-		err = jsonEncoder.Encode(settings)
+		err = WriteSettingsFile(filePath, settings)
 		return
 	}
+	checkPermissions(*filePath)
 	data, err := ioutil.ReadFile(*filePath)
 	if err != nil {
 		return
@@ -356,38 +1465,153 @@ func printProjectName(name *string) {
 	fmt.Printf("\n\n")
 }
 
-func processProject(project *string, azureClient *azure.AzureInterface, cacheEnabled bool, localStore *store.Store) error {
-	printProjectName(project)
-	commits := tfsmetrics.NewCommitCollection(*project, *azureClient, cacheEnabled, *localStore)
+// listProjects возвращает названия всех проектов организации
+func listProjects(azureClient azure.AzureInterface) ([]string, error) {
+	projects, err := azureClient.ListOfProjects()
+	if err != nil {
+		return nil, err
+	}
+	projectNames := make([]string, 0, len(projects))
+	for _, project := range projects {
+		projectNames = append(projectNames, *project)
+	}
+	return projectNames, nil
+}
+
+// projectLoader загружает коммиты проектов для runProjects
+type projectLoader struct {
+	azure azure.AzureInterface
+	cache bool
+	store store.Store
+	// Псевдонимы и команды авторов (nil - без замены авторов)
+	authors *authorMapping
+	opts    *parallelFlags
+}
+
+// load загружает коммиты проекта в память, применяя к итератору псевдонимы авторов и параметры opts,
+// чтобы проекты можно было загружать параллельно, а выводить по очереди. Функция use получает
+// загруженные коммиты при выводе результата, в том числе неполные, если часть коммитов получить не удалось:
+// тогда load возвращает и результат, и ошибку
+func (l *projectLoader) load(project string, opts *logOptions, use func(iter repointerface.CommitIterator) error) (projectResult, error) {
+	res := projectResult{}
+	commits := tfsmetrics.NewCommitCollection(project, l.azure, l.cache, l.store)
 	err := commits.Open()
 	if err != nil {
-		return err
+		return res, err
 	}
 	iter, err := commits.GetCommitIterator()
 	if err != nil {
-		return err
+		return res, err
+	}
+	if l.opts.skipFailed {
+		iter = filter.SkipFailed(iter, func(err *repointerface.ChangesetError) {
+			res.skipped = append(res.skipped, err)
+		})
+	}
+	var exclude func(repointerface.CommitIterator) repointerface.CommitIterator
+	if l.authors != nil {
+		iter = l.authors.Apply(iter)
+		exclude = l.authors.exclude(project)
+	}
+	iter, err = opts.apply(iter, exclude)
+	if err == nil {
+		iter, err = filter.Preload(iter)
+	}
+	res.output = func() error {
+		return use(iter)
+	}
+	if err != nil {
+		return res, fmt.Errorf("данные неполные, получены не все коммиты: %w", err)
+	}
+	return res, nil
+}
+
+// printProject выводит коммиты проекта: текстом, если out == nil, иначе строками в out
+func printProject(project string, iter repointerface.CommitIterator, out renderer) error {
+	if out == nil {
+		printProjectName(&project)
 	}
 	for commit, err := iter.Next(); err == nil; commit, err = iter.Next() {
-		printFullCommit(commit)
+		if out == nil {
+			printFullCommit(commit)
+			continue
+		}
+		err = out.Row(project, commit.Id, commit.Author, commit.Email, commit.Date,
+			commit.AddedRows, commit.DeletedRows, commit.Message)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func connect(prjPath *string) (azure.AzureInterface, error) {
-	filePath := path.Join(*prjPath, "configs/config.json")
-	config, err := ReadConfigFile(&filePath)
+// collectPrometheusMetrics заполняет метрики Prometheus данными по всем проектам.
+// Ошибка возвращается, если не удалось получить список проектов, ошибки отдельных проектов - в сводке
+func collectPrometheusMetrics(loader *projectLoader, filterOptions *filter.Options, reportExcluded bool) (*projectErrors, error) {
+	projectNames, err := listProjects(loader.azure)
 	if err != nil {
 		return nil, err
 	}
+	exp := exporter.NewExporter()
+	opts := &logOptions{filter: filterOptions}
+	errs := runProjects(projectNames, loader.opts, func(project string) (projectResult, error) {
+		return loader.load(project, opts, func(iter repointerface.CommitIterator) error {
+			return exp.PrometheusMetrics(iter, project)
+		})
+	})
+	if reportExcluded {
+		exp.ExcludedMetrics(loader.authors.excluded.Entries())
+	}
+	return errs, nil
+}
+
+// cacheUnavailable возвращает ошибку открытия кеша профиля
+func cacheUnavailable(name string, storeErr error) error {
+	if storeErr != nil {
+		return fmt.Errorf("кеш профиля '%s' недоступен: %w", name, storeErr)
+	}
+	return fmt.Errorf("кеш профиля '%s' недоступен", name)
+}
+
+// cacheAvailable возвращает true, если кеш включен в профиле и файл кеша открыт. Если файл кеша открыть
+// не удалось (например, его занимает запущенный экспортер), команда работает без кеша и выводит предупреждение
+func cacheAvailable(p *profile, localStore store.Store, storeErr error, w io.Writer) bool {
+	if p == nil || !p.CacheEnabled {
+		return false
+	}
+	if localStore != nil {
+		return true
+	}
+	if storeErr == nil {
+		storeErr = errors.New("файл кеша не открыт")
+	}
+	fmt.Fprintf(w, "Внимание: %v, данные загружаются без кеша\n", storeErr)
+	return false
+}
+
+func connect(name string, p *profile) (azure.AzureInterface, error) {
+	if p == nil {
+		if name != defaultProfile {
+			return nil, fmt.Errorf("профиль '%s' не найден (cli-metrics config list)", name)
+		}
+		return nil, errors.New("отсутствуют параметры подключения (cli-metrics config)")
+	}
+	if err := p.resolveToken(readPassphrase); err != nil {
+		return nil, err
+	}
+	config := p.azureConfig()
 	if config.OrganizationUrl == "" && config.Token == "" {
 		return nil, errors.New("отсутствуют параметры подключения (cli-metrics config)")
 	} else if config.OrganizationUrl == "" {
 		return nil, errors.New("отсутствует url подключения (cli-metrics config --url)")
-	} else if config.Token == "" {
+	} else if config.Token == "" && config.AuthType != azure.AuthNone {
 		return nil, errors.New("отсутствует token подключения (cli-metrics config --token)")
 	}
 	azureClient := azure.NewAzure(config)
-	azureClient.Connect()
+	err := azureClient.Connect()
+	if err != nil {
+		return nil, err
+	}
 	err = azureClient.TfvcClientConnection()
 	if err != nil {
 		return nil, err
@@ -412,3 +1636,76 @@ func printByProject(byproject *map[string]*exporter.ByProject) {
 	}
 
 }
+
+func renderByAuthor(out renderer, author string, byauthor map[string]*exporter.ByAuthor) error {
+	projects := make([]string, 0, len(byauthor))
+	for project := range byauthor {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+	for _, project := range projects {
+		stats := byauthor[project]
+		err := out.Row(project, author, stats.Commits, stats.AddedRows, stats.DeletedRows)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderByProject(out renderer, project string, byproject map[string]*exporter.ByProject) error {
+	authors := make([]string, 0, len(byproject))
+	for author := range byproject {
+		authors = append(authors, author)
+	}
+	sort.Strings(authors)
+	for _, author := range authors {
+		stats := byproject[author]
+		err := out.Row(project, author, stats.Commits, stats.AddedRows, stats.DeletedRows)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Группировки данных по проекту в getmetrics
+const (
+	groupByAuthor = "author"
+	groupByTeam   = "team"
+)
+
+// Название для коммитов авторов, которых нет в составе команд
+const noTeam = "без команды"
+
+func sortedTeams(byteam map[string]*exporter.ByTeam) []string {
+	teams := make([]string, 0, len(byteam))
+	for team := range byteam {
+		teams = append(teams, team)
+	}
+	sort.Strings(teams)
+	return teams
+}
+
+func printByTeam(w io.Writer, byteam map[string]*exporter.ByTeam) {
+	for _, team := range sortedTeams(byteam) {
+		stats := byteam[team]
+		if team == "" {
+			team = noTeam
+		}
+		fmt.Fprintf(w, "Команда: %s\n", team)
+		fmt.Fprintf(w, "\tКоличество авторов: %d\n\tКоличество коммитов: %d\n\tКоличество добавленных строк %d\n\tКоличество удаленных строк %d\n",
+			stats.Authors, stats.Commits, stats.AddedRows, stats.DeletedRows)
+	}
+}
+
+func renderByTeam(out renderer, project string, byteam map[string]*exporter.ByTeam) error {
+	for _, team := range sortedTeams(byteam) {
+		stats := byteam[team]
+		err := out.Row(project, team, stats.Authors, stats.Commits, stats.AddedRows, stats.DeletedRows)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
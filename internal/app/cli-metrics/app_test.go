@@ -1,26 +1,74 @@
 package cli_metrics
 
 import (
+	"bytes"
+	"errors"
 	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"go-marathon-team-3/pkg/tfsmetrics/store"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestReadConfigFile(t *testing.T) {
-	configpath := "../../../configs/config.json"
-	_, err := ReadConfigFile(&configpath)
+	dir, err := ioutil.TempDir("", "configs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	configpath := filepath.Join(dir, "config.json")
+	_, err = ReadConfigFile(&configpath)
 	assert.NoError(t, err)
 }
 
 func TestWriteConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
 	config := azure.NewConfig()
 	config.OrganizationUrl = "url.com"
 	config.Token = "12345"
-	configpath := "../../../configs/config.json"
-	err := WriteConfigFile(&configpath, config)
+	configpath := filepath.Join(dir, "config.json")
+	err = WriteConfigFile(&configpath, config)
 	assert.NoError(t, err)
 	readConfig, err := ReadConfigFile(&configpath)
 	assert.Equal(t, config.OrganizationUrl, readConfig.OrganizationUrl)
 	assert.Equal(t, config.Token, readConfig.Token)
 }
+
+func TestCacheAvailable(t *testing.T) {
+	db, err := store.TestStore()
+	require.NoError(t, err)
+	defer os.Remove(db.DB.Path())
+	defer db.Close()
+	locked := errors.New("файл кеша assets.db занят другим процессом")
+
+	tests := []struct {
+		name     string
+		profile  *profile
+		store    store.Store
+		storeErr error
+		want     bool
+		warning  string
+	}{
+		{name: "no profile", store: db},
+		{name: "disabled", profile: &profile{}, store: db},
+		{name: "enabled", profile: &profile{CacheEnabled: true}, store: db, want: true},
+		{
+			name:     "locked",
+			profile:  &profile{CacheEnabled: true},
+			storeErr: locked,
+			warning:  "Внимание: файл кеша assets.db занят другим процессом, данные загружаются без кеша\n",
+		},
+		{name: "disabled and locked", profile: &profile{}, storeErr: locked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			assert.Equal(t, tt.want, cacheAvailable(tt.profile, tt.store, tt.storeErr, &out))
+			assert.Equal(t, tt.warning, out.String())
+		})
+	}
+}
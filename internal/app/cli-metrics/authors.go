@@ -0,0 +1,185 @@
+package cli_metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/exclude"
+	"go-marathon-team-3/pkg/tfsmetrics/identity"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"go-marathon-team-3/pkg/tfsmetrics/team"
+	"io"
+	"os"
+	"strings"
+)
+
+// Файлы псевдонимов авторов и состава команд
+type authorFiles struct {
+	aliases string
+	roster  string
+	// Файл состава команд задан явно и должен существовать
+	rosterRequired bool
+	exclude        string
+	// Файл правил исключения задан явно и должен существовать
+	excludeRequired bool
+}
+
+// Замена авторов коммитов по псевдонимам, определение их команд и исключение ботов
+type authorMapping struct {
+	aliases identity.Resolver
+	roster  team.Roster
+	rules   exclude.Matcher
+	// Коммиты, исключенные правилами
+	excluded *exclude.Report
+}
+
+// load читает файлы псевдонимов, состава команд и правил исключения. С noExclude правила не применяются
+func (f *authorFiles) load(noExclude bool) (*authorMapping, error) {
+	aliases, err := identity.ReadAliases(f.aliases)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := identity.NewResolver(aliases)
+	if err != nil {
+		return nil, err
+	}
+	memberships, err := team.ReadMemberships(f.roster)
+	if os.IsNotExist(err) && !f.rosterRequired {
+		memberships, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Участник, который есть в файле псевдонимов, заменяется ключом человека: этот ключ получают
+	// коммиты всех его учетных записей, поэтому в составе команд можно указать любой псевдоним
+	for i := range memberships {
+		if key := resolver.Key(memberships[i].Member); key != "" {
+			memberships[i].Member = key
+		}
+	}
+	roster := team.NewRoster(memberships)
+	rules := &exclude.Rules{}
+	if _, err = os.Stat(f.exclude); err != nil && f.excludeRequired {
+		return nil, err
+	}
+	if !noExclude {
+		if rules, err = exclude.ReadRules(f.exclude); err != nil {
+			return nil, err
+		}
+	}
+	matcher, err := exclude.NewMatcher(rules)
+	if err != nil {
+		return nil, err
+	}
+	return &authorMapping{aliases: resolver, roster: roster, rules: matcher, excluded: exclude.NewReport()}, nil
+}
+
+// Apply заменяет авторов по псевдонимам и заполняет команду. Команда определяется после замены
+// по ключу человека, в который load переводит участников состава команд
+func (m *authorMapping) Apply(it repointerface.CommitIterator) repointerface.CommitIterator {
+	return m.roster.Apply(m.aliases.Apply(it))
+}
+
+// exclude возвращает функцию, которая убирает из коммитов проекта коммиты ботов и добавляет их в отчет.
+// Правила проверяются после замены псевдонимов, поэтому в них можно указывать основное имя человека
+func (m *authorMapping) exclude(project string) func(repointerface.CommitIterator) repointerface.CommitIterator {
+	return func(it repointerface.CommitIterator) repointerface.CommitIterator {
+		return exclude.Filter(it, m.rules, func(commit *repointerface.Commit, rule string) {
+			m.excluded.Add(project, commit, rule)
+		})
+	}
+}
+
+// printExcluded выводит число исключенных коммитов, а с detailed - исключенные коммиты по авторам и правилам
+func (m *authorMapping) printExcluded(w io.Writer, detailed bool) {
+	commits := m.excluded.Commits()
+	if commits == 0 {
+		return
+	}
+	if !detailed {
+		fmt.Fprintf(w, "Исключено коммитов ботов и служебных учетных записей: %d (подробнее --report-excluded, не исключать --no-exclude)\n", commits)
+		return
+	}
+	fmt.Fprintf(w, "Исключено коммитов ботов и служебных учетных записей: %d\n", commits)
+	for _, entry := range m.excluded.Entries() {
+		fmt.Fprintf(w, "\t%s: %s <%s>, коммитов: %d, строк +%d -%d, правило %s\n", entry.Project, entry.Author, entry.Email,
+			entry.Commits, entry.AddedRows, entry.DeletedRows, entry.Rule)
+	}
+}
+
+// otherNames возвращает имена и почты, под которыми встречался автор, кроме текущих
+func otherNames(author *identity.Identity) []string {
+	res := []string{}
+	for _, name := range author.Names {
+		if name != author.Name {
+			res = append(res, name)
+		}
+	}
+	for _, email := range author.Emails {
+		if email != author.Email {
+			res = append(res, email)
+		}
+	}
+	return res
+}
+
+func printAuthors(w io.Writer, authors []*identity.Identity) {
+	for _, author := range authors {
+		fmt.Fprintf(w, "%s <%s>\n", author.Name, author.Email)
+		fmt.Fprintf(w, "\tКоличество коммитов: %d\n\tИдентификатор: %s\n", author.Commits, author.Key)
+		if other := otherNames(author); len(other) > 0 {
+			fmt.Fprintf(w, "\tТакже встречается как: %s\n", strings.Join(other, ", "))
+		}
+	}
+}
+
+func renderAuthors(out renderer, authors []*identity.Identity) error {
+	err := out.Header("author", "email", "id", "commits", "aliases")
+	if err != nil {
+		return err
+	}
+	for _, author := range authors {
+		err = out.Row(author.Name, author.Email, author.Key, author.Commits, strings.Join(otherNames(author), ", "))
+		if err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// printSuggestions выводит предложения объединить авторов и готовый фрагмент файла псевдонимов
+func printSuggestions(w io.Writer, suggestions []identity.Suggestion, aliasesPath string) error {
+	if len(suggestions) == 0 {
+		fmt.Fprintln(w, "Авторов с похожими почтами не найдено")
+		return nil
+	}
+	aliases := identity.Aliases{Authors: []identity.Person{}}
+	for _, suggestion := range suggestions {
+		fmt.Fprintf(w, "Возможно, это один человек (логин %s):\n", suggestion.Login)
+		for _, author := range suggestion.Identities {
+			fmt.Fprintf(w, "\t%s <%s>, коммитов: %d, идентификатор: %s\n", author.Name, author.Email, author.Commits, author.Key)
+		}
+		aliases.Authors = append(aliases.Authors, suggestion.Person())
+	}
+	data, err := json.MarshalIndent(aliases, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nЧтобы объединить авторов, проверьте предложения и добавьте их в %s:\n%s\n", aliasesPath, data)
+	return nil
+}
+
+func renderSuggestions(out renderer, suggestions []identity.Suggestion) error {
+	err := out.Header("login", "author", "email", "id", "commits")
+	if err != nil {
+		return err
+	}
+	for _, suggestion := range suggestions {
+		for _, author := range suggestion.Identities {
+			err = out.Row(suggestion.Login, author.Name, author.Email, author.Key, author.Commits)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return out.Flush()
+}
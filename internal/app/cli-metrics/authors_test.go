@@ -0,0 +1,171 @@
+package cli_metrics
+
+import (
+	"bytes"
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/exporter"
+	"go-marathon-team-3/pkg/tfsmetrics/identity"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAuthors() *identity.Index {
+	date := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	index := identity.NewIndex()
+	index.Add(&repointerface.Commit{Author: "Ivanov I.", AuthorId: "id-1", Email: `DOMAIN\iivanov`, Date: date})
+	index.Add(&repointerface.Commit{Author: "Ivan Ivanov", AuthorId: "id-1", Email: `DOMAIN\iivanov`, Date: date.Add(time.Hour)})
+	index.Add(&repointerface.Commit{Author: "Ivan", AuthorId: "id-2", Email: "i.ivanov@corp.ru", Date: date})
+	return index
+}
+
+func TestPrintAuthors(t *testing.T) {
+	buf := bytes.Buffer{}
+	printAuthors(&buf, testAuthors().Identities())
+	assert.Equal(t, ""+
+		"Ivan <i.ivanov@corp.ru>\n"+
+		"\tКоличество коммитов: 1\n\tИдентификатор: id-2\n"+
+		"Ivan Ivanov <DOMAIN\\iivanov>\n"+
+		"\tКоличество коммитов: 2\n\tИдентификатор: id-1\n"+
+		"\tТакже встречается как: Ivanov I.\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, renderAuthors(newCsvRenderer(&buf), testAuthors().Identities()))
+	assert.Equal(t, ""+
+		"author,email,id,commits,aliases\n"+
+		"Ivan,i.ivanov@corp.ru,id-2,1,\n"+
+		"Ivan Ivanov,DOMAIN\\iivanov,id-1,2,Ivanov I.\n", buf.String())
+}
+
+func TestPrintSuggestions(t *testing.T) {
+	suggestions := identity.Suggest(testAuthors().Identities())
+	buf := bytes.Buffer{}
+	require.NoError(t, printSuggestions(&buf, suggestions, "aliases.json"))
+	assert.Equal(t, ""+
+		"Возможно, это один человек (логин iivanov):\n"+
+		"\tIvan <i.ivanov@corp.ru>, коммитов: 1, идентификатор: id-2\n"+
+		"\tIvan Ivanov <DOMAIN\\iivanov>, коммитов: 2, идентификатор: id-1\n"+
+		"\n"+
+		"Чтобы объединить авторов, проверьте предложения и добавьте их в aliases.json:\n"+
+		"{\n"+
+		"  \"authors\": [\n"+
+		"    {\n"+
+		"      \"name\": \"Ivan Ivanov\",\n"+
+		"      \"email\": \"DOMAIN\\\\iivanov\",\n"+
+		"      \"aliases\": [\n"+
+		"        \"id-2\",\n"+
+		"        \"i.ivanov@corp.ru\",\n"+
+		"        \"id-1\",\n"+
+		"        \"DOMAIN\\\\iivanov\"\n"+
+		"      ]\n"+
+		"    }\n"+
+		"  ]\n"+
+		"}\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, printSuggestions(&buf, nil, "aliases.json"))
+	assert.Equal(t, "Авторов с похожими почтами не найдено\n", buf.String())
+}
+
+func TestAuthorFiles_load(t *testing.T) {
+	dir := t.TempDir()
+	files := &authorFiles{aliases: filepath.Join(dir, "aliases.json"), roster: filepath.Join(dir, "roster.yaml")}
+	authors, err := files.load(false)
+	require.NoError(t, err)
+	commit := &repointerface.Commit{Author: "Ivan", Date: time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)}
+	all, err := combinator.Collect(authors.Apply(combinator.FromSlice([]*repointerface.Commit{commit})))
+	require.NoError(t, err)
+	assert.Same(t, commit, all[0])
+
+	// Команда определяется по имени после замены псевдонимов
+	require.NoError(t, ioutil.WriteFile(files.aliases, []byte(`{"authors": [{"name": "Иван Иванов", "aliases": ["ivan"]}]}`), 0600))
+	require.NoError(t, ioutil.WriteFile(files.roster, []byte("teams:\n  Backend:\n    - Иван Иванов\n"), 0600))
+	authors, err = files.load(false)
+	require.NoError(t, err)
+	all, err = combinator.Collect(authors.Apply(combinator.FromSlice([]*repointerface.Commit{commit})))
+	require.NoError(t, err)
+	assert.Equal(t, "Иван Иванов", all[0].Author)
+	assert.Equal(t, "Backend", all[0].Team)
+
+	// В составе команд можно указать любой псевдоним: коммиты всех учетных записей человека попадают в его команду
+	require.NoError(t, ioutil.WriteFile(files.aliases, []byte(`{"authors": [{"name": "Иван Иванов", "email": "ivanov@corp.ru", "aliases": ["id-1", "id-2", "DOMAIN\\iivanov"]}]}`), 0600))
+	require.NoError(t, ioutil.WriteFile(files.roster, []byte("teams:\n  Backend:\n    - member: id-1\n      until: 2021-06-30\n  Frontend:\n    - member: domain\\iivanov\n      since: 2021-07-01\n"), 0600))
+	authors, err = files.load(false)
+	require.NoError(t, err)
+	all, err = combinator.Collect(authors.Apply(combinator.FromSlice([]*repointerface.Commit{
+		{Author: "Ivanov I.", AuthorId: "id-2", Date: time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{Author: "Ivan", AuthorId: "id-1", Date: time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)},
+		{Author: "Petr", AuthorId: "id-3", Date: time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)},
+	})))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Backend", "Frontend", ""}, []string{all[0].Team, all[1].Team, all[2].Team})
+
+	// Явно заданный файл состава команд должен существовать
+	files.roster = filepath.Join(dir, "teams.csv")
+	files.rosterRequired = true
+	_, err = files.load(false)
+	assert.Error(t, err)
+}
+
+func TestPrintByTeam(t *testing.T) {
+	data := map[string]*exporter.ByTeam{
+		"Backend": {Authors: 2, Commits: 3, AddedRows: 10, DeletedRows: 1},
+		"":        {Authors: 1, Commits: 1, AddedRows: 2},
+	}
+	buf := bytes.Buffer{}
+	printByTeam(&buf, data)
+	assert.Equal(t, ""+
+		"Команда: без команды\n"+
+		"\tКоличество авторов: 1\n\tКоличество коммитов: 1\n\tКоличество добавленных строк 2\n\tКоличество удаленных строк 0\n"+
+		"Команда: Backend\n"+
+		"\tКоличество авторов: 2\n\tКоличество коммитов: 3\n\tКоличество добавленных строк 10\n\tКоличество удаленных строк 1\n", buf.String())
+
+	buf.Reset()
+	out := newCsvRenderer(&buf)
+	require.NoError(t, out.Header("project", "team", "authors", "commits", "added_rows", "deleted_rows"))
+	require.NoError(t, renderByTeam(out, "prj", data))
+	require.NoError(t, out.Flush())
+	assert.Equal(t, ""+
+		"project,team,authors,commits,added_rows,deleted_rows\n"+
+		"prj,,1,1,2,0\n"+
+		"prj,Backend,2,3,10,1\n", buf.String())
+}
+
+func TestAuthorMapping_exclude(t *testing.T) {
+	dir := t.TempDir()
+	files := &authorFiles{aliases: filepath.Join(dir, "aliases.json"), roster: filepath.Join(dir, "roster.yaml"),
+		exclude: filepath.Join(dir, "exclude.json")}
+	require.NoError(t, ioutil.WriteFile(files.exclude, []byte(`{"authors": ["build@corp.ru"]}`), 0600))
+	commits := []*repointerface.Commit{
+		{Id: 1, Author: "Ivan", Email: "ivan@corp.ru", AddedRows: 10},
+		{Id: 2, Author: "Build", Email: "build@corp.ru", AddedRows: 100, DeletedRows: 3},
+	}
+
+	authors, err := files.load(false)
+	require.NoError(t, err)
+	all, err := combinator.Collect(authors.exclude("project")(combinator.FromSlice(commits)))
+	require.NoError(t, err)
+	assert.Equal(t, commits[:1], all)
+	var buf bytes.Buffer
+	authors.printExcluded(&buf, false)
+	assert.Equal(t, "Исключено коммитов ботов и служебных учетных записей: 1 (подробнее --report-excluded, не исключать --no-exclude)\n", buf.String())
+	buf.Reset()
+	authors.printExcluded(&buf, true)
+	assert.Equal(t, "Исключено коммитов ботов и служебных учетных записей: 1\n"+
+		"\tproject: Build <build@corp.ru>, коммитов: 1, строк +100 -3, правило author:build@corp.ru\n", buf.String())
+
+	// С --no-exclude правила не применяются и сводка не выводится
+	authors, err = files.load(true)
+	require.NoError(t, err)
+	all, err = combinator.Collect(authors.exclude("project")(combinator.FromSlice(commits)))
+	require.NoError(t, err)
+	assert.Equal(t, commits, all)
+	buf.Reset()
+	authors.printExcluded(&buf, true)
+	assert.Empty(t, buf.String())
+}
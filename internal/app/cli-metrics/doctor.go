@@ -0,0 +1,272 @@
+package cli_metrics
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"go-marathon-team-3/pkg/tfsmetrics/store"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Сколько ждать ответа сервера при диагностике
+const doctorTimeout = 10 * time.Second
+
+type checkStatus string
+
+const (
+	checkOK   checkStatus = " OK "
+	checkWarn checkStatus = "WARN"
+	checkFail checkStatus = "FAIL"
+	checkSkip checkStatus = "SKIP"
+)
+
+// Результат одной проверки команды doctor
+type checkResult struct {
+	name   string
+	status checkStatus
+	detail string
+	// Что сделать, чтобы исправить проблему
+	hint string
+}
+
+func passed(name, detail string) checkResult {
+	return checkResult{name: name, status: checkOK, detail: detail}
+}
+
+func failed(name string, err error, hint string) checkResult {
+	return checkResult{name: name, status: checkFail, detail: err.Error(), hint: hint}
+}
+
+func skipped(name, reason string) checkResult {
+	return checkResult{name: name, status: checkSkip, detail: reason}
+}
+
+// Параметры, которые проверяет doctor
+type doctorInput struct {
+	profileName string
+	profile     *profile
+	settings    *cliSettings
+	localStore  store.Store
+	storeErr    error
+}
+
+// runDoctor выполняет проверки по порядку: если не прошла базовая проверка (например, сервер недоступен),
+// зависящие от нее проверки пропускаются
+func runDoctor(in *doctorInput) []checkResult {
+	results := []checkResult{}
+	config, result := checkConfig(in.profileName, in.profile)
+	results = append(results, result)
+	if result.status == checkFail {
+		results = append(results, skipped("Доступность сервера", "нет параметров подключения"))
+	} else {
+		results = append(results, checkServer(config)...)
+	}
+	results = append(results, checkCache(in.localStore, in.storeErr))
+	results = append(results, checkExporterPort(in.settings))
+	return results
+}
+
+func checkConfig(profileName string, p *profile) (*azure.Config, checkResult) {
+	name := "Конфигурация"
+	if p == nil {
+		return nil, failed(name, fmt.Errorf("профиль '%s' не найден", profileName),
+			"создайте профиль: cli-metrics config --url URL --token TOKEN")
+	}
+	if p.OrganizationUrl == "" {
+		return nil, failed(name, errors.New("не задан url подключения"), "cli-metrics config --url URL")
+	}
+	if err := p.resolveToken(readPassphrase); err != nil {
+		return nil, failed(name, err, "задайте пароль в "+envPassphrase+" или сохраните токен заново: cli-metrics config --token TOKEN")
+	}
+	if p.Token == "" && p.AuthType != azure.AuthNone {
+		return nil, failed(name, errors.New("не задан токен"), "cli-metrics config --token TOKEN")
+	}
+	return p.azureConfig(), passed(name, fmt.Sprintf("профиль %s, %s, аутентификация %s", profileName, p.OrganizationUrl, p.authDescription()))
+}
+
+// checkServer проверяет доступность сервера, TLS, аутентификацию и права токена
+func checkServer(config *azure.Config) []checkResult {
+	results := []checkResult{checkReachability(config)}
+	if results[0].status == checkFail {
+		return append(results,
+			skipped("TLS", "сервер недоступен"),
+			skipped("Аутентификация", "сервер недоступен"),
+			skipped("Права токена", "сервер недоступен"))
+	}
+	results = append(results, checkTLS(config))
+	if results[1].status == checkFail {
+		return append(results,
+			skipped("Аутентификация", "не удалось установить защищенное соединение"),
+			skipped("Права токена", "не удалось установить защищенное соединение"))
+	}
+	client := azure.NewAzure(config)
+	auth := checkAuthentication(client)
+	results = append(results, auth)
+	if auth.status == checkFail {
+		return append(results, skipped("Права токена", "аутентификация не пройдена"))
+	}
+	for _, check := range client.Azure().CheckAPIs() {
+		results = append(results, checkAPI(check))
+	}
+	return results
+}
+
+// checkReachability проверяет, что до сервера (или прокси) устанавливается TCP-соединение
+func checkReachability(config *azure.Config) checkResult {
+	name := "Доступность сервера"
+	u, err := url.Parse(config.OrganizationUrl)
+	if err != nil || u.Host == "" {
+		return failed(name, fmt.Errorf("некорректный url '%s'", config.OrganizationUrl),
+			"url должен иметь вид https://dev.azure.com/organization или http://tfs:8080/tfs/Collection")
+	}
+	target, via := hostPort(u), ""
+	if config.Transport.ProxyUrl != "" {
+		proxy, err := url.Parse(config.Transport.ProxyUrl)
+		if err != nil || proxy.Host == "" {
+			return failed(name, fmt.Errorf("некорректный адрес прокси '%s'", config.Transport.ProxyUrl), "cli-metrics config --proxy http://proxy:port")
+		}
+		target, via = hostPort(proxy), " через прокси"
+	}
+	conn, err := net.DialTimeout("tcp", target, doctorTimeout)
+	if err != nil {
+		hint := "проверьте url, сеть и VPN; если сервер доступен только через прокси, укажите его: cli-metrics config --proxy http://proxy:port"
+		if via != "" {
+			hint = "проверьте адрес прокси (cli-metrics config --proxy)"
+		}
+		return failed(name, err, hint)
+	}
+	conn.Close()
+	return passed(name, target+" отвечает"+via)
+}
+
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// checkTLS выполняет запрос без аутентификации: любой HTTP-ответ означает, что сертификат сервера принят
+func checkTLS(config *azure.Config) checkResult {
+	name := "TLS"
+	transport, err := azure.NewTransport(config.Transport)
+	if err != nil {
+		return failed(name, err, "проверьте файлы сертификатов (cli-metrics config --ca-file, --client-cert, --client-key)")
+	}
+	client := &http.Client{Transport: transport, Timeout: doctorTimeout}
+	resp, err := client.Get(strings.TrimRight(config.OrganizationUrl, "/") + "/_apis/connectionData")
+	if err != nil {
+		var unknownAuthority x509.UnknownAuthorityError
+		var hostname x509.HostnameError
+		switch {
+		case errors.As(err, &unknownAuthority):
+			return failed(name, err, "сертификат выдан неизвестным CA: укажите сертификат корпоративного CA (cli-metrics config --ca-file ca.pem)")
+		case errors.As(err, &hostname):
+			return failed(name, err, "сертификат выдан на другое имя: используйте в url имя сервера из сертификата")
+		}
+		return failed(name, err, "проверьте настройки TLS и прокси")
+	}
+	resp.Body.Close()
+	if !strings.EqualFold(resp.Request.URL.Scheme, "https") {
+		return checkResult{name: name, status: checkWarn, detail: "соединение не зашифровано (http)",
+			hint: "токен передается в открытом виде, по возможности используйте https"}
+	}
+	if config.Transport.InsecureSkipVerify {
+		return checkResult{name: name, status: checkWarn, detail: "проверка сертификата отключена",
+			hint: "укажите сертификат CA (--ca-file) и отключите --insecure-skip-verify"}
+	}
+	return passed(name, "сертификат сервера принят")
+}
+
+func checkAuthentication(client azure.AzureInterface) checkResult {
+	name := "Аутентификация"
+	if err := client.Connect(); err != nil {
+		return failed(name, err, "проверьте способ аутентификации (cli-metrics config --auth, --username)")
+	}
+	user, err := client.Azure().AuthenticatedUser()
+	if err != nil {
+		return failed(name, err, authHint(azure.StatusCode(err)))
+	}
+	return passed(name, "пользователь "+user)
+}
+
+func authHint(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return "токен недействителен или истек: создайте новый токен и сохраните его (cli-metrics config --token-stdin); " +
+			"для TFS без поддержки токенов используйте --auth basic"
+	case http.StatusForbidden:
+		return "у пользователя нет доступа к коллекции: проверьте права в настройках сервера"
+	case 0:
+		return "сервер не принял учетные данные (возможно, перенаправил на страницу входа): проверьте токен и способ аутентификации"
+	}
+	return "проверьте url коллекции и учетные данные"
+}
+
+func checkAPI(check azure.APICheck) checkResult {
+	name := "API " + check.API
+	if check.Err == nil {
+		return passed(name, "доступ есть")
+	}
+	switch azure.StatusCode(check.Err) {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return failed(name, check.Err, "выдайте токену область "+check.Scope)
+	case http.StatusNotFound:
+		return checkResult{name: name, status: checkWarn, detail: check.Err.Error(),
+			hint: "API недоступно на сервере (например, старая версия TFS)"}
+	}
+	return failed(name, check.Err, "выдайте токену область "+check.Scope+" и проверьте версию сервера")
+}
+
+func checkCache(localStore store.Store, storeErr error) checkResult {
+	name := "Кеш"
+	if localStore == nil {
+		if storeErr == nil {
+			storeErr = errors.New("файл кеша не открыт")
+		}
+		return failed(name, storeErr, "остановите другие запущенные cli-metrics (например, экспортер) или удалите поврежденный файл кеша")
+	}
+	if err := localStore.Check(); err != nil {
+		return failed(name, err, "файл кеша поврежден: удалите его, данные будут загружены заново")
+	}
+	projects, err := localStore.Projects()
+	if err != nil {
+		return failed(name, err, "файл кеша поврежден: удалите его, данные будут загружены заново")
+	}
+	return passed(name, fmt.Sprintf("проектов в кеше: %d", len(projects)))
+}
+
+func checkExporterPort(settings *cliSettings) checkResult {
+	name := "Порт экспортера"
+	addr, _ := settings.exporterAddr()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return failed(name, err, "порт занят (возможно, экспортер уже запущен): выберите другой порт (cli-metrics config --port)")
+	}
+	listener.Close()
+	return passed(name, addr+" свободен")
+}
+
+// printChecklist печатает результаты и возвращает число проваленных проверок
+func printChecklist(w io.Writer, results []checkResult) int {
+	failures := 0
+	for _, result := range results {
+		fmt.Fprintf(w, "[%s] %s: %s\n", result.status, result.name, result.detail)
+		if result.hint != "" {
+			fmt.Fprintf(w, "       Как исправить: %s\n", result.hint)
+		}
+		if result.status == checkFail {
+			failures++
+		}
+	}
+	return failures
+}
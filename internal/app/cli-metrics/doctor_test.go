@@ -0,0 +1,151 @@
+package cli_metrics
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"go-marathon-team-3/pkg/tfsmetrics/mock"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/microsoft/azure-devops-go-api/azuredevops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile *profile
+		status  checkStatus
+	}{
+		{name: "no profile", status: checkFail},
+		{name: "no url", profile: &profile{Token: "12345"}, status: checkFail},
+		{name: "no token", profile: &profile{OrganizationUrl: "http://tfs:8080/tfs"}, status: checkFail},
+		{name: "headers only", profile: &profile{OrganizationUrl: "http://tfs:8080/tfs", AuthType: azure.AuthNone}, status: checkOK},
+		{name: "ok", profile: &profile{OrganizationUrl: "http://tfs:8080/tfs", Token: "12345"}, status: checkOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, result := checkConfig(defaultProfile, tt.profile)
+			assert.Equal(t, tt.status, result.status)
+			assert.Equal(t, tt.status == checkOK, config != nil)
+		})
+	}
+}
+
+func TestCheckReachability(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	result := checkReachability(&azure.Config{OrganizationUrl: server.URL + "/tfs"})
+	assert.Equal(t, checkOK, result.status)
+
+	server.Close()
+	result = checkReachability(&azure.Config{OrganizationUrl: server.URL + "/tfs"})
+	assert.Equal(t, checkFail, result.status)
+
+	result = checkReachability(&azure.Config{OrganizationUrl: "tfs"})
+	assert.Equal(t, checkFail, result.status)
+}
+
+func TestCheckTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	result := checkTLS(&azure.Config{OrganizationUrl: server.URL})
+	assert.Equal(t, checkFail, result.status)
+	assert.Contains(t, result.hint, "--ca-file")
+
+	dir, err := ioutil.TempDir("", "doctor")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caFile, caPem, 0600))
+	result = checkTLS(&azure.Config{OrganizationUrl: server.URL, Transport: azure.TransportConfig{CAFile: caFile}})
+	assert.Equal(t, checkOK, result.status)
+
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	result = checkTLS(&azure.Config{OrganizationUrl: plain.URL})
+	assert.Equal(t, checkWarn, result.status)
+}
+
+func TestCheckAPI(t *testing.T) {
+	statusError := func(status int) error {
+		message := http.StatusText(status)
+		return &azuredevops.WrappedError{Message: &message, StatusCode: &status}
+	}
+	tests := []struct {
+		name   string
+		err    error
+		status checkStatus
+	}{
+		{name: "ok", status: checkOK},
+		{name: "unauthorized", err: statusError(http.StatusUnauthorized), status: checkFail},
+		{name: "not found", err: statusError(http.StatusNotFound), status: checkWarn},
+		{name: "other", err: errors.New("EOF"), status: checkFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checkAPI(azure.APICheck{API: "tfvc", Scope: "Code (Read)", Err: tt.err})
+			assert.Equal(t, tt.status, result.status)
+			if tt.status == checkFail {
+				assert.Contains(t, result.hint, "Code (Read)")
+			}
+		})
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	assert.Equal(t, checkFail, checkCache(nil, errors.New("файл кеша занят")).status)
+
+	broken := mock.NewMockStore(ctrl)
+	broken.EXPECT().Check().Return(errors.New("page 3: unreachable unfreed"))
+	assert.Equal(t, checkFail, checkCache(broken, nil).status)
+
+	healthy := mock.NewMockStore(ctrl)
+	healthy.EXPECT().Check().Return(nil)
+	healthy.EXPECT().Projects().Return([]string{"project"}, nil)
+	result := checkCache(healthy, nil)
+	assert.Equal(t, checkOK, result.status)
+	assert.Equal(t, "проектов в кеше: 1", result.detail)
+}
+
+func TestCheckExporterPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	settings := &cliSettings{ExporterAddress: "127.0.0.1", ExporterPort: port}
+	assert.Equal(t, checkFail, checkExporterPort(settings).status)
+
+	listener.Close()
+	result := checkExporterPort(settings)
+	assert.Equal(t, checkOK, result.status)
+	assert.Equal(t, "127.0.0.1:"+strconv.Itoa(port)+" свободен", result.detail)
+}
+
+func TestPrintChecklist(t *testing.T) {
+	buf := bytes.Buffer{}
+	failures := printChecklist(&buf, []checkResult{
+		passed("Конфигурация", "профиль default"),
+		failed("Аутентификация", errors.New("401"), "обновите токен"),
+		skipped("Права токена", "аутентификация не пройдена"),
+	})
+	assert.Equal(t, 1, failures)
+	assert.Equal(t, ""+
+		"[ OK ] Конфигурация: профиль default\n"+
+		"[FAIL] Аутентификация: 401\n"+
+		"       Как исправить: обновите токен\n"+
+		"[SKIP] Права токена: аутентификация не пройдена\n", buf.String())
+}
@@ -0,0 +1,223 @@
+package cli_metrics
+
+import (
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"go-marathon-team-3/pkg/tfsmetrics/store"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Переменные окружения переопределяют сохраненные настройки на время запуска и не записываются в конфигурацию
+const (
+	envConfig          = "TFC_CONFIG"
+	envProfile         = "TFC_PROFILE"
+	envUrl             = "TFC_URL"
+	envToken           = "TFC_TOKEN"
+	envProject         = "TFC_PROJECT"
+	envAuth            = "TFC_AUTH"
+	envUsername        = "TFC_USERNAME"
+	envProxy           = "TFC_PROXY"
+	envCAFile          = "TFC_CA_FILE"
+	envClientCert      = "TFC_CLIENT_CERT"
+	envClientKey       = "TFC_CLIENT_KEY"
+	envInsecure        = "TFC_INSECURE_SKIP_VERIFY"
+	envMaxIdleConns    = "TFC_MAX_IDLE_CONNS"
+	envMaxIdlePerHost  = "TFC_MAX_IDLE_CONNS_PER_HOST"
+	envMaxConnsPerHost = "TFC_MAX_CONNS_PER_HOST"
+	envCache           = "TFC_CACHE"
+	envExporterPort    = "TFC_EXPORTER_PORT"
+	envExporterAddress = "TFC_EXPORTER_ADDRESS"
+	envTLSCert         = "TFC_TLS_CERT"
+	envTLSKey          = "TFC_TLS_KEY"
+	envTLSClientCA     = "TFC_TLS_CLIENT_CA"
+	envMetricsUser     = "TFC_METRICS_USER"
+	envMetricsPassword = "TFC_METRICS_PASSWORD"
+	envMetricsToken    = "TFC_METRICS_TOKEN"
+	envAliases         = "TFC_ALIASES"
+	envRoster          = "TFC_ROSTER"
+	envExcludeRules    = "TFC_EXCLUDE_RULES"
+	envCalendar        = "TFC_CALENDAR"
+)
+
+// Имя каталога с настройками внутри пользовательского каталога конфигурации
+const configDirName = "tfc"
+
+// DefaultConfigDir возвращает каталог настроек: $XDG_CONFIG_HOME/tfc (или ~/.config/tfc) в Linux,
+// ~/Library/Application Support/tfc в macOS и %AppData%\tfc в Windows
+func DefaultConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configDirName), nil
+}
+
+// settingsFilePath возвращает путь к файлу настроек экспортера, который лежит рядом с файлом профилей
+func settingsFilePath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "cli-settings.json")
+}
+
+// aliasesFilePath возвращает путь к файлу псевдонимов авторов, который лежит рядом с файлом профилей
+func aliasesFilePath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "aliases.json")
+}
+
+// rosterFilePath возвращает путь к файлу состава команд, который лежит рядом с файлом профилей
+func rosterFilePath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "roster.yaml")
+}
+
+// excludeFilePath возвращает путь к файлу правил исключения коммитов, который лежит рядом с файлом профилей
+func excludeFilePath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "exclude.json")
+}
+
+// calendarFilePath возвращает путь к файлу рабочего календаря, который лежит рядом с файлом профилей
+func calendarFilePath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "calendar.yaml")
+}
+
+// migrateCacheFile переносит файл кеша пространства имен из текущего каталога, где он хранился раньше,
+// в каталог dir. Возвращает путь к перенесенному файлу или пустую строку, если переносить нечего
+func migrateCacheFile(dir, namespace string) (string, error) {
+	oldPath := store.FileName(namespace)
+	newPath := filepath.Join(dir, oldPath)
+	if ex, _ := exists(newPath); ex {
+		return "", nil
+	}
+	if ex, _ := exists(oldPath); !ex {
+		return "", nil
+	}
+	err := os.Rename(oldPath, newPath)
+	if err == nil {
+		return oldPath, nil
+	}
+	// Каталог настроек может находиться на другом разделе диска
+	if err = copyFile(oldPath, newPath); err != nil {
+		os.Remove(newPath)
+		return "", err
+	}
+	return oldPath, os.Remove(oldPath)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+type lookupEnvFunc func(key string) (string, bool)
+
+func lookupString(lookup lookupEnvFunc, key string, dest *string) bool {
+	value, ok := lookup(key)
+	if ok {
+		*dest = value
+	}
+	return ok
+}
+
+func lookupBool(lookup lookupEnvFunc, key string, dest *bool) (bool, error) {
+	value, ok := lookup(key)
+	if !ok {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("некорректное значение %s='%s': ожидается true или false", key, value)
+	}
+	*dest = parsed
+	return true, nil
+}
+
+func lookupInt(lookup lookupEnvFunc, key string, dest *int) (bool, error) {
+	value, ok := lookup(key)
+	if !ok {
+		return false, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return false, fmt.Errorf("некорректное значение %s='%s': ожидается число", key, value)
+	}
+	*dest = parsed
+	return true, nil
+}
+
+// applyEnv переопределяет параметры профиля переменными окружения.
+// Возвращает true, если задана хотя бы одна переменная
+func (p *profile) applyEnv(lookup lookupEnvFunc) (bool, error) {
+	applied := false
+	for key, dest := range map[string]*string{
+		envUrl:        &p.OrganizationUrl,
+		envToken:      &p.Token,
+		envProject:    &p.Project,
+		envUsername:   &p.Username,
+		envProxy:      &p.Transport.ProxyUrl,
+		envCAFile:     &p.Transport.CAFile,
+		envClientCert: &p.Transport.CertFile,
+		envClientKey:  &p.Transport.KeyFile,
+	} {
+		if lookupString(lookup, key, dest) {
+			applied = true
+		}
+	}
+	if authType, ok := lookup(envAuth); ok {
+		if err := validateAuthType(authType); err != nil {
+			return false, err
+		}
+		p.AuthType = azure.AuthType(authType)
+		applied = true
+	}
+	ok, err := lookupBool(lookup, envCache, &p.CacheEnabled)
+	if err != nil {
+		return false, err
+	}
+	applied = applied || ok
+	ok, err = lookupBool(lookup, envInsecure, &p.Transport.InsecureSkipVerify)
+	if err != nil {
+		return false, err
+	}
+	applied = applied || ok
+	for key, dest := range map[string]*int{
+		envMaxIdleConns:    &p.Transport.MaxIdleConns,
+		envMaxIdlePerHost:  &p.Transport.MaxIdleConnsPerHost,
+		envMaxConnsPerHost: &p.Transport.MaxConnsPerHost,
+	} {
+		ok, err = lookupInt(lookup, key, dest)
+		if err != nil {
+			return false, err
+		}
+		applied = applied || ok
+	}
+	return applied, nil
+}
+
+// applyEnv переопределяет настройки экспортера переменными окружения
+func (s *cliSettings) applyEnv(lookup lookupEnvFunc) error {
+	for key, dest := range map[string]*string{
+		envExporterAddress: &s.ExporterAddress,
+		envTLSCert:         &s.ExporterTLSCert,
+		envTLSKey:          &s.ExporterTLSKey,
+		envTLSClientCA:     &s.ExporterClientCA,
+		envMetricsUser:     &s.ExporterUsername,
+		envMetricsPassword: &s.ExporterPassword,
+		envMetricsToken:    &s.ExporterBearerToken,
+	} {
+		lookupString(lookup, key, dest)
+	}
+	_, err := lookupInt(lookup, envExporterPort, &s.ExporterPort)
+	return err
+}
@@ -0,0 +1,126 @@
+package cli_metrics
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLookup(env map[string]string) lookupEnvFunc {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestProfile_applyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		applied bool
+		want    *profile
+		wantErr bool
+	}{
+		{
+			name: "no variables",
+			env:  map[string]string{},
+			want: &profile{OrganizationUrl: "url.com", Token: "12345", CacheEnabled: true},
+		},
+		{
+			name:    "override",
+			env:     map[string]string{envUrl: "https://dev.azure.com/org", envToken: "", envProject: "main", envCache: "false"},
+			applied: true,
+			want:    &profile{OrganizationUrl: "https://dev.azure.com/org", Project: "main"},
+		},
+		{
+			name:    "transport",
+			env:     map[string]string{envProxy: "http://proxy:3128", envInsecure: "true", envMaxConnsPerHost: "4"},
+			applied: true,
+			want: &profile{OrganizationUrl: "url.com", Token: "12345", CacheEnabled: true,
+				Transport: azure.TransportConfig{ProxyUrl: "http://proxy:3128", InsecureSkipVerify: true, MaxConnsPerHost: 4}},
+		},
+		{
+			name:    "bad pool limit",
+			env:     map[string]string{envMaxIdleConns: "many"},
+			wantErr: true,
+		},
+		{
+			name:    "bad cache value",
+			env:     map[string]string{envCache: "yes please"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &profile{OrganizationUrl: "url.com", Token: "12345", CacheEnabled: true}
+			applied, err := p.applyEnv(testLookup(tt.env))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.applied, applied)
+			assert.Equal(t, tt.want, p)
+		})
+	}
+}
+
+func TestCliSettings_applyEnv(t *testing.T) {
+	settings := &cliSettings{ExporterPort: 8080, ExporterUsername: "prometheus"}
+	err := settings.applyEnv(testLookup(map[string]string{
+		envExporterPort:    "9100",
+		envExporterAddress: "127.0.0.1",
+		envMetricsPassword: "secret",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, &cliSettings{
+		ExporterPort:     9100,
+		ExporterAddress:  "127.0.0.1",
+		ExporterUsername: "prometheus",
+		ExporterPassword: "secret",
+	}, settings)
+
+	err = settings.applyEnv(testLookup(map[string]string{envExporterPort: "port"}))
+	assert.Error(t, err)
+}
+
+func TestMigrateCacheFile(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "workdir")
+	require.NoError(t, err)
+	defer os.RemoveAll(workDir)
+	configDir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(workDir))
+	defer os.Chdir(wd)
+
+	// Старого файла нет - переносить нечего
+	moved, err := migrateCacheFile(configDir, "")
+	assert.NoError(t, err)
+	assert.Empty(t, moved)
+
+	require.NoError(t, ioutil.WriteFile("assets-work.db", []byte("cache"), 0600))
+	moved, err = migrateCacheFile(configDir, "work")
+	assert.NoError(t, err)
+	assert.Equal(t, "assets-work.db", moved)
+	assert.NoFileExists(t, filepath.Join(workDir, "assets-work.db"))
+	data, err := ioutil.ReadFile(filepath.Join(configDir, "assets-work.db"))
+	require.NoError(t, err)
+	assert.Equal(t, "cache", string(data))
+
+	// Файл в каталоге настроек не перезаписывается старым
+	require.NoError(t, ioutil.WriteFile("assets-work.db", []byte("old"), 0600))
+	moved, err = migrateCacheFile(configDir, "work")
+	assert.NoError(t, err)
+	assert.Empty(t, moved)
+	data, err = ioutil.ReadFile(filepath.Join(configDir, "assets-work.db"))
+	require.NoError(t, err)
+	assert.Equal(t, "cache", string(data))
+}
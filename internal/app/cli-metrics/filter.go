@@ -0,0 +1,141 @@
+package cli_metrics
+
+import (
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/exporter"
+	"go-marathon-team-3/pkg/tfsmetrics/filter"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"regexp"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Флаги отбора коммитов, общие для log, getmetrics и экспортера
+type filterFlags struct {
+	author   string
+	email    string
+	team     string
+	since    string
+	until    string
+	grep     string
+	path     string
+	minLines int
+	// Не применять правила исключения ботов и служебных учетных записей
+	noExclude bool
+	// Вывести исключенные коммиты подробно
+	reportExcluded bool
+}
+
+// flags возвращает флаги отбора. В getmetrics флаг --author уже означает автора, по которому
+// выводятся данные, поэтому его можно не добавлять (withAuthor = false)
+func (f *filterFlags) flags(withAuthor bool) []cli.Flag {
+	flags := []cli.Flag{}
+	if withAuthor {
+		flags = append(flags, &cli.StringFlag{
+			Name:        "author",
+			Aliases:     []string{"a"},
+			Usage:       "только коммиты авторов, имя которых содержит строку",
+			Destination: &f.author,
+		})
+	}
+	return append(flags,
+		&cli.StringFlag{
+			Name:        "email",
+			Usage:       "только коммиты авторов, почта которых содержит строку",
+			Destination: &f.email,
+		},
+		&cli.StringFlag{
+			Name:        "team",
+			Usage:       "только коммиты, сделанные авторами в составе команды (см. --roster)",
+			Destination: &f.team,
+		},
+		&cli.StringFlag{
+			Name:        "since",
+			Usage:       "только коммиты не раньше даты (2006-01-02 или RFC3339)",
+			Destination: &f.since,
+		},
+		&cli.StringFlag{
+			Name:        "until",
+			Usage:       "только коммиты не позже даты (2006-01-02 или RFC3339)",
+			Destination: &f.until,
+		},
+		&cli.StringFlag{
+			Name:        "grep",
+			Usage:       "только коммиты, сообщение которых подходит под регулярное выражение",
+			Destination: &f.grep,
+		},
+		&cli.StringFlag{
+			Name:        "path",
+			Usage:       "только коммиты, затронувшие каталог или файлы по шаблону (например $/Project/src или $/Project/*.go)",
+			Destination: &f.path,
+		},
+		&cli.IntFlag{
+			Name:        "min-lines",
+			Usage:       "только коммиты, в которых изменено не меньше заданного числа строк",
+			Destination: &f.minLines,
+		},
+		&cli.BoolFlag{
+			Name:        "no-exclude",
+			Usage:       "не исключать коммиты ботов и служебных учетных записей (см. --exclude-rules)",
+			Destination: &f.noExclude,
+		},
+		&cli.BoolFlag{
+			Name:        "report-excluded",
+			Usage:       "вывести в конце исключенные коммиты по авторам и правилам (экспортер публикует их метрикой " + exporter.ExcludedCommitsMetric + ")",
+			Destination: &f.reportExcluded,
+		},
+	)
+}
+
+func (f *filterFlags) options() (*filter.Options, error) {
+	opts := &filter.Options{
+		Author:   f.author,
+		Email:    f.email,
+		Team:     f.team,
+		Path:     f.path,
+		MinLines: f.minLines,
+	}
+	var err error
+	if f.since != "" {
+		opts.Since, err = filter.ParseDate(f.since, false)
+		if err != nil {
+			return nil, fmt.Errorf("неверная дата --since: %s", f.since)
+		}
+	}
+	if f.until != "" {
+		opts.Until, err = filter.ParseDate(f.until, true)
+		if err != nil {
+			return nil, fmt.Errorf("неверная дата --until: %s", f.until)
+		}
+	}
+	if f.grep != "" {
+		opts.Grep, err = regexp.Compile(f.grep)
+		if err != nil {
+			return nil, fmt.Errorf("неверное регулярное выражение --grep: %v", err)
+		}
+	}
+	return opts, nil
+}
+
+// Параметры вывода коммитов командой log
+type logOptions struct {
+	filter  *filter.Options
+	limit   int
+	reverse bool
+}
+
+// apply применяет фильтры, затем exclude (если задана), чтобы правила исключения не учитывали коммиты,
+// не прошедшие фильтры, и только потом ограничивает число коммитов
+func (o *logOptions) apply(iter repointerface.CommitIterator, exclude func(repointerface.CommitIterator) repointerface.CommitIterator) (repointerface.CommitIterator, error) {
+	iter = o.filter.Apply(iter)
+	if exclude != nil {
+		iter = exclude(iter)
+	}
+	if o.limit > 0 {
+		iter = filter.Limit(iter, o.limit)
+	}
+	if o.reverse {
+		return filter.Reverse(iter)
+	}
+	return iter, nil
+}
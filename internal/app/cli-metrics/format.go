@@ -0,0 +1,246 @@
+package cli_metrics
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Формат вывода по умолчанию - текст на русском языке, который печатают сами команды
+const textFormat = "text"
+
+// renderer выводит данные команды построчно в одном из машиночитаемых форматов
+type renderer interface {
+	// Задает названия колонок, вызывается один раз перед первой строкой
+	Header(columns ...string) error
+	Row(values ...interface{}) error
+	// Дописывает вывод, вызывается один раз после последней строки
+	Flush() error
+}
+
+var renderers = map[string]func(w io.Writer) renderer{
+	"json":     newJsonRenderer,
+	"jsonl":    newJsonLinesRenderer,
+	"csv":      newCsvRenderer,
+	"markdown": newMarkdownRenderer,
+	"table":    newTableRenderer,
+}
+
+func formatNames() []string {
+	names := []string{textFormat}
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// newRenderer возвращает nil для текстового формата
+func newRenderer(format string, w io.Writer) (renderer, error) {
+	if format == "" || format == textFormat {
+		return nil, nil
+	}
+	constructor, ok := renderers[format]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный формат вывода '%s', доступны: %s", format, strings.Join(formatNames(), ", "))
+	}
+	return constructor(w), nil
+}
+
+func newFormatFlag(destination *string) cli.Flag {
+	return &cli.StringFlag{
+		Name:        "format",
+		Aliases:     []string{"f"},
+		Usage:       "формат вывода: " + strings.Join(formatNames(), ", "),
+		Value:       textFormat,
+		Destination: destination,
+	}
+}
+
+// formatCell приводит значение к строке для текстовых форматов (csv, markdown, table)
+func formatCell(value interface{}, timeLayout string) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(timeLayout)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type jsonRenderer struct {
+	w       io.Writer
+	columns []string
+	lines   bool
+	rows    int
+}
+
+// newJsonRenderer выводит массив объектов, ключи которых идут в порядке колонок
+func newJsonRenderer(w io.Writer) renderer {
+	return &jsonRenderer{w: w}
+}
+
+// newJsonLinesRenderer выводит по одному объекту в строке
+func newJsonLinesRenderer(w io.Writer) renderer {
+	return &jsonRenderer{w: w, lines: true}
+}
+
+func (r *jsonRenderer) Header(columns ...string) error {
+	r.columns = columns
+	if r.lines {
+		return nil
+	}
+	_, err := io.WriteString(r.w, "[")
+	return err
+}
+
+func (r *jsonRenderer) Row(values ...interface{}) error {
+	buf := bytes.Buffer{}
+	if !r.lines {
+		if r.rows > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  ")
+	}
+	buf.WriteString("{")
+	for i, column := range r.columns {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		key, _ := json.Marshal(column)
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+	if r.lines {
+		buf.WriteString("\n")
+	}
+	r.rows++
+	_, err := r.w.Write(buf.Bytes())
+	return err
+}
+
+func (r *jsonRenderer) Flush() error {
+	if r.lines {
+		return nil
+	}
+	end := "\n]\n"
+	if r.rows == 0 {
+		end = "]\n"
+	}
+	_, err := io.WriteString(r.w, end)
+	return err
+}
+
+type csvRenderer struct {
+	w *csv.Writer
+}
+
+func newCsvRenderer(w io.Writer) renderer {
+	return &csvRenderer{w: csv.NewWriter(w)}
+}
+
+func (r *csvRenderer) Header(columns ...string) error {
+	return r.w.Write(columns)
+}
+
+func (r *csvRenderer) Row(values ...interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = formatCell(value, time.RFC3339)
+	}
+	return r.w.Write(record)
+}
+
+func (r *csvRenderer) Flush() error {
+	r.w.Flush()
+	return r.w.Error()
+}
+
+type markdownRenderer struct {
+	w io.Writer
+}
+
+func newMarkdownRenderer(w io.Writer) renderer {
+	return &markdownRenderer{w: w}
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ")
+
+func (r *markdownRenderer) writeLine(cells []string) error {
+	_, err := fmt.Fprintf(r.w, "| %s |\n", strings.Join(cells, " | "))
+	return err
+}
+
+func (r *markdownRenderer) Header(columns ...string) error {
+	if err := r.writeLine(columns); err != nil {
+		return err
+	}
+	separators := make([]string, len(columns))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	return r.writeLine(separators)
+}
+
+func (r *markdownRenderer) Row(values ...interface{}) error {
+	cells := make([]string, len(values))
+	for i, value := range values {
+		cells[i] = markdownEscaper.Replace(formatCell(value, "2006-01-02 15:04:05"))
+	}
+	return r.writeLine(cells)
+}
+
+func (r *markdownRenderer) Flush() error {
+	return nil
+}
+
+type tableRenderer struct {
+	w *tabwriter.Writer
+}
+
+// newTableRenderer выравнивает колонки пробелами (вывод появляется после Flush)
+func newTableRenderer(w io.Writer) renderer {
+	return &tableRenderer{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)}
+}
+
+var tableEscaper = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ")
+
+func (r *tableRenderer) writeLine(cells []string) error {
+	_, err := fmt.Fprintln(r.w, strings.Join(cells, "\t"))
+	return err
+}
+
+func (r *tableRenderer) Header(columns ...string) error {
+	upper := make([]string, len(columns))
+	for i, column := range columns {
+		upper[i] = strings.ToUpper(column)
+	}
+	return r.writeLine(upper)
+}
+
+func (r *tableRenderer) Row(values ...interface{}) error {
+	cells := make([]string, len(values))
+	for i, value := range values {
+		cells[i] = tableEscaper.Replace(formatCell(value, "2006-01-02 15:04:05"))
+	}
+	return r.writeLine(cells)
+}
+
+func (r *tableRenderer) Flush() error {
+	return r.w.Flush()
+}
@@ -0,0 +1,84 @@
+package cli_metrics
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderers(t *testing.T) {
+	date := time.Date(2021, 10, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "json",
+			want: `[
+  {"project":"project","id":1,"date":"2021-10-01T12:30:00Z","message":"fix | \"quotes\""},
+  {"project":"project","id":2,"date":"2021-10-01T12:30:00Z","message":"line1\nline2"}
+]
+`,
+		},
+		{
+			format: "jsonl",
+			want: `{"project":"project","id":1,"date":"2021-10-01T12:30:00Z","message":"fix | \"quotes\""}
+{"project":"project","id":2,"date":"2021-10-01T12:30:00Z","message":"line1\nline2"}
+`,
+		},
+		{
+			format: "csv",
+			want: `project,id,date,message
+project,1,2021-10-01T12:30:00Z,"fix | ""quotes"""
+project,2,2021-10-01T12:30:00Z,"line1
+line2"
+`,
+		},
+		{
+			format: "markdown",
+			want: `| project | id | date | message |
+| --- | --- | --- | --- |
+| project | 1 | 2021-10-01 12:30:00 | fix \| "quotes" |
+| project | 2 | 2021-10-01 12:30:00 | line1 line2 |
+`,
+		},
+		{
+			format: "table",
+			want: `PROJECT  ID  DATE                 MESSAGE
+project  1   2021-10-01 12:30:00  fix | "quotes"
+project  2   2021-10-01 12:30:00  line1 line2
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			out, err := newRenderer(tt.format, &buf)
+			require.NoError(t, err)
+			require.NoError(t, out.Header("project", "id", "date", "message"))
+			require.NoError(t, out.Row("project", 1, date, `fix | "quotes"`))
+			require.NoError(t, out.Row("project", 2, date, "line1\nline2"))
+			require.NoError(t, out.Flush())
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestNewRenderer(t *testing.T) {
+	out, err := newRenderer(textFormat, &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Nil(t, out)
+
+	_, err = newRenderer("xml", &bytes.Buffer{})
+	assert.Error(t, err)
+
+	buf := bytes.Buffer{}
+	out, err = newRenderer("json", &buf)
+	require.NoError(t, err)
+	require.NoError(t, out.Header("project"))
+	require.NoError(t, out.Flush())
+	assert.Equal(t, "[]\n", buf.String())
+}
@@ -0,0 +1,176 @@
+package cli_metrics
+
+import (
+	"errors"
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Сколько проектов обрабатывается одновременно по умолчанию
+const defaultJobs = 4
+
+// Флаги обработки проектов, общие для log, getmetrics, push и экспортера
+type parallelFlags struct {
+	jobs       int
+	failFast   bool
+	skipFailed bool
+}
+
+func (f *parallelFlags) flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:        "jobs",
+			Aliases:     []string{"j"},
+			Usage:       "сколько проектов обрабатывать одновременно",
+			Value:       defaultJobs,
+			Destination: &f.jobs,
+		},
+		&cli.BoolFlag{
+			Name:        "fail-fast",
+			Usage:       "остановиться на первой ошибке (по умолчанию проекты с ошибками пропускаются и перечисляются в конце)",
+			Destination: &f.failFast,
+		},
+		&cli.BoolFlag{
+			Name:        "skip-failed",
+			Usage:       "пропускать ченджсеты, которые не удалось получить, и перечислить их в конце (по умолчанию проект с такой ошибкой считается обработанным не полностью)",
+			Destination: &f.skipFailed,
+		},
+	}
+}
+
+// Ошибка обработки одного проекта
+type projectError struct {
+	project string
+	err     error
+}
+
+// Ошибки обработки проектов, которые выводятся сводкой после завершения команды
+type projectErrors struct {
+	errors []projectError
+	// Ченджсеты, пропущенные с --skip-failed
+	skipped []projectError
+	total   int
+	stopped bool // обработка прервана из-за --fail-fast
+}
+
+// merge добавляет в сводку результаты обработки других проектов
+func (e *projectErrors) merge(other *projectErrors) {
+	e.errors = append(e.errors, other.errors...)
+	e.skipped = append(e.skipped, other.skipped...)
+	e.total += other.total
+	e.stopped = e.stopped || other.stopped
+}
+
+func (e *projectErrors) Error() string {
+	return fmt.Sprintf("не удалось обработать проектов: %d из %d", len(e.errors), e.total)
+}
+
+// report выводит сводку ошибок и возвращает ее как ошибку (nil, если ошибок не было).
+// Пропущенные ченджсеты выводятся как предупреждение и ошибкой не считаются
+func (e *projectErrors) report(w io.Writer) error {
+	if len(e.skipped) > 0 {
+		fmt.Fprintf(w, "Внимание: пропущены ченджсеты, которые не удалось получить (%d), данные по ним не учтены:\n", len(e.skipped))
+		for _, skipped := range e.skipped {
+			fmt.Fprintf(w, "\t%s: %v\n", skipped.project, skipped.err)
+		}
+	}
+	if len(e.errors) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "Не удалось обработать проектов: %d из %d\n", len(e.errors), e.total)
+	for _, projectErr := range e.errors {
+		fmt.Fprintf(w, "\t%s: %v\n", projectErr.project, projectErr.err)
+	}
+	if e.stopped {
+		fmt.Fprintln(w, "Обработка остановлена после первой ошибки (--fail-fast)")
+	}
+	return e
+}
+
+// Результат загрузки проекта
+type projectResult struct {
+	// Выводит результат. Может быть задана и при ошибке: тогда выводятся неполные данные
+	output func() error
+	// Ченджсеты, пропущенные с --skip-failed
+	skipped []*repointerface.ChangesetError
+}
+
+// Результат обработки проекта, который не выводится из-за остановки по --fail-fast
+var errNotProcessed = errors.New("not processed")
+
+// runProjects загружает проекты параллельно (не больше jobs одновременно). Функция process выполняется
+// в отдельной горутине и возвращает функцию вывода результата. Функции вывода вызываются последовательно
+// в порядке projects, поэтому вывод разных проектов не перемешивается. Ошибки проектов собираются в сводку,
+// а с failFast после первой ошибки новые проекты не запускаются и дальнейшие результаты не выводятся
+func runProjects(projects []string, opts *parallelFlags, process func(project string) (projectResult, error)) *projectErrors {
+	jobs := opts.jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	type result struct {
+		projectResult
+		err error
+	}
+	results := make([]chan result, len(projects))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	var stop int32
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		limit := make(chan struct{}, jobs)
+		for i, project := range projects {
+			limit <- struct{}{}
+			if atomic.LoadInt32(&stop) == 1 {
+				<-limit
+				results[i] <- result{err: errNotProcessed}
+				continue
+			}
+			wg.Add(1)
+			go func(i int, project string) {
+				defer wg.Done()
+				defer func() { <-limit }()
+				res, err := process(project)
+				if err != nil && opts.failFast {
+					atomic.StoreInt32(&stop, 1)
+				}
+				results[i] <- result{projectResult: res, err: err}
+			}(i, project)
+		}
+	}()
+
+	errs := &projectErrors{total: len(projects)}
+	for i, project := range projects {
+		res := <-results[i]
+		if res.err == errNotProcessed {
+			continue
+		}
+		for _, skipped := range res.skipped {
+			errs.skipped = append(errs.skipped, projectError{project: project, err: skipped})
+		}
+		if res.output != nil {
+			if err := res.output(); res.err == nil {
+				res.err = err
+			}
+		}
+		if res.err != nil {
+			errs.errors = append(errs.errors, projectError{project: project, err: res.err})
+			if opts.failFast {
+				atomic.StoreInt32(&stop, 1)
+				errs.stopped = i < len(projects)-1
+				break
+			}
+		}
+	}
+	// Запущенные проекты дожидаемся, даже если их результат уже не нужен: они используют кеш,
+	// который закрывается после выполнения команды
+	wg.Wait()
+	return errs
+}
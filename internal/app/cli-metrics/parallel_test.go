@@ -0,0 +1,159 @@
+package cli_metrics
+
+import (
+	"bytes"
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"go-marathon-team-3/pkg/tfsmetrics/filter"
+	"go-marathon-team-3/pkg/tfsmetrics/mock/mock_azure"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_runProjects(t *testing.T) {
+	projects := []string{"a", "b", "c", "d", "e"}
+	// Проекты завершаются в обратном порядке, а выводиться должны в исходном
+	delays := map[string]time.Duration{"a": 40 * time.Millisecond, "b": 30 * time.Millisecond, "c": 20 * time.Millisecond}
+	failed := map[string]bool{"b": true, "d": true}
+
+	tests := []struct {
+		name     string
+		failFast bool
+		output   []string
+		errors   []string
+		stopped  bool
+	}{
+		{
+			name:   "continue on error",
+			output: []string{"a", "c", "e"},
+			errors: []string{"b", "d"},
+		},
+		{
+			name:     "fail fast",
+			failFast: true,
+			output:   []string{"a"},
+			errors:   []string{"b"},
+			stopped:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, maxRunning int32
+			output := []string{}
+			errs := runProjects(projects, &parallelFlags{jobs: 3, failFast: tt.failFast}, func(project string) (projectResult, error) {
+				current := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+					if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+						break
+					}
+				}
+				time.Sleep(delays[project])
+				if failed[project] {
+					return projectResult{}, errors.New("timeout")
+				}
+				return projectResult{output: func() error {
+					output = append(output, project)
+					return nil
+				}}, nil
+			})
+			assert.Equal(t, tt.output, output)
+			assert.LessOrEqual(t, maxRunning, int32(3))
+			require.Len(t, errs.errors, len(tt.errors))
+			for i, project := range tt.errors {
+				assert.Equal(t, project, errs.errors[i].project)
+			}
+			assert.Equal(t, tt.stopped, errs.stopped)
+		})
+	}
+}
+
+func Test_projectErrors_report(t *testing.T) {
+	var buf bytes.Buffer
+	errs := &projectErrors{total: 3}
+	assert.NoError(t, errs.report(&buf))
+	assert.Empty(t, buf.String())
+
+	// Пропущенные ченджсеты - только предупреждение
+	errs.skipped = []projectError{{project: "a", err: &repointerface.ChangesetError{Id: 7, Err: errors.New("timeout")}}}
+	assert.NoError(t, errs.report(&buf))
+	assert.Equal(t, "Внимание: пропущены ченджсеты, которые не удалось получить (1), данные по ним не учтены:\n"+
+		"\ta: ченджсет 7: timeout\n", buf.String())
+
+	buf.Reset()
+	errs.skipped = nil
+	errs.errors = []projectError{{project: "b", err: errors.New("timeout")}}
+	errs.stopped = true
+	err := errs.report(&buf)
+	assert.EqualError(t, err, "не удалось обработать проектов: 1 из 3")
+	assert.Equal(t, "Не удалось обработать проектов: 1 из 3\n\tb: timeout\n"+
+		"Обработка остановлена после первой ошибки (--fail-fast)\n", buf.String())
+}
+
+func Test_projectLoader_load(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedAzure := mock_azure.NewMockAzureInterface(ctrl)
+	mockedAzure.EXPECT().TfvcClientConnection().Return(nil).AnyTimes()
+	ids := []int{1, 2, 3}
+	mockedAzure.EXPECT().GetChangesets("project").Return([]*int{&ids[0], &ids[1], &ids[2]}, nil).AnyTimes()
+	mockedAzure.EXPECT().GetChangesetChanges(gomock.Any(), "project").DoAndReturn(func(id *int, project string) (*azure.ChangeSet, error) {
+		if *id == 2 {
+			return nil, errors.New("timeout")
+		}
+		return &azure.ChangeSet{Id: *id, Author: "Ivan"}, nil
+	}).AnyTimes()
+	opts := &logOptions{filter: &filter.Options{}}
+	tests := []struct {
+		name       string
+		skipFailed bool
+		commits    []int
+		err        string
+		skipped    int
+	}{
+		{
+			name:    "partial data",
+			commits: []int{1},
+			err:     "данные неполные, получены не все коммиты: ченджсет 2: timeout",
+		},
+		{
+			name:       "skip failed",
+			skipFailed: true,
+			commits:    []int{1, 3},
+			skipped:    2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := &projectLoader{azure: mockedAzure, opts: &parallelFlags{skipFailed: tt.skipFailed}}
+			commits := []int{}
+			res, err := loader.load("project", opts, func(iter repointerface.CommitIterator) error {
+				for commit, err := iter.Next(); err == nil; commit, err = iter.Next() {
+					commits = append(commits, commit.Id)
+				}
+				return nil
+			})
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+			require.NotNil(t, res.output)
+			assert.NoError(t, res.output())
+			assert.Equal(t, tt.commits, commits)
+			if tt.skipped == 0 {
+				assert.Empty(t, res.skipped)
+			} else {
+				require.Len(t, res.skipped, 1)
+				assert.Equal(t, tt.skipped, res.skipped[0].Id)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package cli_metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Профиль, который используется, если другой не выбран
+const defaultProfile = "default"
+
+// Название профиля входит в имя файла кеша, поэтому допускаются только безопасные символы
+var profileNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Именованный профиль подключения к Azure DevOps / TFS
+type profile struct {
+	OrganizationUrl string `json:"organization_url"`
+	Token           string `json:"personal_access_token"`
+	// Токен, зашифрованный паролем (см. encryptToken). Используется, если Token не задан
+	EncryptedToken string `json:"encrypted_token,omitempty"`
+	// Способ аутентификации (по умолчанию personal access token)
+	AuthType azure.AuthType `json:"auth_type,omitempty"`
+	// Имя пользователя для basic-аутентификации, токен при этом используется как пароль
+	Username string            `json:"username,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	// Прокси, сертификаты и пул соединений
+	Transport azure.TransportConfig `json:"transport"`
+	// Проект, который используется командами, если проект не указан явно
+	Project      string `json:"project,omitempty"`
+	CacheEnabled bool   `json:"cache-enabled"`
+}
+
+func newProfile() *profile {
+	return &profile{CacheEnabled: true}
+}
+
+// resolveToken расшифровывает сохраненный токен, если открытый токен не задан
+func (p *profile) resolveToken(passphrase func() (string, error)) error {
+	if p.Token != "" || p.EncryptedToken == "" {
+		return nil
+	}
+	value, err := passphrase()
+	if err != nil {
+		return err
+	}
+	p.Token, err = decryptToken(p.EncryptedToken, value)
+	return err
+}
+
+// displayToken возвращает токен в виде, пригодном для вывода на экран
+func (p *profile) displayToken() string {
+	if p.Token == "" && p.EncryptedToken != "" {
+		return "(зашифрован)"
+	}
+	return maskToken(p.Token)
+}
+
+func (p *profile) azureConfig() *azure.Config {
+	config := azure.NewConfig()
+	config.OrganizationUrl = p.OrganizationUrl
+	config.Token = p.Token
+	config.AuthType = p.AuthType
+	config.Username = p.Username
+	config.Headers = p.Headers
+	config.Transport = p.Transport
+	return config
+}
+
+// authDescription описывает способ аутентификации для вывода на экран (без секретов)
+func (p *profile) authDescription() string {
+	auth := string(p.AuthType)
+	if auth == "" {
+		auth = string(azure.AuthPAT)
+	}
+	if p.AuthType == azure.AuthBasic {
+		auth += " (" + p.Username + ")"
+	}
+	if len(p.Headers) > 0 {
+		names := make([]string, 0, len(p.Headers))
+		for name := range p.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		auth += ", заголовки: " + strings.Join(names, ", ")
+	}
+	return auth
+}
+
+func validateAuthType(authType string) error {
+	switch azure.AuthType(authType) {
+	case azure.AuthPAT, azure.AuthBasic, azure.AuthBearer, azure.AuthNone:
+		return nil
+	}
+	return fmt.Errorf("неизвестный способ аутентификации '%s', доступны: %s, %s, %s, %s",
+		authType, azure.AuthPAT, azure.AuthBasic, azure.AuthBearer, azure.AuthNone)
+}
+
+// parseHeaders разбирает заголовки вида "Name: value". Пустая строка удаляет все заголовки
+func parseHeaders(values []string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		parts := strings.SplitN(value, ":", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, fmt.Errorf("некорректный заголовок '%s', ожидается 'Name: value'", value)
+		}
+		headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(parts[1])
+	}
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	return headers, nil
+}
+
+// Содержимое configs/config.json: профили подключения и текущий профиль
+type profilesConfig struct {
+	Current  string              `json:"current-profile"`
+	Profiles map[string]*profile `json:"profiles"`
+}
+
+func newProfilesConfig() *profilesConfig {
+	return &profilesConfig{
+		Current:  defaultProfile,
+		Profiles: map[string]*profile{},
+	}
+}
+
+func validateProfileName(name string) error {
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("некорректное название профиля '%s': допускаются латинские буквы, цифры, '.', '-' и '_'", name)
+	}
+	return nil
+}
+
+// Get возвращает профиль по названию (пустое название - текущий профиль)
+func (c *profilesConfig) Get(name string) (string, *profile, bool) {
+	if name == "" {
+		name = c.Current
+	}
+	p, ok := c.Profiles[name]
+	return name, p, ok
+}
+
+// GetOrCreate возвращает профиль по названию, создавая его при отсутствии
+func (c *profilesConfig) GetOrCreate(name string) (string, *profile, error) {
+	name, p, ok := c.Get(name)
+	if ok {
+		return name, p, nil
+	}
+	if err := validateProfileName(name); err != nil {
+		return "", nil, err
+	}
+	p = newProfile()
+	c.Profiles[name] = p
+	return name, p, nil
+}
+
+func (c *profilesConfig) Use(name string) error {
+	if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("профиль '%s' не найден", name)
+	}
+	c.Current = name
+	return nil
+}
+
+// Remove удаляет профиль. Если удален текущий профиль, текущим становится профиль по умолчанию
+func (c *profilesConfig) Remove(name string) error {
+	if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("профиль '%s' не найден", name)
+	}
+	delete(c.Profiles, name)
+	if c.Current == name {
+		c.Current = defaultProfile
+	}
+	return nil
+}
+
+func (c *profilesConfig) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// namespace возвращает пространство имен кеша профиля. Профиль по умолчанию
+// использует общий кеш, созданный до появления профилей
+func namespace(name string) string {
+	if name == defaultProfile {
+		return ""
+	}
+	return name
+}
+
+// readProfiles читает профили. Конфигурация старого формата (одна пара url/token)
+// превращается в профиль по умолчанию, а признак включения кеша для него берется
+// из cli-settings.json, где он хранился раньше
+func readProfiles(filePath string) (*profilesConfig, error) {
+	config := newProfilesConfig()
+	ex, _ := exists(filePath)
+	if !ex {
+		return config, nil
+	}
+	checkPermissions(filePath)
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	if config.Profiles == nil {
+		config.Profiles = map[string]*profile{}
+	}
+	if len(config.Profiles) == 0 {
+		legacy := newProfile()
+		if err = json.Unmarshal(data, legacy); err != nil {
+			return nil, err
+		}
+		if legacy.OrganizationUrl != "" || legacy.Token != "" || legacy.EncryptedToken != "" {
+			if err = readLegacyCacheSetting(settingsFilePath(filePath), legacy); err != nil {
+				return nil, err
+			}
+			config.Profiles[defaultProfile] = legacy
+		}
+	}
+	if config.Current == "" {
+		config.Current = defaultProfile
+	}
+	return config, nil
+}
+
+// readLegacyCacheSetting переносит в профиль признак включения кеша из файла настроек старого формата
+func readLegacyCacheSetting(settingsPath string, p *profile) error {
+	ex, _ := exists(settingsPath)
+	if !ex {
+		return nil
+	}
+	data, err := ioutil.ReadFile(settingsPath)
+	if err != nil {
+		return err
+	}
+	var legacy struct {
+		CacheEnabled *bool `json:"cache-enabled"`
+	}
+	if err = json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	if legacy.CacheEnabled != nil {
+		p.CacheEnabled = *legacy.CacheEnabled
+	}
+	return nil
+}
+
+func writeProfiles(filePath string, config *profilesConfig) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeSecureFile(filePath, append(data, '\n'))
+}
+
+// projectOrDefault возвращает name, а если он не задан - проект профиля по умолчанию
+func (p *profile) projectOrDefault(name string) string {
+	if name == "" && p != nil {
+		return p.Project
+	}
+	return name
+}
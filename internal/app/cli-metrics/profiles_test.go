@@ -0,0 +1,131 @@
+package cli_metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profiles")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, "config.json")
+	settingsPath := filepath.Join(dir, "cli-settings.json")
+
+	tests := []struct {
+		name     string
+		content  string
+		settings string
+		current  string
+		profiles map[string]*profile
+	}{
+		{
+			name:     "no file",
+			current:  defaultProfile,
+			profiles: map[string]*profile{},
+		},
+		{
+			name:    "legacy",
+			content: `{"organization_url":"https://dev.azure.com/org","personal_access_token":"12345"}`,
+			current: defaultProfile,
+			profiles: map[string]*profile{
+				defaultProfile: {OrganizationUrl: "https://dev.azure.com/org", Token: "12345", CacheEnabled: true},
+			},
+		},
+		{
+			name:     "legacy with cache setting",
+			content:  `{"organization_url":"https://dev.azure.com/org","personal_access_token":"12345"}`,
+			settings: `{"cache-enabled":false,"exporter-port":8080}`,
+			current:  defaultProfile,
+			profiles: map[string]*profile{
+				defaultProfile: {OrganizationUrl: "https://dev.azure.com/org", Token: "12345"},
+			},
+		},
+		{
+			name:     "legacy without cache setting",
+			content:  `{"organization_url":"https://dev.azure.com/org","personal_access_token":"12345"}`,
+			settings: `{"exporter-port":8080}`,
+			current:  defaultProfile,
+			profiles: map[string]*profile{
+				defaultProfile: {OrganizationUrl: "https://dev.azure.com/org", Token: "12345", CacheEnabled: true},
+			},
+		},
+		{
+			name:     "empty legacy",
+			content:  `{"organization_url":"","personal_access_token":""}`,
+			current:  defaultProfile,
+			profiles: map[string]*profile{},
+		},
+		{
+			name: "profiles",
+			content: `{"current-profile":"tfs","profiles":{
+				"tfs":{"organization_url":"http://tfs:8080/tfs/collection","personal_access_token":"1","project":"main","cache-enabled":false},
+				"org":{"organization_url":"https://dev.azure.com/org","personal_access_token":"2","cache-enabled":true}}}`,
+			current: "tfs",
+			profiles: map[string]*profile{
+				"tfs": {OrganizationUrl: "http://tfs:8080/tfs/collection", Token: "1", Project: "main"},
+				"org": {OrganizationUrl: "https://dev.azure.com/org", Token: "2", CacheEnabled: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(configPath)
+			os.Remove(settingsPath)
+			if tt.content != "" {
+				require.NoError(t, ioutil.WriteFile(configPath, []byte(tt.content), 0600))
+			}
+			if tt.settings != "" {
+				require.NoError(t, ioutil.WriteFile(settingsPath, []byte(tt.settings), 0600))
+			}
+			config, err := readProfiles(configPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.current, config.Current)
+			assert.Equal(t, tt.profiles, config.Profiles)
+		})
+	}
+}
+
+func TestProfilesConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profiles")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, "config.json")
+
+	config := newProfilesConfig()
+	_, _, err = config.GetOrCreate("../assets")
+	assert.Error(t, err)
+
+	name, work, err := config.GetOrCreate("work")
+	require.NoError(t, err)
+	assert.Equal(t, "work", name)
+	assert.True(t, work.CacheEnabled)
+	work.OrganizationUrl = "https://dev.azure.com/work"
+	work.Project = "main"
+	_, _, err = config.GetOrCreate("home")
+	require.NoError(t, err)
+
+	assert.Error(t, config.Use("unknown"))
+	require.NoError(t, config.Use("work"))
+	require.NoError(t, writeProfiles(configPath, config))
+
+	config, err = readProfiles(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"home", "work"}, config.Names())
+	name, current, ok := config.Get("")
+	assert.True(t, ok)
+	assert.Equal(t, "work", name)
+	assert.Equal(t, "main", current.projectOrDefault(""))
+	assert.Equal(t, "other", current.projectOrDefault("other"))
+
+	require.NoError(t, config.Remove("work"))
+	assert.Equal(t, defaultProfile, config.Current)
+	assert.Error(t, config.Remove("work"))
+	assert.Equal(t, "", namespace(defaultProfile))
+	assert.Equal(t, "home", namespace("home"))
+}
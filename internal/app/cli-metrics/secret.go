@@ -0,0 +1,229 @@
+package cli_metrics
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/term"
+)
+
+// Переменная с паролем для шифрования токена (если не задана, пароль запрашивается в консоли)
+const envPassphrase = "TFC_PASSPHRASE"
+
+// Формат зашифрованного токена: префикс версии и base64(соль | nonce | шифротекст AES-GCM)
+const (
+	encryptedTokenPrefix = "v1:"
+	saltSize             = 16
+	keySize              = 32
+	pbkdf2Iterations     = 200000
+)
+
+// Права на файлы с токенами: чтение и запись только владельцем
+const secureFileMode = 0600
+
+// Ввод пользователя читается через один буфер, чтобы токен и пароль можно было передать в stdin подряд
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// maskToken скрывает токен при выводе, оставляя последние 4 символа для сверки
+func maskToken(token string) string {
+	if token == "" {
+		return ""
+	}
+	if len(token) <= 8 {
+		return "****"
+	}
+	return "****" + token[len(token)-4:]
+}
+
+// readLine выводит приглашение в stderr (чтобы не смешивать его с результатом команды) и читает строку из stdin
+func readLine(prompt string) (string, error) {
+	if prompt != "" {
+		fmt.Fprint(os.Stderr, prompt)
+	}
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readSecret читает токен или пароль. Если stdin - терминал, ввод не отображается на экране,
+// иначе (токен передан через канал или файл) строка читается как обычно
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return readLine(prompt)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	// Перевод строки, введенный пользователем, не выводится
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// readTokenFile читает токен из файла, отбрасывая пробелы и перевод строки в конце
+func readTokenFile(filePath string) (string, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("файл '%s' не содержит токен", filePath)
+	}
+	return token, nil
+}
+
+// Источники токена для команды config: значение флага, файл, переменная окружения или stdin
+type tokenSource struct {
+	value string
+	file  string
+	env   string
+	stdin bool
+}
+
+// read возвращает токен из заданного источника или пустую строку, если источник не задан
+func (s *tokenSource) read() (string, error) {
+	sources := 0
+	for _, set := range []bool{s.value != "", s.file != "", s.env != "", s.stdin} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", errors.New("укажите только один источник токена: --token, --token-file, --token-env или --token-stdin")
+	}
+	switch {
+	case s.file != "":
+		return readTokenFile(s.file)
+	case s.env != "":
+		token, ok := os.LookupEnv(s.env)
+		if !ok || token == "" {
+			return "", fmt.Errorf("переменная окружения %s не задана", s.env)
+		}
+		return token, nil
+	case s.stdin:
+		token, err := readSecret("Personal access token: ")
+		if err != nil {
+			return "", err
+		}
+		if token = strings.TrimSpace(token); token == "" {
+			return "", errors.New("токен не может быть пустым")
+		}
+		return token, nil
+	}
+	return s.value, nil
+}
+
+// readPassphrase возвращает пароль из TFC_PASSPHRASE или запрашивает его в консоли
+func readPassphrase() (string, error) {
+	if passphrase, ok := os.LookupEnv(envPassphrase); ok {
+		return passphrase, nil
+	}
+	passphrase, err := readSecret("Пароль для токена: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("пароль для токена не может быть пустым")
+	}
+	return passphrase, nil
+}
+
+func newTokenCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(passphrase), salt, pbkdf2Iterations, keySize, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptToken шифрует токен ключом, полученным из пароля
+func encryptToken(token, passphrase string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	aead, err := newTokenCipher(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	data := append(salt, nonce...)
+	data = aead.Seal(data, nonce, []byte(token), nil)
+	return encryptedTokenPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func decryptToken(value, passphrase string) (string, error) {
+	if !strings.HasPrefix(value, encryptedTokenPrefix) {
+		return "", errors.New("неизвестный формат зашифрованного токена")
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedTokenPrefix))
+	if err != nil {
+		return "", err
+	}
+	if len(data) < saltSize {
+		return "", errors.New("зашифрованный токен поврежден")
+	}
+	aead, err := newTokenCipher(passphrase, data[:saltSize])
+	if err != nil {
+		return "", err
+	}
+	data = data[saltSize:]
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("зашифрованный токен поврежден")
+	}
+	token, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("неверный пароль для токена")
+	}
+	return string(token), nil
+}
+
+// writeSecureFile записывает файл с правами 0600, в том числе если он уже существовал с более широкими правами
+func writeSecureFile(filePath string, data []byte) error {
+	// Права меняются до записи, чтобы новое содержимое не было доступно другим пользователям
+	err := os.Chmod(filePath, secureFileMode)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return ioutil.WriteFile(filePath, data, secureFileMode)
+}
+
+// Файлы, о правах на которые уже предупредили (конфигурация читается за запуск несколько раз)
+var permissionWarnings = map[string]bool{}
+
+// checkPermissions предупреждает, если файл с токенами доступен другим пользователям.
+// В Windows права доступа устроены иначе, поэтому проверка не выполняется
+func checkPermissions(filePath string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 && !permissionWarnings[filePath] {
+		permissionWarnings[filePath] = true
+		fmt.Fprintf(os.Stderr, "Внимание: файл %s доступен другим пользователям (права %04o), выполните chmod 600 %s\n",
+			filePath, perm, filePath)
+	}
+}
@@ -0,0 +1,105 @@
+package cli_metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  string
+	}{
+		{"", ""},
+		{"12345", "****"},
+		{"abcdefghijklmnopqrstuvwxyz", "****wxyz"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, maskToken(tt.token))
+	}
+}
+
+func TestDecryptToken_compatibility(t *testing.T) {
+	// Токен, зашифрованный предыдущей версией (PBKDF2-HMAC-SHA256, 200000 итераций)
+	token, err := decryptToken("v1:LApoZikA2RAFniTNJJVFYK6l+1XHZcJQyNNHuIml2LteIFf+sqon6zTibQe9q08myg==", "passphrase")
+	require.NoError(t, err)
+	assert.Equal(t, "12345", token)
+}
+
+func TestEncryptToken(t *testing.T) {
+	encrypted, err := encryptToken("12345", "passphrase")
+	require.NoError(t, err)
+	assert.NotContains(t, encrypted, "12345")
+
+	token, err := decryptToken(encrypted, "passphrase")
+	assert.NoError(t, err)
+	assert.Equal(t, "12345", token)
+
+	_, err = decryptToken(encrypted, "wrong")
+	assert.Error(t, err)
+	_, err = decryptToken("12345", "passphrase")
+	assert.Error(t, err)
+
+	p := &profile{EncryptedToken: encrypted}
+	assert.Equal(t, "(зашифрован)", p.displayToken())
+	require.NoError(t, p.resolveToken(func() (string, error) { return "passphrase", nil }))
+	assert.Equal(t, "12345", p.Token)
+}
+
+func TestTokenSource_read(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("from-file\n"), 0600))
+	os.Setenv("TFC_TEST_TOKEN", "from-env")
+	defer os.Unsetenv("TFC_TEST_TOKEN")
+
+	tests := []struct {
+		name    string
+		source  tokenSource
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", source: tokenSource{}},
+		{name: "value", source: tokenSource{value: "12345"}, want: "12345"},
+		{name: "file", source: tokenSource{file: tokenFile}, want: "from-file"},
+		{name: "env", source: tokenSource{env: "TFC_TEST_TOKEN"}, want: "from-env"},
+		{name: "missing env", source: tokenSource{env: "TFC_TEST_MISSING"}, wantErr: true},
+		{name: "several sources", source: tokenSource{value: "12345", file: tokenFile}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.source.read()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, token)
+		})
+	}
+}
+
+func TestWriteSecureFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("права доступа в Windows не проверяются")
+	}
+	dir, err := ioutil.TempDir("", "secure")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "config.json")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("{}"), 0644))
+	require.NoError(t, os.Chmod(filePath, 0644))
+
+	require.NoError(t, writeSecureFile(filePath, []byte("{}")))
+	info, err := os.Stat(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
@@ -0,0 +1,125 @@
+package cli_metrics
+
+import (
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	colorReset = "\033[0m"
+	colorBold  = "\033[1m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorCyan  = "\033[36m"
+)
+
+// Параметры вывода команды show
+type showOptions struct {
+	diff    bool
+	context int
+	color   bool
+}
+
+// isTerminal сообщает, выводится ли результат в терминал (иначе цвета не нужны)
+func isTerminal(file *os.File) bool {
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return []string{}
+	}
+	return strings.Split(content, "\n")
+}
+
+func fileDiff(file *azure.FileChange, context int) string {
+	return azure.UnifiedDiff(file.Path, file.Path, splitLines(file.Previous), splitLines(file.Current), context)
+}
+
+func printChangeset(w io.Writer, details *azure.ChangeSetDetails, opts *showOptions) {
+	fmt.Fprintf(w, "Ченджсет %d\n", details.Id)
+	fmt.Fprintf(w, "Автор: %s <%s>\n", details.Author, details.Email)
+	fmt.Fprintf(w, "Дата: %s\n", details.Date.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "%d строк добавлено и %d строк удалено\n", details.AddedRows, details.DeletedRows)
+	fmt.Fprintf(w, "Сообщение:\n\n\t%s\n\n", details.Message)
+	fmt.Fprintf(w, "Измененные файлы (%d):\n", len(details.Changes))
+	for _, file := range details.Changes {
+		counts := fmt.Sprintf("+%d -%d", file.AddedRows, file.DeletedRows)
+		if file.Binary {
+			counts = "бинарный"
+		}
+		fmt.Fprintf(w, "\t%-10s %-12s %s\n", file.ChangeType, counts, file.Path)
+	}
+	if !opts.diff {
+		return
+	}
+	for i := range details.Changes {
+		file := &details.Changes[i]
+		if file.Binary {
+			continue
+		}
+		fmt.Fprintln(w)
+		printDiff(w, fileDiff(file, opts.context), opts.color)
+	}
+}
+
+// printDiff выводит unified diff, при color = true раскрашивая его как git diff
+func printDiff(w io.Writer, diff string, color bool) {
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line == "" {
+			continue
+		}
+		if !color {
+			fmt.Fprint(w, line)
+			continue
+		}
+		text := strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			fmt.Fprintln(w, colorBold+text+colorReset)
+		case strings.HasPrefix(line, "@@"):
+			fmt.Fprintln(w, colorCyan+text+colorReset)
+		case strings.HasPrefix(line, "-"):
+			fmt.Fprintln(w, colorRed+text+colorReset)
+		case strings.HasPrefix(line, "+"):
+			fmt.Fprintln(w, colorGreen+text+colorReset)
+		default:
+			fmt.Fprintln(w, text)
+		}
+	}
+}
+
+func renderChangeset(out renderer, details *azure.ChangeSetDetails, opts *showOptions) error {
+	columns := []string{"changeset", "author", "email", "date", "path", "change_type", "added_rows", "deleted_rows"}
+	if opts.diff {
+		columns = append(columns, "diff")
+	}
+	err := out.Header(columns...)
+	if err != nil {
+		return err
+	}
+	for i := range details.Changes {
+		file := &details.Changes[i]
+		values := []interface{}{details.Id, details.Author, details.Email, details.Date,
+			file.Path, file.ChangeType, file.AddedRows, file.DeletedRows}
+		if opts.diff {
+			diff := ""
+			if !file.Binary {
+				diff = fileDiff(file, opts.context)
+			}
+			values = append(values, diff)
+		}
+		err = out.Row(values...)
+		if err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
@@ -0,0 +1,65 @@
+package cli_metrics
+
+import (
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics"
+	"io"
+	"time"
+)
+
+// Как часто выводить прогресс, если вывод перенаправлен в файл (в терминале строка обновляется на месте)
+const progressInterval = 5 * time.Second
+
+// progressPrinter выводит прогресс синхронизации: в терминале одной обновляемой строкой,
+// в остальных случаях отдельными строками не чаще progressInterval
+type progressPrinter struct {
+	w        io.Writer
+	terminal bool
+	interval time.Duration
+	last     time.Time
+}
+
+func newProgressPrinter(w io.Writer, terminal bool) *progressPrinter {
+	return &progressPrinter{w: w, terminal: terminal, interval: progressInterval}
+}
+
+func (p *progressPrinter) update(progress tfsmetrics.SyncProgress) {
+	if p.terminal {
+		// \033[K стирает остаток предыдущей, более длинной строки
+		fmt.Fprintf(p.w, "\r%s\033[K", formatProgress(progress))
+		return
+	}
+	if time.Since(p.last) < p.interval && progress.Done < progress.Total {
+		return
+	}
+	p.last = time.Now()
+	fmt.Fprintln(p.w, formatProgress(progress))
+}
+
+// finish выводит итог синхронизации проекта
+func (p *progressPrinter) finish(progress tfsmetrics.SyncProgress, err error) {
+	if p.terminal {
+		fmt.Fprintln(p.w)
+	}
+	p.last = time.Time{}
+	if err != nil {
+		fmt.Fprintf(p.w, "%s: ошибка после %d из %d ченджсетов: %v\n"+
+			"       Загруженные данные сохранены, повторный запуск sync продолжит с места остановки\n",
+			progress.Project, progress.Done, progress.Total, err)
+		return
+	}
+	fmt.Fprintf(p.w, "%s: готово, загружено ченджсетов: %d из %d за %s, запросов к API: %d\n",
+		progress.Project, progress.Fetched, progress.Total, progress.Elapsed.Round(time.Second), progress.APICalls)
+}
+
+func formatProgress(progress tfsmetrics.SyncProgress) string {
+	percent := 100
+	if progress.Total > 0 {
+		percent = progress.Done * 100 / progress.Total
+	}
+	line := fmt.Sprintf("%s: %d/%d (%d%%), запросов к API: %d", progress.Project, progress.Done, progress.Total, percent, progress.APICalls)
+	if eta := progress.ETA(); eta > 0 {
+		line += fmt.Sprintf(", осталось ~%s", eta.Round(time.Second))
+	}
+	return line
+}
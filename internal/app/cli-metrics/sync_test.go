@@ -0,0 +1,61 @@
+package cli_metrics
+
+import (
+	"bytes"
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_formatProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress tfsmetrics.SyncProgress
+		want     string
+	}{
+		{
+			name:     "started",
+			progress: tfsmetrics.SyncProgress{Project: "prj", Total: 40},
+			want:     "prj: 0/40 (0%), запросов к API: 0",
+		},
+		{
+			name:     "with eta",
+			progress: tfsmetrics.SyncProgress{Project: "prj", Done: 10, Total: 40, APICalls: 30, Elapsed: 10 * time.Second},
+			want:     "prj: 10/40 (25%), запросов к API: 30, осталось ~30s",
+		},
+		{
+			name:     "empty project",
+			progress: tfsmetrics.SyncProgress{Project: "prj"},
+			want:     "prj: 0/0 (100%), запросов к API: 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatProgress(tt.progress))
+		})
+	}
+}
+
+func Test_progressPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	printer := newProgressPrinter(&buf, false)
+	printer.update(tfsmetrics.SyncProgress{Project: "prj", Done: 1, Total: 3})
+	// Промежуточный прогресс чаще progressInterval не выводится
+	printer.update(tfsmetrics.SyncProgress{Project: "prj", Done: 2, Total: 3})
+	printer.update(tfsmetrics.SyncProgress{Project: "prj", Done: 3, Total: 3})
+	printer.finish(tfsmetrics.SyncProgress{Project: "prj", Done: 3, Total: 3, Fetched: 2, APICalls: 6}, nil)
+	assert.Equal(t, "prj: 1/3 (33%), запросов к API: 0\n"+
+		"prj: 3/3 (100%), запросов к API: 0\n"+
+		"prj: готово, загружено ченджсетов: 2 из 3 за 0s, запросов к API: 6\n", buf.String())
+
+	buf.Reset()
+	printer = newProgressPrinter(&buf, true)
+	printer.update(tfsmetrics.SyncProgress{Project: "prj", Done: 1, Total: 3})
+	printer.finish(tfsmetrics.SyncProgress{Project: "prj", Done: 1, Total: 3}, errors.New("timeout"))
+	assert.Equal(t, "\rprj: 1/3 (33%), запросов к API: 0\033[K\n"+
+		"prj: ошибка после 1 из 3 ченджсетов: timeout\n"+
+		"       Загруженные данные сохранены, повторный запуск sync продолжит с места остановки\n", buf.String())
+}
@@ -0,0 +1,251 @@
+package cli_metrics
+
+import (
+	"errors"
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/aggregate"
+	"go-marathon-team-3/pkg/tfsmetrics/filter"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Флаги команды trend
+type trendFlags struct {
+	period   string
+	by       string
+	timezone string
+	last     int
+	metric   string
+}
+
+func (f *trendFlags) flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "period",
+			Usage:       "период: day, week или month",
+			Value:       string(aggregate.Week),
+			Destination: &f.period,
+		},
+		&cli.IntFlag{
+			Name:        "last",
+			Usage:       "только последние N периодов, включая текущий (вместо --since)",
+			Destination: &f.last,
+		},
+		&cli.StringFlag{
+			Name:        "by",
+			Usage:       "отдельный ряд для каждого значения признаков через запятую, например team или project,author",
+			Destination: &f.by,
+		},
+		newTimezoneFlag(&f.timezone, "часовой пояс, в котором определяются периоды, например Europe/Moscow или UTC (по умолчанию локальный)"),
+		&cli.StringFlag{
+			Name:        "metric",
+			Usage:       "значение для графика в текстовом выводе: " + strings.Join(trendMetricNames, ", "),
+			Value:       trendMetricNames[0],
+			Destination: &f.metric,
+		},
+	}
+}
+
+// newTimezoneFlag возвращает флаг часового пояса (название из базы IANA, см. parseLocation)
+func newTimezoneFlag(destination *string, usage string) cli.Flag {
+	return &cli.StringFlag{
+		Name:        "timezone",
+		Aliases:     []string{"tz"},
+		Usage:       usage,
+		Destination: destination,
+	}
+}
+
+// parseLocation возвращает часовой пояс по названию из базы IANA (локальный, если название пустое)
+func parseLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("неизвестный часовой пояс '%s'", name)
+	}
+	return location, nil
+}
+
+// spec возвращает параметры ряда. Ряд заканчивается текущим периодом или --until, а с --last
+// начинается за last периодов до конца и ограничивает отбор коммитов этой датой
+func (f *trendFlags) spec(opts *filter.Options, now time.Time) (aggregate.TrendSpec, error) {
+	spec := aggregate.TrendSpec{Since: opts.Since, Until: opts.Until}
+	var err error
+	spec.Period, err = aggregate.ParsePeriod(f.period)
+	if err != nil {
+		return spec, err
+	}
+	if f.by != "" {
+		spec.By, err = aggregate.ParseDimensions(f.by)
+		if err != nil {
+			return spec, err
+		}
+		for _, dimension := range spec.By {
+			if dimension == aggregate.Day || dimension == aggregate.Week || dimension == aggregate.Month {
+				return spec, fmt.Errorf("--by не может содержать период '%s', период задает --period", dimension)
+			}
+		}
+	}
+	if trendMetric(f.metric) == nil {
+		return spec, fmt.Errorf("неизвестное значение --metric '%s', доступны: %s", f.metric, strings.Join(trendMetricNames, ", "))
+	}
+	spec.Location, err = parseLocation(f.timezone)
+	if err != nil {
+		return spec, err
+	}
+	if spec.Until.IsZero() {
+		spec.Until = now
+	}
+	if f.last > 0 {
+		if !opts.Since.IsZero() {
+			return spec, errors.New("укажите либо --last, либо --since")
+		}
+		end := aggregate.PeriodStart(spec.Period, spec.Until, spec.Location)
+		spec.Since = aggregate.AddPeriods(spec.Period, end, 1-f.last)
+		opts.Since = spec.Since
+	}
+	return spec, nil
+}
+
+// Значения, которые можно вывести графиком
+var trendMetricNames = []string{"commits", "churn", "added_rows", "deleted_rows", "authors"}
+
+var trendMetricTitles = map[string]string{
+	"commits":      "Коммиты",
+	"churn":        "Измененные строки",
+	"added_rows":   "Добавленные строки",
+	"deleted_rows": "Удаленные строки",
+	"authors":      "Авторы",
+}
+
+// trendMetric возвращает функцию, выбирающую значение из точки ряда (nil для неизвестного значения)
+func trendMetric(name string) func(point aggregate.Point) int {
+	switch name {
+	case "commits":
+		return func(point aggregate.Point) int { return point.Commits }
+	case "churn":
+		return aggregate.Point.Churn
+	case "added_rows":
+		return func(point aggregate.Point) int { return point.AddedRows }
+	case "deleted_rows":
+		return func(point aggregate.Point) int { return point.DeletedRows }
+	case "authors":
+		return func(point aggregate.Point) int { return point.Authors }
+	}
+	return nil
+}
+
+var periodTitles = map[aggregate.Dimension]string{
+	aggregate.Day:   "по дням",
+	aggregate.Week:  "по неделям",
+	aggregate.Month: "по месяцам",
+}
+
+// periodLabel возвращает начало периода для вывода: дату, а для месяца - год и месяц
+func periodLabel(period aggregate.Dimension, start time.Time) string {
+	if period == aggregate.Month {
+		return start.Format("2006-01")
+	}
+	return start.Format("2006-01-02")
+}
+
+// seriesLabel возвращает название ряда по значениям признаков
+func seriesLabel(by []aggregate.Dimension, keys []string) string {
+	if len(keys) == 0 {
+		return "Все коммиты"
+	}
+	labels := make([]string, len(keys))
+	for i, key := range keys {
+		switch {
+		case key != "":
+			labels[i] = key
+		case by[i] == aggregate.Team:
+			labels[i] = noTeam
+		default:
+			labels[i] = "-"
+		}
+	}
+	return strings.Join(labels, ", ")
+}
+
+// Символы графика от меньшего значения к большему
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline рисует значения символами разной высоты. Нулевые значения рисуются самым низким символом,
+// а ненулевые - хотя бы вторым, чтобы периоды без коммитов были видны
+func sparkline(values []int) string {
+	max := 0
+	for _, value := range values {
+		if value > max {
+			max = value
+		}
+	}
+	res := make([]rune, len(values))
+	for i, value := range values {
+		level := 0
+		if max > 0 {
+			level = (value*(len(sparkBars)-1) + max - 1) / max
+		}
+		res[i] = sparkBars[level]
+	}
+	return string(res)
+}
+
+// printTrend выводит ряды графиками с итогом, средним и максимумом за период
+func printTrend(w io.Writer, res *aggregate.TrendResult, metric string) {
+	if len(res.Periods) == 0 {
+		fmt.Fprintln(w, "Нет коммитов")
+		return
+	}
+	value := trendMetric(metric)
+	fmt.Fprintf(w, "%s %s с %s по %s:\n", trendMetricTitles[metric], periodTitles[res.Period],
+		periodLabel(res.Period, res.Periods[0]), periodLabel(res.Period, res.Periods[len(res.Periods)-1]))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, series := range res.Series {
+		values := make([]int, len(series.Points))
+		total, max := 0, 0
+		for i, point := range series.Points {
+			values[i] = value(point)
+			total += values[i]
+			if values[i] > values[max] {
+				max = i
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\tвсего %d, в среднем %.1f, максимум %d (%s)\n", seriesLabel(res.By, series.Keys),
+			sparkline(values), total, float64(total)/float64(len(values)), values[max], periodLabel(res.Period, res.Periods[max]))
+	}
+	tw.Flush()
+}
+
+// trendColumns возвращает колонки вывода рядов в машиночитаемых форматах
+func trendColumns(spec aggregate.TrendSpec) []string {
+	columns := []string{"period"}
+	for _, dimension := range spec.By {
+		columns = append(columns, string(dimension))
+	}
+	return append(columns, "commits", "authors", "added_rows", "deleted_rows", "churn")
+}
+
+// renderTrend выводит строку на каждый период каждого ряда, включая периоды без коммитов
+func renderTrend(out renderer, res *aggregate.TrendResult) error {
+	for i, period := range res.Periods {
+		for _, series := range res.Series {
+			point := series.Points[i]
+			row := []interface{}{periodLabel(res.Period, period)}
+			for _, key := range series.Keys {
+				row = append(row, key)
+			}
+			row = append(row, point.Commits, point.Authors, point.AddedRows, point.DeletedRows, point.Churn())
+			if err := out.Row(row...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
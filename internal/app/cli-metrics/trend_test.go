@@ -0,0 +1,82 @@
+package cli_metrics
+
+import (
+	"bytes"
+	"go-marathon-team-3/pkg/tfsmetrics/aggregate"
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/filter"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_sparkline(t *testing.T) {
+	assert.Equal(t, "▁▂▅█▁", sparkline([]int{0, 1, 5, 10, 0}))
+	assert.Equal(t, "▁▁", sparkline([]int{0, 0}))
+	assert.Equal(t, "", sparkline(nil))
+}
+
+func TestTrendFlags_spec(t *testing.T) {
+	now := time.Date(2021, 10, 6, 12, 0, 0, 0, time.UTC)
+	flags := &trendFlags{period: "week", last: 4, timezone: "UTC", metric: "churn"}
+	opts := &filter.Options{}
+	spec, err := flags.spec(opts, now)
+	require.NoError(t, err)
+	assert.Equal(t, aggregate.Week, spec.Period)
+	assert.Equal(t, time.Date(2021, 9, 13, 0, 0, 0, 0, time.UTC), spec.Since)
+	assert.Equal(t, now, spec.Until)
+	// Коммиты раньше первого периода не загружаются
+	assert.Equal(t, spec.Since, opts.Since)
+
+	tests := []struct {
+		name  string
+		flags trendFlags
+		opts  filter.Options
+	}{
+		{name: "period", flags: trendFlags{period: "year", metric: "commits"}},
+		{name: "period in by", flags: trendFlags{period: "week", by: "team,month", metric: "commits"}},
+		{name: "metric", flags: trendFlags{period: "week", metric: "files"}},
+		{name: "timezone", flags: trendFlags{period: "week", metric: "commits", timezone: "Mars/Olympus"}},
+		{name: "last and since", flags: trendFlags{period: "week", metric: "commits", last: 2}, opts: filter.Options{Since: now}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.flags.spec(&tt.opts, now)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPrintTrend(t *testing.T) {
+	date := time.Date(2021, 10, 6, 12, 0, 0, 0, time.UTC)
+	commits := []*repointerface.Commit{
+		{Author: "Ivan", AddedRows: 10, DeletedRows: 2, Date: date, Team: "Backend"},
+		{Author: "Petr", AddedRows: 1, Date: date.AddDate(0, 0, 14)},
+	}
+	spec := aggregate.TrendSpec{Period: aggregate.Week, By: []aggregate.Dimension{aggregate.Team}, Location: time.UTC}
+	series := aggregate.NewTrend(spec)
+	require.NoError(t, series.Add("prj", combinator.FromSlice(commits)))
+	res := series.Result()
+
+	var buf bytes.Buffer
+	printTrend(&buf, res, "churn")
+	assert.Equal(t, "Измененные строки по неделям с 2021-10-04 по 2021-10-18:\n"+
+		"без команды  ▁▁█  всего 1, в среднем 0.3, максимум 1 (2021-10-18)\n"+
+		"Backend      █▁▁  всего 12, в среднем 4.0, максимум 12 (2021-10-04)\n", buf.String())
+
+	buf.Reset()
+	out := newCsvRenderer(&buf)
+	require.NoError(t, out.Header(trendColumns(spec)...))
+	require.NoError(t, renderTrend(out, res))
+	require.NoError(t, out.Flush())
+	assert.Equal(t, "period,team,commits,authors,added_rows,deleted_rows,churn\n"+
+		"2021-10-04,,0,0,0,0,0\n"+
+		"2021-10-04,Backend,1,1,10,2,12\n"+
+		"2021-10-11,,0,0,0,0,0\n"+
+		"2021-10-11,Backend,0,0,0,0,0\n"+
+		"2021-10-18,,1,1,1,0,1\n"+
+		"2021-10-18,Backend,0,0,0,0,0\n", buf.String())
+}
@@ -0,0 +1,208 @@
+package cli_metrics
+
+import (
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/aggregate"
+	"go-marathon-team-3/pkg/tfsmetrics/worktime"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Флаги команды worktime
+type worktimeFlags struct {
+	by        string
+	calendar  string
+	timezone  string
+	threshold float64
+	summary   bool
+}
+
+func (f *worktimeFlags) flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:        "by",
+			Usage:       "отдельная карта для каждого значения признаков через запятую, например author, team или project,team",
+			Value:       string(aggregate.Author),
+			Destination: &f.by,
+		},
+		&cli.StringFlag{
+			Name:        "calendar",
+			Usage:       "файл рабочего календаря в формате YAML (по умолчанию calendar.yaml рядом с файлом профилей, см. README)",
+			EnvVars:     []string{envCalendar},
+			Destination: &f.calendar,
+		},
+		newTimezoneFlag(&f.timezone, "часовой пояс, в котором определяются дни и часы коммитов (по умолчанию из календаря или локальный)"),
+		&cli.Float64Flag{
+			Name:        "threshold",
+			Usage:       "отметить авторов и команды, у которых доля коммитов вне рабочего времени больше порога, %",
+			Value:       20,
+			Destination: &f.threshold,
+		},
+		&cli.BoolFlag{
+			Name:        "summary",
+			Usage:       "вывести только долю коммитов вне рабочего времени, без тепловых карт",
+			Destination: &f.summary,
+		},
+	}
+}
+
+// spec возвращает параметры анализа. Часовой пояс --timezone заменяет часовой пояс календаря.
+// Файл календаря по умолчанию может отсутствовать, тогда рабочее время - с понедельника по пятницу с 9 до 18
+func (f *worktimeFlags) spec(configPath string) (worktime.Spec, error) {
+	spec := worktime.Spec{}
+	if f.by != "" {
+		by, err := aggregate.ParseDimensions(f.by)
+		if err != nil {
+			return spec, err
+		}
+		for _, dimension := range by {
+			switch dimension {
+			case aggregate.Day, aggregate.Week, aggregate.Month, aggregate.Weekday, aggregate.Hour:
+				return spec, fmt.Errorf("--by не может содержать время коммита '%s'", dimension)
+			}
+		}
+		spec.By = by
+	}
+	path, required := f.calendar, f.calendar != ""
+	if !required {
+		path = calendarFilePath(configPath)
+	}
+	calendar, err := worktime.ReadCalendar(path)
+	if os.IsNotExist(err) && !required {
+		calendar, err = worktime.DefaultCalendar(), nil
+	}
+	if err != nil {
+		return spec, err
+	}
+	if f.timezone != "" {
+		if calendar.Location, err = parseLocation(f.timezone); err != nil {
+			return spec, err
+		}
+	}
+	spec.Calendar = calendar
+	return spec, nil
+}
+
+// flagged возвращает true, если доля коммитов вне рабочего времени больше порога
+func (f *worktimeFlags) flagged(activity *worktime.Activity) bool {
+	return activity.Commits > 0 && activity.OutsideShare()*100 > f.threshold
+}
+
+var weekdayTitles = []string{"пн", "вт", "ср", "чт", "пт", "сб", "вс"}
+
+// describeCalendar возвращает описание рабочего времени календаря
+func describeCalendar(calendar *worktime.Calendar) string {
+	days := []string{}
+	for i, title := range weekdayTitles {
+		if calendar.Weekdays[time.Weekday((i+1)%7)] {
+			days = append(days, title)
+		}
+	}
+	return fmt.Sprintf("Рабочее время: %s с %02d:00 до %02d:00 (%s), праздников: %d, перенесенных рабочих дней: %d",
+		strings.Join(days, ", "), calendar.Start, calendar.End, calendar.Location, len(calendar.Holidays), len(calendar.Workdays))
+}
+
+// describeActivity возвращает строку сводки по ряду
+func describeActivity(by []aggregate.Dimension, activity *worktime.Activity) string {
+	return fmt.Sprintf("%s: коммитов %d, вне рабочего времени %d (%.1f%%), из них в выходные и праздники %d",
+		seriesLabel(by, activity.Keys), activity.Commits, activity.Outside, activity.OutsideShare()*100, activity.DaysOff)
+}
+
+// printHeatmap выводит число коммитов по дням недели (строки) и часам (колонки)
+func printHeatmap(w io.Writer, heatmap *worktime.Heatmap) {
+	width := 2
+	for _, hours := range heatmap {
+		for _, commits := range hours {
+			if digits := len(strconv.Itoa(commits)); digits > width {
+				width = digits
+			}
+		}
+	}
+	fmt.Fprint(w, "  ")
+	for hour := 0; hour < 24; hour++ {
+		fmt.Fprintf(w, " %*s", width, fmt.Sprintf("%02d", hour))
+	}
+	fmt.Fprintln(w)
+	for day, hours := range heatmap {
+		fmt.Fprint(w, weekdayTitles[day])
+		for _, commits := range hours {
+			cell := "·"
+			if commits > 0 {
+				cell = strconv.Itoa(commits)
+			}
+			fmt.Fprintf(w, " %*s", width, cell)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// printWorktime выводит сводку и тепловую карту каждого ряда, а в конце - ряды с долей
+// коммитов вне рабочего времени выше порога
+func printWorktime(w io.Writer, spec worktime.Spec, activities []*worktime.Activity, opts *worktimeFlags) {
+	fmt.Fprintln(w, describeCalendar(spec.Calendar))
+	flagged := []string{}
+	for _, activity := range activities {
+		line := describeActivity(spec.By, activity)
+		if opts.flagged(activity) {
+			line += " (!)"
+			flagged = append(flagged, fmt.Sprintf("%s (%.1f%%)", seriesLabel(spec.By, activity.Keys), activity.OutsideShare()*100))
+		}
+		if opts.summary {
+			fmt.Fprintln(w, line)
+			continue
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, line)
+		printHeatmap(w, &activity.Heatmap)
+	}
+	fmt.Fprintln(w)
+	if len(flagged) == 0 {
+		fmt.Fprintf(w, "Доля коммитов вне рабочего времени нигде не больше %g%%\n", opts.threshold)
+		return
+	}
+	fmt.Fprintf(w, "Доля коммитов вне рабочего времени больше %g%%: %s\n", opts.threshold, strings.Join(flagged, ", "))
+}
+
+// worktimeColumns возвращает колонки вывода в машиночитаемых форматах: сводку по рядам
+// или, без --summary, число коммитов в каждой ячейке тепловой карты
+func worktimeColumns(spec worktime.Spec, summary bool) []string {
+	columns := []string{}
+	for _, dimension := range spec.By {
+		columns = append(columns, string(dimension))
+	}
+	if summary {
+		return append(columns, "commits", "outside", "days_off", "outside_share", "flagged")
+	}
+	return append(columns, "weekday", "hour", "commits")
+}
+
+func renderWorktime(out renderer, activities []*worktime.Activity, opts *worktimeFlags) error {
+	for _, activity := range activities {
+		keys := []interface{}{}
+		for _, key := range activity.Keys {
+			keys = append(keys, key)
+		}
+		if opts.summary {
+			row := append(keys, activity.Commits, activity.Outside, activity.DaysOff,
+				round(activity.OutsideShare()), opts.flagged(activity))
+			if err := out.Row(row...); err != nil {
+				return err
+			}
+			continue
+		}
+		for day, hours := range activity.Heatmap {
+			for hour, commits := range hours {
+				row := append(append([]interface{}{}, keys...), day+1, hour, commits)
+				if err := out.Row(row...); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package cli_metrics
+
+import (
+	"bytes"
+	"go-marathon-team-3/pkg/tfsmetrics/aggregate"
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"go-marathon-team-3/pkg/tfsmetrics/worktime"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorktimeFlags_spec(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+
+	// Без файла календаря - календарь по умолчанию
+	flags := &worktimeFlags{by: "team", timezone: "UTC"}
+	spec, err := flags.spec(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, []aggregate.Dimension{aggregate.Team}, spec.By)
+	assert.Equal(t, time.UTC, spec.Calendar.Location)
+	assert.Equal(t, 9, spec.Calendar.Start)
+
+	require.NoError(t, ioutil.WriteFile(calendarFilePath(configPath), []byte("hours: 10-19\ntimezone: UTC\n"), 0600))
+	spec, err = (&worktimeFlags{}).spec(configPath)
+	require.NoError(t, err)
+	assert.Nil(t, spec.By)
+	assert.Equal(t, 10, spec.Calendar.Start)
+
+	for _, flags := range []worktimeFlags{
+		{by: "author,hour"},
+		{by: "year"},
+		{calendar: filepath.Join(dir, "missing.yaml")},
+		{timezone: "Mars/Olympus"},
+	} {
+		_, err := flags.spec(configPath)
+		assert.Error(t, err, flags)
+	}
+}
+
+func TestPrintWorktime(t *testing.T) {
+	calendar := worktime.DefaultCalendar()
+	calendar.Location = time.UTC
+	spec := worktime.Spec{By: []aggregate.Dimension{aggregate.Author}, Calendar: calendar}
+	analyzer := worktime.NewAnalyzer(spec)
+	require.NoError(t, analyzer.Add("prj", combinator.FromSlice([]*repointerface.Commit{
+		{Author: "Ivan", Date: time.Date(2021, 11, 3, 10, 0, 0, 0, time.UTC)},
+		{Author: "Petr", Date: time.Date(2021, 11, 3, 23, 0, 0, 0, time.UTC)},
+		{Author: "Petr", Date: time.Date(2021, 11, 7, 12, 0, 0, 0, time.UTC)},
+	})))
+	res := analyzer.Result()
+
+	var buf bytes.Buffer
+	printWorktime(&buf, spec, res, &worktimeFlags{threshold: 20, summary: true})
+	assert.Equal(t, "Рабочее время: пн, вт, ср, чт, пт с 09:00 до 18:00 (UTC), праздников: 0, перенесенных рабочих дней: 0\n"+
+		"Ivan: коммитов 1, вне рабочего времени 0 (0.0%), из них в выходные и праздники 0\n"+
+		"Petr: коммитов 2, вне рабочего времени 2 (100.0%), из них в выходные и праздники 1 (!)\n"+
+		"\n"+
+		"Доля коммитов вне рабочего времени больше 20%: Petr (100.0%)\n", buf.String())
+
+	buf.Reset()
+	printWorktime(&buf, spec, res[:1], &worktimeFlags{threshold: 20})
+	lines := strings.Split(buf.String(), "\n")
+	require.Len(t, lines, 14)
+	assert.True(t, strings.HasPrefix(lines[3], "   00 01 02 03 04 05 06 07 08 09 10 11"), lines[3])
+	assert.True(t, strings.HasPrefix(lines[6], "ср  ·  ·  ·  ·  ·  ·  ·  ·  ·  ·  1  ·"), lines[6])
+	assert.Equal(t, "Доля коммитов вне рабочего времени нигде не больше 20%", lines[12])
+
+	buf.Reset()
+	out := newCsvRenderer(&buf)
+	opts := &worktimeFlags{threshold: 20, summary: true}
+	require.NoError(t, out.Header(worktimeColumns(spec, true)...))
+	require.NoError(t, renderWorktime(out, res, opts))
+	require.NoError(t, out.Flush())
+	assert.Equal(t, "author,commits,outside,days_off,outside_share,flagged\n"+
+		"Ivan,1,0,0,0,false\n"+
+		"Petr,2,2,1,1,true\n", buf.String())
+}
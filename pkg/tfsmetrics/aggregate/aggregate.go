@@ -0,0 +1,256 @@
+// Package aggregate группирует коммиты по произвольному набору признаков (проект, автор, команда, период,
+// день недели, час, расширение или каталог файлов) и считает по группам число коммитов и авторов, а также
+// сумму, минимум, максимум, среднее и процентили добавленных и удаленных строк. Используется командами CLI
+// и экспортером.
+// Trend раскладывает коммиты по дням, неделям или месяцам в заданном часовом поясе во временные ряды
+package aggregate
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/identity"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Параметры группировки
+type Spec struct {
+	// Признаки группировки в порядке ключей групп
+	By []Dimension
+	// Процентили (0..100), которые считаются для строк
+	Percentiles []float64
+	// Часовой пояс, в котором определяются периоды (по умолчанию - локальный)
+	Location *time.Location
+}
+
+// Группа коммитов с одинаковыми значениями признаков
+type Group struct {
+	// Значения признаков в порядке Spec.By
+	Keys        []string
+	Commits     int
+	Authors     int
+	AddedRows   Stats
+	DeletedRows Stats
+	// Автор группы, если группировка по автору
+	Identity *identity.Identity
+}
+
+// Результат группировки
+type Result struct {
+	By          []Dimension
+	Percentiles []float64
+	// Группы, упорядоченные по ключам
+	Groups []*Group
+}
+
+// Key возвращает значение признака группы (пустую строку, если группировка не по нему)
+func (r *Result) Key(group *Group, dimension Dimension) string {
+	for i, by := range r.By {
+		if by == dimension {
+			return group.Keys[i]
+		}
+	}
+	return ""
+}
+
+// Find возвращает группу с заданными значениями признаков или nil
+func (r *Result) Find(keys ...string) *Group {
+	for _, group := range r.Groups {
+		if equalKeys(group.Keys, keys) {
+			return group
+		}
+	}
+	return nil
+}
+
+// Группы с одинаковыми первыми ключами (например, группы автора по периодам)
+type GroupSeries struct {
+	// Общие первые ключи групп
+	Keys   []string
+	Groups []*Group
+}
+
+// SplitSeries разбивает упорядоченные по ключам группы на ряды по первым prefix ключам. Ряды и группы в рядах
+// идут в порядке групп. Если prefix = 0, возвращается единственный ряд, даже если групп нет
+func SplitSeries(groups []*Group, prefix int) []*GroupSeries {
+	res := []*GroupSeries{}
+	if prefix == 0 {
+		return append(res, &GroupSeries{Keys: []string{}, Groups: groups})
+	}
+	for _, group := range groups {
+		keys := group.Keys[:prefix]
+		// Группы упорядочены по ключам, поэтому группы одного ряда идут подряд
+		if len(res) == 0 || !equalKeys(res[len(res)-1].Keys, keys) {
+			res = append(res, &GroupSeries{Keys: keys})
+		}
+		last := res[len(res)-1]
+		last.Groups = append(last.Groups, group)
+	}
+	return res
+}
+
+func equalKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type Aggregator interface {
+	// Add вычитывает коммиты проекта. Можно вызывать одновременно для разных проектов.
+	// Если итератор завершился ошибкой (не ErrNoMoreItems), она возвращается, а полученные
+	// до нее коммиты учитываются: такие данные неполные
+	Add(project string, iterator repointerface.CommitIterator) error
+	// Result группирует добавленные коммиты
+	Result() *Result
+}
+
+// Коммит вместе с проектом, из которого он получен
+type record struct {
+	project string
+	commit  *repointerface.Commit
+}
+
+type aggregator struct {
+	spec    Spec
+	mu      sync.Mutex
+	index   *identity.Index
+	records []record
+}
+
+func New(spec Spec) Aggregator {
+	if spec.Location == nil {
+		spec.Location = time.Local
+	}
+	return &aggregator{
+		spec:  spec,
+		index: identity.NewIndex(),
+	}
+}
+
+// Aggregate группирует коммиты одного проекта
+func Aggregate(spec Spec, project string, iterator repointerface.CommitIterator) (*Result, error) {
+	aggregator := New(spec)
+	err := aggregator.Add(project, iterator)
+	return aggregator.Result(), err
+}
+
+func (a *aggregator) Add(project string, iterator repointerface.CommitIterator) error {
+	records := []record{}
+	commit, err := iterator.Next()
+	for ; err == nil; commit, err = iterator.Next() {
+		records = append(records, record{project: project, commit: commit})
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, record := range records {
+		a.index.Add(record.commit)
+	}
+	a.records = append(a.records, records...)
+	if err == repointerface.ErrNoMoreItems {
+		return nil
+	}
+	return err
+}
+
+// Накопленные значения группы
+type accumulator struct {
+	group       *Group
+	authors     map[*identity.Identity]bool
+	addedRows   []int
+	deletedRows []int
+}
+
+func (a *aggregator) Result() *Result {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	labels := a.index.Labels()
+	groups := make(map[string]*accumulator)
+	for _, record := range a.records {
+		author := a.index.Find(record.commit)
+		for _, keys := range a.keys(record, labels[author]) {
+			id := strings.Join(keys, "\x00")
+			acc, ok := groups[id]
+			if !ok {
+				acc = &accumulator{group: &Group{Keys: keys}, authors: make(map[*identity.Identity]bool)}
+				groups[id] = acc
+			}
+			acc.group.Commits++
+			acc.authors[author] = true
+			acc.addedRows = append(acc.addedRows, record.commit.AddedRows)
+			acc.deletedRows = append(acc.deletedRows, record.commit.DeletedRows)
+		}
+	}
+
+	res := &Result{By: a.spec.By, Percentiles: a.spec.Percentiles, Groups: make([]*Group, 0, len(groups))}
+	for _, acc := range groups {
+		group := acc.group
+		group.Authors = len(acc.authors)
+		group.AddedRows = newStats(acc.addedRows, a.spec.Percentiles)
+		group.DeletedRows = newStats(acc.deletedRows, a.spec.Percentiles)
+		if hasDimension(a.spec.By, Author) {
+			for author := range acc.authors {
+				group.Identity = author
+			}
+		}
+		res.Groups = append(res.Groups, group)
+	}
+	sort.Slice(res.Groups, func(i, j int) bool {
+		return lessKeys(res.Groups[i].Keys, res.Groups[j].Keys)
+	})
+	return res
+}
+
+// keys возвращает ключи групп, в которые попадает коммит: несколько, если группировка по файлам
+func (a *aggregator) keys(record record, author string) [][]string {
+	res := [][]string{{}}
+	for _, dimension := range a.spec.By {
+		values := []string{}
+		switch dimension {
+		case Project:
+			values = append(values, record.project)
+		case Author:
+			values = append(values, author)
+		case Team:
+			values = append(values, record.commit.Team)
+		case Day, Week, Month:
+			values = append(values, periodKey(dimension, record.commit.Date, a.spec.Location))
+		case Weekday, Hour:
+			values = append(values, clockKey(dimension, record.commit.Date, a.spec.Location))
+		case Extension, Directory:
+			values = fileKeys(dimension, record.commit)
+		}
+		next := make([][]string, 0, len(res)*len(values))
+		for _, keys := range res {
+			for _, value := range values {
+				next = append(next, append(append(make([]string, 0, len(keys)+1), keys...), value))
+			}
+		}
+		res = next
+	}
+	return res
+}
+
+func hasDimension(dimensions []Dimension, dimension Dimension) bool {
+	for _, d := range dimensions {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+func lessKeys(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
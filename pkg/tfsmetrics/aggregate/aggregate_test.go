@@ -0,0 +1,170 @@
+package aggregate
+
+import (
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Среда, 6 октября 2021 года
+var date = time.Date(2021, 10, 6, 12, 0, 0, 0, time.UTC)
+
+func testCommits() []*repointerface.Commit {
+	return []*repointerface.Commit{
+		{Id: 1, Author: "Ivan", AuthorId: "id-1", AddedRows: 10, DeletedRows: 1, Date: date, Team: "Backend",
+			Files: []string{"$/prj/src/main.go", "$/prj/src/util.go"}},
+		{Id: 2, Author: "Ivan", AuthorId: "id-1", AddedRows: 20, Date: date.AddDate(0, 0, 5), Team: "Backend",
+			Files: []string{"$/prj/README.md"}},
+		{Id: 3, Author: "Petr", AuthorId: "id-2", AddedRows: 30, DeletedRows: 3, Date: date.AddDate(0, 1, 0), Team: "Backend",
+			Files: []string{"$/prj/src/main.go", "$/prj/docs/index.MD"}},
+		{Id: 4, Author: "Guest", AuthorId: "id-3", AddedRows: 40, Date: date},
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	res, err := Aggregate(Spec{By: []Dimension{Team}, Percentiles: []float64{50, 90}}, "prj", combinator.FromSlice(testCommits()))
+	require.NoError(t, err)
+	require.Len(t, res.Groups, 2)
+	assert.Equal(t, &Group{
+		Keys:        []string{""},
+		Commits:     1,
+		Authors:     1,
+		AddedRows:   Stats{Sum: 40, Min: 40, Max: 40, Mean: 40, Percentiles: []float64{40, 40}},
+		DeletedRows: Stats{Percentiles: []float64{0, 0}},
+	}, res.Groups[0])
+	backend := res.Find("Backend")
+	require.NotNil(t, backend)
+	assert.Equal(t, 3, backend.Commits)
+	assert.Equal(t, 2, backend.Authors)
+	assert.Equal(t, Stats{Sum: 60, Min: 10, Max: 30, Mean: 20, Percentiles: []float64{20, 28}}, backend.AddedRows)
+	assert.Equal(t, Stats{Sum: 4, Min: 0, Max: 3, Mean: 4.0 / 3, Percentiles: []float64{1, 2.6}}, backend.DeletedRows)
+	assert.Nil(t, backend.Identity)
+}
+
+func TestAggregate_dimensions(t *testing.T) {
+	tests := []struct {
+		name string
+		by   []Dimension
+		want map[string]int // ключи группы через "|" -> число коммитов
+	}{
+		{name: "author and team", by: []Dimension{Author, Team},
+			want: map[string]int{"Guest|": 1, "Ivan|Backend": 2, "Petr|Backend": 1}},
+		{name: "day", by: []Dimension{Day},
+			want: map[string]int{"2021-10-06": 2, "2021-10-11": 1, "2021-11-06": 1}},
+		{name: "week", by: []Dimension{Week},
+			want: map[string]int{"2021-10-04": 2, "2021-10-11": 1, "2021-11-01": 1}},
+		{name: "project and month", by: []Dimension{Project, Month},
+			want: map[string]int{"prj|2021-10": 3, "prj|2021-11": 1}},
+		{name: "weekday and hour", by: []Dimension{Weekday, Hour},
+			want: map[string]int{"1|12": 1, "3|12": 2, "6|12": 1}},
+		{name: "extension", by: []Dimension{Extension},
+			want: map[string]int{"": 1, ".go": 2, ".md": 2}},
+		{name: "directory", by: []Dimension{Directory},
+			want: map[string]int{"": 1, "$/prj": 1, "$/prj/docs": 1, "$/prj/src": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Aggregate(Spec{By: tt.by, Location: time.UTC}, "prj", combinator.FromSlice(testCommits()))
+			require.NoError(t, err)
+			got := make(map[string]int)
+			for _, group := range res.Groups {
+				key := group.Keys[0]
+				for _, k := range group.Keys[1:] {
+					key += "|" + k
+				}
+				got[key] = group.Commits
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAggregate_location(t *testing.T) {
+	commits := []*repointerface.Commit{{Author: "Ivan", Date: time.Date(2021, 10, 31, 22, 0, 0, 0, time.UTC)}}
+	res, err := Aggregate(Spec{By: []Dimension{Month}, Location: time.UTC}, "prj", combinator.FromSlice(commits))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2021-10"}, res.Groups[0].Keys)
+
+	moscow := time.FixedZone("MSK", 3*60*60)
+	res, err = Aggregate(Spec{By: []Dimension{Month}, Location: moscow}, "prj", combinator.FromSlice(commits))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2021-11"}, res.Groups[0].Keys)
+}
+
+func TestAggregator_projects(t *testing.T) {
+	aggregator := New(Spec{By: []Dimension{Author, Project}})
+	commits := testCommits()
+	failure := &repointerface.ChangesetError{Id: 5, Err: errors.New("timeout")}
+	require.NoError(t, aggregator.Add("first", combinator.FromSlice(commits[:2])))
+	err := aggregator.Add("second", &failingIterator{commits: commits[2:3], err: failure})
+	assert.ErrorIs(t, err, failure)
+
+	res := aggregator.Result()
+	require.Len(t, res.Groups, 2)
+	assert.Equal(t, []string{"Ivan", "first"}, res.Groups[0].Keys)
+	assert.Equal(t, 2, res.Groups[0].Commits)
+	assert.Equal(t, "Ivan", res.Groups[0].Identity.Name)
+	assert.Equal(t, "second", res.Key(res.Groups[1], Project))
+	assert.Equal(t, "", res.Key(res.Groups[1], Team))
+}
+
+func TestSplitSeries(t *testing.T) {
+	groups := []*Group{
+		{Keys: []string{"Backend", "Ivan", "2021-10-01"}},
+		{Keys: []string{"Backend", "Ivan", "2021-10-02"}},
+		{Keys: []string{"Backend", "Petr", "2021-10-01"}},
+		{Keys: []string{"Frontend", "Ivan", "2021-10-03"}},
+	}
+	series := SplitSeries(groups, 2)
+	require.Len(t, series, 3)
+	assert.Equal(t, []string{"Backend", "Ivan"}, series[0].Keys)
+	assert.Equal(t, groups[:2], series[0].Groups)
+	assert.Equal(t, []string{"Backend", "Petr"}, series[1].Keys)
+	assert.Equal(t, []string{"Frontend", "Ivan"}, series[2].Keys)
+
+	assert.Len(t, SplitSeries(groups, 1), 2)
+	// Без признаков ряд один, даже если групп нет
+	assert.Equal(t, []*GroupSeries{{Keys: []string{}}}, SplitSeries(nil, 0))
+}
+
+func TestParseDimensions(t *testing.T) {
+	dimensions, err := ParseDimensions("Project, author,month")
+	require.NoError(t, err)
+	assert.Equal(t, []Dimension{Project, Author, Month}, dimensions)
+
+	for _, value := range []string{"", "author,author", "year"} {
+		_, err := ParseDimensions(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestParsePercentiles(t *testing.T) {
+	percentiles, err := ParsePercentiles("p50, 99.9")
+	require.NoError(t, err)
+	assert.Equal(t, []float64{50, 99.9}, percentiles)
+	assert.Equal(t, "p99.9", PercentileName(percentiles[1]))
+
+	for _, value := range []string{"101", "pp", "-1", "nan", "p-Inf"} {
+		_, err := ParsePercentiles(value)
+		assert.Error(t, err, value)
+	}
+}
+
+type failingIterator struct {
+	commits []*repointerface.Commit
+	err     error
+}
+
+func (i *failingIterator) Next() (*repointerface.Commit, error) {
+	if len(i.commits) == 0 {
+		return nil, i.err
+	}
+	commit := i.commits[0]
+	i.commits = i.commits[1:]
+	return commit, nil
+}
@@ -0,0 +1,149 @@
+package aggregate
+
+import (
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Признак, по которому группируются коммиты
+type Dimension string
+
+const (
+	Project Dimension = "project"
+	// Автор (см. identity.Index.Labels): последнее имя, а для разных людей с одинаковым именем - имя с почтой
+	Author Dimension = "author"
+	Team   Dimension = "team"
+	// Периоды по дате коммита в часовом поясе Spec.Location
+	Day   Dimension = "day"
+	Week  Dimension = "week" // неделя с понедельника, ключ - дата понедельника
+	Month Dimension = "month"
+	// День недели (1 - понедельник, 7 - воскресенье) и час (00-23) коммита в часовом поясе Spec.Location
+	Weekday Dimension = "weekday"
+	Hour    Dimension = "hour"
+	// Группировки по измененным файлам: коммит попадает в группу каждого расширения (каталога) своих файлов
+	// целиком, поэтому сумма по группам может быть больше итога. Коммиты без списка файлов - под пустым ключом
+	Extension Dimension = "extension"
+	Directory Dimension = "directory"
+)
+
+// Dimensions перечисляет все признаки группировки
+var Dimensions = []Dimension{Project, Author, Team, Day, Week, Month, Weekday, Hour, Extension, Directory}
+
+// ParseDimensions разбирает список признаков через запятую, например "project,author,month"
+func ParseDimensions(value string) ([]Dimension, error) {
+	res := []Dimension{}
+	seen := make(map[Dimension]bool)
+	for _, part := range strings.Split(value, ",") {
+		dimension := Dimension(strings.ToLower(strings.TrimSpace(part)))
+		if dimension == "" {
+			continue
+		}
+		if !dimension.valid() {
+			return nil, fmt.Errorf("неизвестная группировка '%s', доступны: %s", dimension, DimensionNames())
+		}
+		if seen[dimension] {
+			return nil, fmt.Errorf("группировка '%s' указана дважды", dimension)
+		}
+		seen[dimension] = true
+		res = append(res, dimension)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("не указана группировка, доступны: %s", DimensionNames())
+	}
+	return res, nil
+}
+
+func (d Dimension) valid() bool {
+	for _, dimension := range Dimensions {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+// DimensionNames возвращает названия признаков группировки через запятую (для справки и сообщений об ошибках)
+func DimensionNames() string {
+	names := make([]string, len(Dimensions))
+	for i, dimension := range Dimensions {
+		names[i] = string(dimension)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Форматы ключей периодов
+const (
+	dayLayout   = "2006-01-02"
+	monthLayout = "2006-01"
+)
+
+// periodKey возвращает ключ периода, в который попадает дата
+func periodKey(period Dimension, date time.Time, location *time.Location) string {
+	if period == Month {
+		return PeriodStart(period, date, location).Format(monthLayout)
+	}
+	return PeriodStart(period, date, location).Format(dayLayout)
+}
+
+// PeriodStart возвращает начало периода (дня, недели с понедельника или месяца) в часовом поясе location,
+// в который попадает дата
+func PeriodStart(period Dimension, date time.Time, location *time.Location) time.Time {
+	date = date.In(location)
+	year, month, day := date.Date()
+	switch period {
+	case Week:
+		// Сдвиг до понедельника: Weekday считает неделю с воскресенья
+		return time.Date(year, month, day-(int(date.Weekday())+6)%7, 0, 0, 0, 0, location)
+	case Month:
+		return time.Date(year, month, 1, 0, 0, 0, 0, location)
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, location)
+	}
+}
+
+// AddPeriods возвращает начало периода, отстоящего на n периодов (n < 0 - назад) от периода с началом start
+func AddPeriods(period Dimension, start time.Time, n int) time.Time {
+	switch period {
+	case Week:
+		return start.AddDate(0, 0, 7*n)
+	case Month:
+		return start.AddDate(0, n, 0)
+	default:
+		return start.AddDate(0, 0, n)
+	}
+}
+
+// clockKey возвращает день недели или час коммита
+func clockKey(dimension Dimension, date time.Time, location *time.Location) string {
+	date = date.In(location)
+	if dimension == Weekday {
+		return strconv.Itoa((int(date.Weekday())+6)%7 + 1)
+	}
+	return fmt.Sprintf("%02d", date.Hour())
+}
+
+// fileKeys возвращает разные расширения или каталоги измененных файлов коммита
+func fileKeys(dimension Dimension, commit *repointerface.Commit) []string {
+	res := []string{}
+	seen := make(map[string]bool)
+	for _, file := range commit.Files {
+		var key string
+		if dimension == Extension {
+			key = strings.ToLower(path.Ext(file))
+		} else {
+			key = path.Dir(file)
+		}
+		if !seen[key] {
+			seen[key] = true
+			res = append(res, key)
+		}
+	}
+	if len(res) == 0 {
+		return []string{""}
+	}
+	return res
+}
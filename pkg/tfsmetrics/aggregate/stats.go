@@ -0,0 +1,73 @@
+package aggregate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Процентили, которые считаются, если они не заданы
+var DefaultPercentiles = []float64{50, 90}
+
+// Статистика значения (например, числа добавленных строк) по коммитам группы
+type Stats struct {
+	Sum  int
+	Min  int
+	Max  int
+	Mean float64
+	// Значения процентилей в порядке Spec.Percentiles
+	Percentiles []float64
+}
+
+// newStats считает статистику по значениям (values сортируется)
+func newStats(values []int, percentiles []float64) Stats {
+	res := Stats{Percentiles: make([]float64, len(percentiles))}
+	if len(values) == 0 {
+		return res
+	}
+	sort.Ints(values)
+	res.Min = values[0]
+	res.Max = values[len(values)-1]
+	for _, value := range values {
+		res.Sum += value
+	}
+	res.Mean = float64(res.Sum) / float64(len(values))
+	for i, p := range percentiles {
+		res.Percentiles[i] = percentile(values, p)
+	}
+	return res
+}
+
+// percentile возвращает процентиль p (0..100) отсортированных значений
+// с линейной интерполяцией между соседними значениями
+func percentile(sorted []int, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return float64(sorted[lower]) + (rank-float64(lower))*float64(sorted[upper]-sorted[lower])
+}
+
+// ParsePercentiles разбирает список процентилей через запятую, например "50,90,99" или "p50,p95"
+func ParsePercentiles(value string) ([]float64, error) {
+	res := []float64{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(part)), "p")
+		if part == "" {
+			continue
+		}
+		p, err := strconv.ParseFloat(part, 64)
+		// ParseFloat принимает и "nan", который не проходит ни одно сравнение
+		if err != nil || !(p >= 0 && p <= 100) {
+			return nil, fmt.Errorf("неверный процентиль '%s', ожидается число от 0 до 100", part)
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// PercentileName возвращает название процентиля для заголовков, например p50 или p99.9
+func PercentileName(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
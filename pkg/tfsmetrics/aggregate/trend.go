@@ -0,0 +1,156 @@
+package aggregate
+
+import (
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"strings"
+	"time"
+)
+
+// Параметры временного ряда
+type TrendSpec struct {
+	// Период ряда: Day, Week или Month
+	Period Dimension
+	// Признаки, по которым коммиты разделяются на отдельные ряды (например, по командам).
+	// Без них строится один ряд по всем коммитам
+	By []Dimension
+	// Часовой пояс, в котором определяются периоды (по умолчанию - локальный)
+	Location *time.Location
+	// Границы ряда. Если граница не задана, ряд начинается с периода первого коммита
+	// или заканчивается периодом последнего коммита. Коммиты вне границ не учитываются
+	Since time.Time
+	Until time.Time
+}
+
+// Значения за период
+type Point struct {
+	Commits     int
+	Authors     int
+	AddedRows   int
+	DeletedRows int
+}
+
+// Churn возвращает число измененных строк: добавленных и удаленных
+func (p Point) Churn() int {
+	return p.AddedRows + p.DeletedRows
+}
+
+// Ряд значений по периодам
+type Series struct {
+	// Значения признаков TrendSpec.By
+	Keys []string
+	// Значения в порядке TrendResult.Periods, для периодов без коммитов - нулевые
+	Points []Point
+}
+
+// Временные ряды
+type TrendResult struct {
+	Period Dimension
+	By     []Dimension
+	// Начала всех периодов подряд, без пропусков
+	Periods []time.Time
+	// Ряды, упорядоченные по ключам
+	Series []*Series
+}
+
+type Trend interface {
+	// Add вычитывает коммиты проекта, как Aggregator.Add
+	Add(project string, iterator repointerface.CommitIterator) error
+	// Result раскладывает добавленные коммиты по периодам
+	Result() *TrendResult
+}
+
+type trend struct {
+	spec       TrendSpec
+	aggregator Aggregator
+}
+
+func NewTrend(spec TrendSpec) Trend {
+	if spec.Location == nil {
+		spec.Location = time.Local
+	}
+	by := append(append([]Dimension{}, spec.By...), spec.Period)
+	return &trend{
+		spec:       spec,
+		aggregator: New(Spec{By: by, Location: spec.Location}),
+	}
+}
+
+// ParsePeriod разбирает название периода: day, week или month
+func ParsePeriod(value string) (Dimension, error) {
+	period := Dimension(strings.ToLower(strings.TrimSpace(value)))
+	if period != Day && period != Week && period != Month {
+		return "", fmt.Errorf("неизвестный период '%s', доступны: %s, %s, %s", value, Day, Week, Month)
+	}
+	return period, nil
+}
+
+func (t *trend) Add(project string, iterator repointerface.CommitIterator) error {
+	return t.aggregator.Add(project, iterator)
+}
+
+func (t *trend) Result() *TrendResult {
+	groups := t.aggregator.Result().Groups
+	res := &TrendResult{Period: t.spec.Period, By: t.spec.By, Periods: []time.Time{}, Series: []*Series{}}
+
+	// Границы ряда: заданные или по первому и последнему коммиту (ключи периодов упорядочены как даты)
+	first, last := "", ""
+	for _, group := range groups {
+		key := group.Keys[len(group.Keys)-1]
+		if first == "" || key < first {
+			first = key
+		}
+		if key > last {
+			last = key
+		}
+	}
+	start, end := t.parseKey(first), t.parseKey(last)
+	if !t.spec.Since.IsZero() {
+		start = PeriodStart(t.spec.Period, t.spec.Since, t.spec.Location)
+	}
+	if !t.spec.Until.IsZero() {
+		end = PeriodStart(t.spec.Period, t.spec.Until, t.spec.Location)
+	}
+
+	index := make(map[string]int)
+	for period := start; !period.IsZero() && !period.After(end); period = AddPeriods(t.spec.Period, period, 1) {
+		index[periodKey(t.spec.Period, period, t.spec.Location)] = len(res.Periods)
+		res.Periods = append(res.Periods, period)
+	}
+
+	for _, groups := range SplitSeries(groups, len(t.spec.By)) {
+		s := &Series{Keys: groups.Keys, Points: make([]Point, len(res.Periods))}
+		// Ряд без коммитов в границах не выводится (кроме единственного)
+		found := len(t.spec.By) == 0
+		for _, group := range groups.Groups {
+			i, ok := index[group.Keys[len(group.Keys)-1]]
+			if !ok {
+				continue
+			}
+			found = true
+			s.Points[i] = Point{
+				Commits:     group.Commits,
+				Authors:     group.Authors,
+				AddedRows:   group.AddedRows.Sum,
+				DeletedRows: group.DeletedRows.Sum,
+			}
+		}
+		if found {
+			res.Series = append(res.Series, s)
+		}
+	}
+	return res
+}
+
+// parseKey возвращает начало периода по его ключу (нулевое время для пустого ключа)
+func (t *trend) parseKey(key string) time.Time {
+	layout := dayLayout
+	if t.spec.Period == Month {
+		layout = monthLayout
+	}
+	start, err := time.ParseInLocation(layout, key, t.spec.Location)
+	if err != nil {
+		return time.Time{}
+	}
+	return start
+}
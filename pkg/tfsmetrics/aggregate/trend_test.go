@@ -0,0 +1,95 @@
+package aggregate
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriodStart(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+	// Воскресенье в UTC, но уже понедельник в Москве
+	date := time.Date(2021, 10, 31, 22, 0, 0, 0, time.UTC)
+	tests := []struct {
+		period   Dimension
+		location *time.Location
+		want     time.Time
+	}{
+		{period: Day, location: time.UTC, want: time.Date(2021, 10, 31, 0, 0, 0, 0, time.UTC)},
+		{period: Week, location: time.UTC, want: time.Date(2021, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{period: Month, location: time.UTC, want: time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{period: Day, location: moscow, want: time.Date(2021, 11, 1, 0, 0, 0, 0, moscow)},
+		{period: Week, location: moscow, want: time.Date(2021, 11, 1, 0, 0, 0, 0, moscow)},
+		{period: Month, location: moscow, want: time.Date(2021, 11, 1, 0, 0, 0, 0, moscow)},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.period)+" "+tt.location.String(), func(t *testing.T) {
+			start := PeriodStart(tt.period, date, tt.location)
+			assert.True(t, tt.want.Equal(start), start)
+			assert.True(t, AddPeriods(tt.period, start, 1).After(date))
+			assert.True(t, tt.want.Equal(AddPeriods(tt.period, AddPeriods(tt.period, start, -3), 3)))
+		})
+	}
+}
+
+func TestTrend(t *testing.T) {
+	trend := NewTrend(TrendSpec{Period: Week, Location: time.UTC})
+	require.NoError(t, trend.Add("prj", combinator.FromSlice(testCommits())))
+	res := trend.Result()
+
+	// С 4 октября по 1 ноября: пустые недели заполнены нулями
+	require.Len(t, res.Periods, 5)
+	assert.Equal(t, time.Date(2021, 10, 4, 0, 0, 0, 0, time.UTC), res.Periods[0])
+	assert.Equal(t, time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC), res.Periods[4])
+	require.Len(t, res.Series, 1)
+	assert.Equal(t, []Point{
+		{Commits: 2, Authors: 2, AddedRows: 50, DeletedRows: 1},
+		{Commits: 1, Authors: 1, AddedRows: 20},
+		{}, {},
+		{Commits: 1, Authors: 1, AddedRows: 30, DeletedRows: 3},
+	}, res.Series[0].Points)
+	assert.Equal(t, 33, res.Series[0].Points[4].Churn())
+}
+
+func TestTrend_series(t *testing.T) {
+	trend := NewTrend(TrendSpec{
+		Period:   Month,
+		By:       []Dimension{Team},
+		Location: time.UTC,
+		Since:    time.Date(2021, 9, 15, 0, 0, 0, 0, time.UTC),
+		Until:    time.Date(2021, 10, 31, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, trend.Add("prj", combinator.FromSlice(testCommits())))
+	res := trend.Result()
+
+	// Коммит за ноябрь вне границ не учитывается
+	assert.Equal(t, []time.Time{
+		time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC),
+	}, res.Periods)
+	require.Len(t, res.Series, 2)
+	assert.Equal(t, &Series{Keys: []string{""}, Points: []Point{{}, {Commits: 1, Authors: 1, AddedRows: 40}}}, res.Series[0])
+	assert.Equal(t, &Series{Keys: []string{"Backend"}, Points: []Point{{}, {Commits: 2, Authors: 1, AddedRows: 30, DeletedRows: 1}}}, res.Series[1])
+}
+
+func TestTrend_empty(t *testing.T) {
+	trend := NewTrend(TrendSpec{Period: Day, Location: time.UTC,
+		Since: time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2021, 10, 3, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, trend.Add("prj", combinator.FromSlice([]*repointerface.Commit{})))
+	res := trend.Result()
+	assert.Len(t, res.Periods, 3)
+	require.Len(t, res.Series, 1)
+	assert.Equal(t, make([]Point, 3), res.Series[0].Points)
+}
+
+func TestParsePeriod(t *testing.T) {
+	period, err := ParsePeriod("Week")
+	require.NoError(t, err)
+	assert.Equal(t, Week, period)
+	_, err = ParsePeriod("author")
+	assert.Error(t, err)
+}
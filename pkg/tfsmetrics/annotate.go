@@ -0,0 +1,112 @@
+package tfsmetrics
+
+import (
+	"errors"
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"go-marathon-team-3/pkg/tfsmetrics/store"
+	"log"
+	"strings"
+)
+
+// Строка файла вместе с ченджсетом, в котором она последний раз изменялась
+type AnnotatedLine struct {
+	Number  int
+	Text    string
+	Version *azure.ItemVersion
+}
+
+// Annotate восстанавливает авторство строк файла, проходя по истории его изменений
+// и сравнивая соседние версии. Если cache = true, версии файла сохраняются в store
+// и при повторном вызове не загружаются из Azure. Версии, которых нет на сервере (например, удаление файла),
+// пропускаются, остальные ошибки возвращаются.
+// История берется только под текущим путем (см. GetItemHistory), поэтому строки, не менявшиеся
+// с переименования файла, приписываются ченджсету переименования
+func Annotate(path string, azureClient azure.AzureInterface, cache bool, store store.Store) ([]AnnotatedLine, error) {
+	history, err := azureClient.GetItemHistory(path)
+	if err != nil {
+		return nil, err
+	}
+	cache = cache && store != nil
+	var lines []string
+	var owners []*azure.ItemVersion
+	loaded := false
+	for i := range history {
+		version := &history[i]
+		content, err := itemContent(path, version.ChangesetId, azureClient, cache, store)
+		if azure.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("версия файла %s в ченджсете %d: %w", path, version.ChangesetId, err)
+		}
+		current := strings.Split(content, "\n")
+		if loaded {
+			owners = annotateVersion(lines, owners, current, version)
+		} else {
+			owners = make([]*azure.ItemVersion, len(current))
+			for j := range owners {
+				owners[j] = version
+			}
+			loaded = true
+		}
+		lines = current
+	}
+	if !loaded {
+		return nil, errors.New("не удалось получить ни одной версии файла " + path)
+	}
+
+	res := make([]AnnotatedLine, len(lines))
+	for i := range lines {
+		res[i] = AnnotatedLine{
+			Number:  i + 1,
+			Text:    lines[i],
+			Version: owners[i],
+		}
+	}
+	return res, nil
+}
+
+// annotateVersion переносит авторство неизмененных строк из предыдущей версии,
+// а добавленные строки приписывает версии version
+func annotateVersion(previous []string, owners []*azure.ItemVersion, current []string,
+	version *azure.ItemVersion) []*azure.ItemVersion {
+	chunks := azure.DiffChunks(previous, current)
+	if chunks == nil {
+		return owners
+	}
+	res := make([]*azure.ItemVersion, 0, len(current))
+	index := 0
+	for _, chunk := range chunks {
+		index += len(chunk.Deleted)
+		for range chunk.Added {
+			res = append(res, version)
+		}
+		for range chunk.Equal {
+			res = append(res, owners[index])
+			index++
+		}
+	}
+	return res
+}
+
+// itemContent возвращает версию файла из кеша или из Azure. Ошибка записи в кеш не прерывает
+// восстановление авторства: версия уже получена, поэтому в лог выводится только предупреждение
+func itemContent(path string, changeset int, azureClient azure.AzureInterface, cache bool, store store.Store) (string, error) {
+	if cache {
+		content, err := store.FindContent(path, changeset)
+		if err == nil {
+			return content, nil
+		}
+	}
+	content, err := azureClient.GetItemContentAt(path, changeset)
+	if err != nil {
+		return "", err
+	}
+	if cache {
+		if err := store.WriteContent(path, changeset, content); err != nil {
+			log.Printf("Внимание: версия файла %s в ченджсете %d не сохранена в кеш: %v", path, changeset, err)
+		}
+	}
+	return content, nil
+}
@@ -0,0 +1,88 @@
+package tfsmetrics
+
+import (
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"go-marathon-team-3/pkg/tfsmetrics/mock"
+	"go-marathon-team-3/pkg/tfsmetrics/mock/mock_azure"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/microsoft/azure-devops-go-api/azuredevops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedAzure := mock_azure.NewMockAzureInterface(ctrl)
+	mockedStore := mock.NewMockStore(ctrl)
+
+	path := "$/project/main.go"
+	history := []azure.ItemVersion{
+		{ChangesetId: 1, Author: "Ivan"},
+		{ChangesetId: 2, Author: "Pety"},
+		{ChangesetId: 3, Author: "Pety"},
+		{ChangesetId: 4, Author: "Ivan"},
+	}
+	mockedAzure.EXPECT().GetItemHistory(path).Return(history, nil)
+
+	// первая версия уже в кеше
+	mockedStore.EXPECT().FindContent(path, 1).Return("a\nb\nc", nil)
+	// вторая загружается и сохраняется в кеш
+	mockedStore.EXPECT().FindContent(path, 2).Return("", errors.New("no item"))
+	mockedAzure.EXPECT().GetItemContentAt(path, 2).Return("a\nB\nc\nd", nil)
+	mockedStore.EXPECT().WriteContent(path, 2, "a\nB\nc\nd").Return(nil)
+	// третьей нет на сервере - она пропускается
+	mockedStore.EXPECT().FindContent(path, 3).Return("", errors.New("no item"))
+	mockedAzure.EXPECT().GetItemContentAt(path, 3).Return("", notFound())
+	mockedStore.EXPECT().FindContent(path, 4).Return("", errors.New("no item"))
+	mockedAzure.EXPECT().GetItemContentAt(path, 4).Return("x\na\nB\nd", nil)
+	// ошибка записи в кеш не прерывает восстановление авторства
+	mockedStore.EXPECT().WriteContent(path, 4, "x\na\nB\nd").Return(errors.New("disk full"))
+
+	lines, err := Annotate(path, mockedAzure, true, mockedStore)
+	require.NoError(t, err)
+	assert.Equal(t, []AnnotatedLine{
+		{Number: 1, Text: "x", Version: &history[3]},
+		{Number: 2, Text: "a", Version: &history[0]},
+		{Number: 3, Text: "B", Version: &history[1]},
+		{Number: 4, Text: "d", Version: &history[1]},
+	}, lines)
+}
+
+func TestAnnotate_noVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedAzure := mock_azure.NewMockAzureInterface(ctrl)
+
+	path := "$/project/main.go"
+	mockedAzure.EXPECT().GetItemHistory(path).Return([]azure.ItemVersion{{ChangesetId: 1}}, nil)
+	mockedAzure.EXPECT().GetItemContentAt(path, 1).Return("", notFound())
+
+	_, err := Annotate(path, mockedAzure, false, nil)
+	assert.EqualError(t, err, "не удалось получить ни одной версии файла "+path)
+}
+
+func TestAnnotate_error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedAzure := mock_azure.NewMockAzureInterface(ctrl)
+
+	// ошибки, кроме отсутствия версии, не пропускаются: иначе авторство строк было бы искажено
+	path := "$/project/main.go"
+	mockedAzure.EXPECT().GetItemHistory(path).Return([]azure.ItemVersion{{ChangesetId: 1}, {ChangesetId: 2}}, nil)
+	mockedAzure.EXPECT().GetItemContentAt(path, 1).Return("a", nil)
+	mockedAzure.EXPECT().GetItemContentAt(path, 2).Return("", errors.New("connection reset"))
+
+	_, err := Annotate(path, mockedAzure, false, nil)
+	assert.EqualError(t, err, "версия файла "+path+" в ченджсете 2: connection reset")
+}
+
+func notFound() error {
+	status := http.StatusNotFound
+	message := "TF401174: The item could not be found"
+	return &azuredevops.WrappedError{Message: &message, StatusCode: &status}
+}
@@ -0,0 +1,123 @@
+package azure
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Способ аутентификации на сервере
+type AuthType string
+
+const (
+	AuthPAT    AuthType = "pat"    // personal access token (Azure DevOps, TFS 2017+)
+	AuthBasic  AuthType = "basic"  // имя пользователя и пароль (TFS с включенной basic-аутентификацией)
+	AuthBearer AuthType = "bearer" // OAuth/bearer-токен
+	AuthNone   AuthType = "none"   // без заголовка Authorization (например, только дополнительные заголовки)
+)
+
+// Authenticator добавляет в каждый запрос к серверу заголовки аутентификации
+type Authenticator interface {
+	Authorize(header http.Header)
+}
+
+type patAuth struct {
+	token string
+}
+
+// PatAuth передает personal access token через basic-аутентификацию с пустым именем пользователя
+func PatAuth(token string) Authenticator {
+	return &patAuth{token: token}
+}
+
+func (a *patAuth) Authorize(header http.Header) {
+	header.Set("Authorization", basicHeader("", a.token))
+}
+
+type basicAuth struct {
+	username string
+	password string
+}
+
+func BasicAuth(username, password string) Authenticator {
+	return &basicAuth{username: username, password: password}
+}
+
+func (a *basicAuth) Authorize(header http.Header) {
+	header.Set("Authorization", basicHeader(a.username, a.password))
+}
+
+type bearerAuth struct {
+	token string
+}
+
+func BearerAuth(token string) Authenticator {
+	return &bearerAuth{token: token}
+}
+
+func (a *bearerAuth) Authorize(header http.Header) {
+	header.Set("Authorization", "Bearer "+a.token)
+}
+
+type headerAuth struct {
+	headers map[string]string
+}
+
+// HeaderAuth добавляет произвольные заголовки (например, ключ API-шлюза перед сервером)
+func HeaderAuth(headers map[string]string) Authenticator {
+	return &headerAuth{headers: headers}
+}
+
+func (a *headerAuth) Authorize(header http.Header) {
+	for name, value := range a.headers {
+		header.Set(name, value)
+	}
+}
+
+type chainAuth []Authenticator
+
+func (a chainAuth) Authorize(header http.Header) {
+	for _, auth := range a {
+		auth.Authorize(header)
+	}
+}
+
+func basicHeader(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// NewAuthenticator возвращает аутентификацию, заданную в конфигурации: Config.Auth, если он указан,
+// иначе способ AuthType (по умолчанию PAT) с дополнительными заголовками Headers
+func NewAuthenticator(config *Config) (Authenticator, error) {
+	if config.Auth != nil {
+		return config.Auth, nil
+	}
+	var auth Authenticator
+	switch config.AuthType {
+	case "", AuthPAT:
+		if config.Token == "" {
+			return nil, errors.New("не задан personal access token")
+		}
+		auth = PatAuth(config.Token)
+	case AuthBasic:
+		if config.Username == "" {
+			return nil, errors.New("для basic-аутентификации нужно указать имя пользователя")
+		}
+		auth = BasicAuth(config.Username, config.Token)
+	case AuthBearer:
+		if config.Token == "" {
+			return nil, errors.New("не задан bearer-токен")
+		}
+		auth = BearerAuth(config.Token)
+	case AuthNone:
+		auth = chainAuth{}
+	default:
+		return nil, fmt.Errorf("неизвестный способ аутентификации '%s', доступны: %s, %s, %s, %s",
+			config.AuthType, AuthPAT, AuthBasic, AuthBearer, AuthNone)
+	}
+	if len(config.Headers) > 0 {
+		auth = chainAuth{auth, HeaderAuth(config.Headers)}
+	}
+	return auth, nil
+}
@@ -3,7 +3,9 @@ package azure
 import (
 	"fmt"
 	"io"
+	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/microsoft/azure-devops-go-api/azuredevops"
@@ -14,31 +16,39 @@ import (
 
 type AzureInterface interface {
 	Azure() *Azure
-	Connect()                           // Подключение к Azure DevOps
+	Connect() error                     // Подключение к Azure DevOps
 	TfvcClientConnection() error        // для Repository.Open()
 	ListOfProjects() ([]*string, error) // Получаем список проектов
 
 	GetChangesets(nameOfProject string) ([]*int, error)              // Получает все id ченджсетов проекта
 	GetChangesetChanges(id *int, project string) (*ChangeSet, error) // получает все изминения для конкретного changeSet
 	ChangedRows(currentFilePath, version string) (int, int, error)   // Принимает ссылки на разные версии файлов возвращает Добавленные и Удаленные строки
+	// Получает ченджсет со списком измененных файлов и их содержимым до и после изменения (project можно не указывать)
+	GetChangesetDetails(id *int, project string) (*ChangeSetDetails, error)
+	GetItemHistory(path string) ([]ItemVersion, error)           // Получает все ченджсеты, изменявшие файл, по возрастанию id
+	GetItemContentAt(path string, changeset int) (string, error) // Получает содержимое файла в версии ченджсета
 }
 
 type ChangeSet struct {
 	ProjectName string
 	Id          int
 	Author      string
+	AuthorId    string // идентификатор учетной записи, не меняется при переименовании
 	Email       string
 	AddedRows   int
 	DeletedRows int
 	Date        time.Time
 	Message     string
 	Hash        string
+	Files       []string // пути измененных файлов
 }
 
 type Azure struct {
 	Config     *Config
 	Connection *azuredevops.Connection
 	TfvcClient tfvc.Client
+
+	settings *connectionSettings
 }
 
 func NewAzure(conf *Config) AzureInterface {
@@ -51,15 +61,45 @@ func (a *Azure) Azure() *Azure {
 	return a
 }
 
-func (a *Azure) Connect() {
-	organizationUrl := a.Config.OrganizationUrl
-	personalAccessToken := a.Config.Token
-
-	connection := azuredevops.NewPatConnection(organizationUrl, personalAccessToken)
+func (a *Azure) Connect() error {
+	auth, err := NewAuthenticator(a.Config)
+	if err != nil {
+		return err
+	}
+	connection := azuredevops.NewAnonymousConnection(a.Config.OrganizationUrl)
+	// Заголовок Authorization передает и сам клиент: так он попадает и в запросы к другим хостам организации
+	header := http.Header{}
+	auth.Authorize(header)
+	connection.AuthorizationString = header.Get("Authorization")
+	transport, err := NewTransport(a.Config.Transport)
+	if err != nil {
+		return err
+	}
+	settings, err := registerConnection(connection, auth, transport)
+	if err != nil {
+		return err
+	}
+	unregisterConnection(a.settings)
 	a.Connection = connection
+	a.TfvcClient = nil
+	a.settings = settings
+	return nil
+}
+
+// Requests возвращает число HTTP-запросов, выполненных к серверу с момента подключения
+func (a *Azure) Requests() int64 {
+	if a.settings == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&a.settings.requests)
 }
 
+// TfvcClientConnection создает клиент TFVC. Повторные вызовы (Repository.Open для каждого проекта)
+// используют уже созданный клиент, поэтому после подключения их можно выполнять параллельно
 func (a *Azure) TfvcClientConnection() error {
+	if a.TfvcClient != nil {
+		return nil
+	}
 	tfvcClient, err := tfvc.NewClient(a.Config.Context, a.Connection)
 	if err != nil {
 		return err
@@ -114,6 +154,7 @@ func (a *Azure) GetChangesetChanges(id *int, project string) (*ChangeSet, error)
 	//получаем кол-во добавленных и удаленных строк
 	addedRows := 0
 	deletedRows := 0
+	files := []string{}
 	for _, v := range getChanges.Value {
 		if v.Item.(map[string]interface{})["isFolder"] != nil {
 			if v.Item.(map[string]interface{})["isFolder"].(bool) {
@@ -122,6 +163,7 @@ func (a *Azure) GetChangesetChanges(id *int, project string) (*ChangeSet, error)
 		}
 
 		path := v.Item.(map[string]interface{})["path"].(string)
+		files = append(files, path)
 		if isImage(path) {
 			continue
 		}
@@ -134,15 +176,21 @@ func (a *Azure) GetChangesetChanges(id *int, project string) (*ChangeSet, error)
 		deletedRows += dr
 	}
 
+	authorId := ""
+	if changes.Author.Id != nil {
+		authorId = *changes.Author.Id
+	}
 	commit := &ChangeSet{
 		ProjectName: project,
 		Id:          *id,
 		Author:      *changes.Author.DisplayName,
+		AuthorId:    authorId,
 		Email:       *changes.Author.UniqueName,
 		Date:        changes.CreatedDate.Time,
 		AddedRows:   addedRows,
 		DeletedRows: deletedRows,
 		Message:     messg,
+		Files:       files,
 	}
 	return commit, nil
 }
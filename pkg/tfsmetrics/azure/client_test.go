@@ -30,12 +30,14 @@ func TestAzure_GetChangesetChanges(t *testing.T) {
 		ProjectName: "project",
 		Id:          1,
 		Author:      "Ivan",
+		AuthorId:    "6b1f9a52-0d1e-4c2b-9d35-2f0f8c1c7a11",
 		Email:       "example@example.com",
 		AddedRows:   2,
 		DeletedRows: 1,
 		Date:        time.Now(),
 		Message:     "hello world",
 		Hash:        "",
+		Files:       []string{"currentFilePath", "image.jpg"},
 	}
 
 	// правильная работа, без ощибки
@@ -43,7 +45,7 @@ func TestAzure_GetChangesetChanges(t *testing.T) {
 		EXPECT().
 		GetChangeset(azure.Config.Context, tfvc.GetChangesetArgs{Id: &cs.Id, Project: &cs.ProjectName}).
 		Return(&git.TfvcChangeset{
-			Author:      &webapi.IdentityRef{DisplayName: &cs.Author, UniqueName: &cs.Email},
+			Author:      &webapi.IdentityRef{DisplayName: &cs.Author, UniqueName: &cs.Email, Id: &cs.AuthorId},
 			CreatedDate: &azuredevops.Time{Time: cs.Date},
 			Comment:     &cs.Message,
 		}, nil)
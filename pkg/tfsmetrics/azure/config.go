@@ -4,14 +4,24 @@ import "context"
 
 type Config struct {
 	OrganizationUrl string `json:"organization_url"`
-	Token           string `json:"personal_access_token"`
-	Context         context.Context `json:"-"`
+	// Personal access token, пароль для basic-аутентификации или bearer-токен (в зависимости от AuthType)
+	Token    string   `json:"personal_access_token"`
+	AuthType AuthType `json:"auth_type,omitempty"`
+	// Имя пользователя для basic-аутентификации
+	Username string `json:"username,omitempty"`
+	// Дополнительные заголовки для каждого запроса
+	Headers map[string]string `json:"headers,omitempty"`
+	// Настройки HTTP-транспорта: прокси, сертификаты, пул соединений
+	Transport TransportConfig `json:"transport"`
+	// Собственная реализация аутентификации, заменяет AuthType
+	Auth    Authenticator   `json:"-"`
+	Context context.Context `json:"-"`
 }
 
 func NewConfig() *Config {
 	return &Config{
-		Context: context.Background(),
+		Context:         context.Background(),
 		OrganizationUrl: "",
-		Token: "",
+		Token:           "",
 	}
 }
@@ -0,0 +1,123 @@
+package azure
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/tfvc"
+)
+
+// Изменение одного файла в ченджсете
+type FileChange struct {
+	Path        string
+	ChangeType  string // тип изменения в терминах TFVC: add, edit, delete, rename...
+	Version     string
+	Binary      bool // для бинарных файлов строки не считаются и содержимое не загружается
+	AddedRows   int
+	DeletedRows int
+	Previous    string // содержимое файла до изменения
+	Current     string // содержимое файла после изменения
+}
+
+// Ченджсет вместе со списком измененных файлов
+type ChangeSetDetails struct {
+	ChangeSet
+	Changes []FileChange
+}
+
+func (a *Azure) GetChangesetDetails(id *int, project string) (*ChangeSetDetails, error) {
+	args := tfvc.GetChangesetArgs{Id: id}
+	if project != "" {
+		args.Project = &project
+	}
+	changeset, err := a.TfvcClient.GetChangeset(a.Config.Context, args)
+	if err != nil {
+		return nil, err
+	}
+	details := &ChangeSetDetails{
+		ChangeSet: ChangeSet{
+			ProjectName: project,
+			Id:          *id,
+			Date:        changeset.CreatedDate.Time,
+		},
+	}
+	if changeset.Author != nil {
+		details.Author = *changeset.Author.DisplayName
+		details.Email = *changeset.Author.UniqueName
+	}
+	if changeset.Comment != nil {
+		details.Message = *changeset.Comment
+	}
+
+	changes, err := a.TfvcClient.GetChangesetChanges(a.Config.Context, tfvc.GetChangesetChangesArgs{Id: id})
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes.Value {
+		item := change.Item.(map[string]interface{})
+		if isFolder, ok := item["isFolder"].(bool); ok && isFolder {
+			continue
+		}
+		file := FileChange{
+			Path:    item["path"].(string),
+			Version: fmt.Sprint(item["version"]),
+			Binary:  isImage(item["path"].(string)),
+		}
+		if change.ChangeType != nil {
+			file.ChangeType = string(*change.ChangeType)
+		}
+		if !file.Binary {
+			err = a.loadFileChange(&file)
+			if err != nil {
+				return nil, err
+			}
+		}
+		details.Files = append(details.Files, file.Path)
+		details.Changes = append(details.Changes, file)
+		details.AddedRows += file.AddedRows
+		details.DeletedRows += file.DeletedRows
+	}
+	return details, nil
+}
+
+// loadFileChange загружает версии файла до и после изменения и считает строки
+func (a *Azure) loadFileChange(file *FileChange) error {
+	previous, err := a.itemContent(file.Path, &git.TfvcVersionDescriptor{Version: &file.Version,
+		VersionOption: &git.TfvcVersionOptionValues.Previous})
+	if strings.Contains(file.ChangeType, string(git.VersionControlChangeTypeValues.Delete)) {
+		if err != nil {
+			return err
+		}
+		file.Previous = previous
+		file.DeletedRows = len(strings.Split(previous, "\n"))
+		return nil
+	}
+	current, currentErr := a.itemContent(file.Path, &git.TfvcVersionDescriptor{Version: &file.Version})
+	if currentErr != nil {
+		return currentErr
+	}
+	file.Current = current
+	if err != nil { // нет прошлой версии - все строки файла добавлены
+		file.AddedRows = len(strings.Split(current, "\n"))
+		return nil
+	}
+	file.Previous = previous
+	file.AddedRows, file.DeletedRows = Diff(previous, current)
+	return nil
+}
+
+func (a *Azure) itemContent(path string, version *git.TfvcVersionDescriptor) (string, error) {
+	content, err := a.TfvcClient.GetItemContent(a.Config.Context, tfvc.GetItemContentArgs{Path: &path,
+		VersionDescriptor: version})
+	if err != nil {
+		return "", err
+	}
+	defer content.Close()
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
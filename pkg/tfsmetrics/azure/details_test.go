@@ -0,0 +1,108 @@
+package azure
+
+import (
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/mock"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/microsoft/azure-devops-go-api/azuredevops"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/tfvc"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/webapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func content(s string) io.ReadCloser {
+	return io.NopCloser(strings.NewReader(s))
+}
+
+func TestAzure_GetChangesetDetails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedClient := mock.NewMockClient(ctrl)
+
+	azure := Azure{
+		Config:     NewConfig(),
+		TfvcClient: mockedClient,
+	}
+	id := 5
+	author := "Ivan"
+	email := "example@example.com"
+	message := "hello world"
+	date := time.Now()
+	mockedClient.
+		EXPECT().
+		GetChangeset(azure.Config.Context, tfvc.GetChangesetArgs{Id: &id}).
+		Return(&git.TfvcChangeset{
+			Author:      &webapi.IdentityRef{DisplayName: &author, UniqueName: &email},
+			CreatedDate: &azuredevops.Time{Time: date},
+			Comment:     &message,
+		}, nil)
+
+	edit := git.VersionControlChangeTypeValues.Edit
+	add := git.VersionControlChangeTypeValues.Add
+	del := git.VersionControlChangeType("delete, encoding")
+	mockedClient.
+		EXPECT().
+		GetChangesetChanges(azure.Config.Context, tfvc.GetChangesetChangesArgs{Id: &id}).
+		Return(&tfvc.GetChangesetChangesResponseValue{Value: []git.TfvcChange{
+			{Item: map[string]interface{}{"isFolder": true, "path": "$/project/dir"}, ChangeType: &add},
+			{Item: map[string]interface{}{"path": "$/project/edited.go", "version": 5}, ChangeType: &edit},
+			{Item: map[string]interface{}{"path": "$/project/new.go", "version": 5}, ChangeType: &add},
+			{Item: map[string]interface{}{"path": "$/project/old.go", "version": 5}, ChangeType: &del},
+			{Item: map[string]interface{}{"path": "$/project/logo.png", "version": 5}, ChangeType: &add},
+		}}, nil)
+
+	version := "5"
+	expectContent := func(path string, previous bool, result io.ReadCloser, err error) {
+		descriptor := &git.TfvcVersionDescriptor{Version: &version}
+		if previous {
+			descriptor.VersionOption = &git.TfvcVersionOptionValues.Previous
+		}
+		p := path
+		mockedClient.
+			EXPECT().
+			GetItemContent(azure.Config.Context, tfvc.GetItemContentArgs{Path: &p, VersionDescriptor: descriptor}).
+			Return(result, err)
+	}
+	expectContent("$/project/edited.go", true, content("a\nb\nc"), nil)
+	expectContent("$/project/edited.go", false, content("a\nB\nc\nd"), nil)
+	expectContent("$/project/new.go", true, nil, errors.New("no previous version"))
+	expectContent("$/project/new.go", false, content("x\ny"), nil)
+	expectContent("$/project/old.go", true, content("1\n2\n3"), nil)
+
+	details, err := azure.GetChangesetDetails(&id, "")
+	require.NoError(t, err)
+	assert.Equal(t, ChangeSet{
+		Id:          id,
+		Author:      author,
+		Email:       email,
+		Date:        date,
+		Message:     message,
+		AddedRows:   4,
+		DeletedRows: 4,
+		Files:       []string{"$/project/edited.go", "$/project/new.go", "$/project/old.go", "$/project/logo.png"},
+	}, details.ChangeSet)
+	assert.Equal(t, []FileChange{
+		{Path: "$/project/edited.go", ChangeType: "edit", Version: "5", AddedRows: 2, DeletedRows: 1,
+			Previous: "a\nb\nc", Current: "a\nB\nc\nd"},
+		{Path: "$/project/new.go", ChangeType: "add", Version: "5", AddedRows: 2, Current: "x\ny"},
+		{Path: "$/project/old.go", ChangeType: "delete, encoding", Version: "5", DeletedRows: 3, Previous: "1\n2\n3"},
+		{Path: "$/project/logo.png", ChangeType: "add", Version: "5", Binary: true},
+	}, details.Changes)
+
+	// azure возвращает ошибку
+	id++
+	mockedClient.
+		EXPECT().
+		GetChangeset(azure.Config.Context, tfvc.GetChangesetArgs{Id: &id}).
+		Return(nil, errors.New("error"))
+	details, err = azure.GetChangesetDetails(&id, "")
+	assert.Error(t, err)
+	assert.Nil(t, details)
+}
@@ -0,0 +1,92 @@
+package azure
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/location"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/tfvc"
+)
+
+// Результат проверки доступа к одному из API
+type APICheck struct {
+	API string
+	// Область personal access token, которая нужна для API
+	Scope string
+	Err   error
+}
+
+// AuthenticatedUser возвращает имя пользователя, от имени которого сервер выполняет запросы
+func (a *Azure) AuthenticatedUser() (string, error) {
+	client := location.NewClient(a.Config.Context, a.Connection)
+	data, err := client.GetConnectionData(a.Config.Context, location.GetConnectionDataArgs{})
+	if err != nil {
+		return "", err
+	}
+	if data.AuthenticatedUser == nil || data.AuthenticatedUser.ProviderDisplayName == nil {
+		return "", errors.New("сервер не вернул данные пользователя")
+	}
+	return *data.AuthenticatedUser.ProviderDisplayName, nil
+}
+
+// CheckAPIs проверяет доступ к API, которые использует библиотека, запрашивая по одному элементу
+func (a *Azure) CheckAPIs() []APICheck {
+	ctx := a.Config.Context
+	top := 1
+	checks := []struct {
+		api   string
+		scope string
+		check func() error
+	}{
+		{"core", "Project and Team (Read)", func() error {
+			client, err := core.NewClient(ctx, a.Connection)
+			if err != nil {
+				return err
+			}
+			_, err = client.GetProjects(ctx, core.GetProjectsArgs{Top: &top})
+			return err
+		}},
+		{"tfvc", "Code (Read)", func() error {
+			client, err := tfvc.NewClient(ctx, a.Connection)
+			if err != nil {
+				return err
+			}
+			_, err = client.GetChangesets(ctx, tfvc.GetChangesetsArgs{Top: &top})
+			return err
+		}},
+		{"git", "Code (Read)", func() error {
+			client, err := git.NewClient(ctx, a.Connection)
+			if err != nil {
+				return err
+			}
+			_, err = client.GetRepositories(ctx, git.GetRepositoriesArgs{})
+			return err
+		}},
+	}
+	res := make([]APICheck, len(checks))
+	for i, check := range checks {
+		res[i] = APICheck{API: check.api, Scope: check.scope, Err: check.check()}
+	}
+	return res
+}
+
+// StatusCode возвращает HTTP-код ответа из ошибки клиента Azure DevOps (0, если ошибка не связана с ответом сервера)
+func StatusCode(err error) int {
+	var wrapped *azuredevops.WrappedError
+	if errors.As(err, &wrapped) && wrapped.StatusCode != nil {
+		return *wrapped.StatusCode
+	}
+	var value azuredevops.WrappedError
+	if errors.As(err, &value) && value.StatusCode != nil {
+		return *value.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound возвращает true, если сервер ответил, что запрошенного элемента нет (код 404)
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
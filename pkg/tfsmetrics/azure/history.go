@@ -0,0 +1,67 @@
+package azure
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/tfvc"
+)
+
+// Версия элемента TFVC - ченджсет, в котором элемент изменялся
+type ItemVersion struct {
+	ChangesetId int
+	Author      string
+	Email       string
+	Date        time.Time
+	Message     string
+}
+
+// Количество ченджсетов, запрашиваемых за один раз
+const historyPageSize = 100
+
+// GetItemHistory возвращает ченджсеты, изменявшие элемент, по возрастанию id. История до переименования
+// не запрашивается: содержимое версий читается по текущему пути, а до переименования элемента по нему не было
+func (a *Azure) GetItemHistory(path string) ([]ItemVersion, error) {
+	followRenames := false
+	orderBy := "id asc"
+	versions := []ItemVersion{}
+	for skip := 0; ; skip += historyPageSize {
+		top, offset := historyPageSize, skip
+		refs, err := a.TfvcClient.GetChangesets(a.Config.Context, tfvc.GetChangesetsArgs{
+			Top:     &top,
+			Skip:    &offset,
+			Orderby: &orderBy,
+			SearchCriteria: &git.TfvcChangesetSearchCriteria{
+				ItemPath:      &path,
+				FollowRenames: &followRenames,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range *refs {
+			version := ItemVersion{ChangesetId: *ref.ChangesetId}
+			if ref.Author != nil {
+				version.Author = *ref.Author.DisplayName
+				version.Email = *ref.Author.UniqueName
+			}
+			if ref.CreatedDate != nil {
+				version.Date = ref.CreatedDate.Time
+			}
+			if ref.Comment != nil {
+				version.Message = *ref.Comment
+			}
+			versions = append(versions, version)
+		}
+		if len(*refs) < historyPageSize {
+			return versions, nil
+		}
+	}
+}
+
+func (a *Azure) GetItemContentAt(path string, changeset int) (string, error) {
+	version := strconv.Itoa(changeset)
+	return a.itemContent(path, &git.TfvcVersionDescriptor{Version: &version,
+		VersionType: &git.TfvcVersionTypeValues.Changeset})
+}
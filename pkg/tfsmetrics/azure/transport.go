@@ -0,0 +1,196 @@
+package azure
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops"
+)
+
+// Параметры HTTP-транспорта для подключения к серверу. Пустые поля оставляют настройки по умолчанию
+type TransportConfig struct {
+	// Прокси-сервер (по умолчанию берется из HTTP_PROXY/HTTPS_PROXY)
+	ProxyUrl string `json:"proxy_url,omitempty"`
+	// Дополнительные сертификаты CA в формате PEM (к системным)
+	CAFile string `json:"ca_file,omitempty"`
+	// Клиентский сертификат и ключ в формате PEM
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+	// Отключает проверку сертификата сервера. Только для отладки!
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// Ограничения пула соединений (см. http.Transport)
+	MaxIdleConns        int `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host,omitempty"`
+	MaxConnsPerHost     int `json:"max_conns_per_host,omitempty"`
+}
+
+func (c *TransportConfig) isDefault() bool {
+	return *c == TransportConfig{}
+}
+
+// Клиенты azure-devops-go-api создают http.Client без возможности подменить транспорт, поэтому все их запросы
+// выполняются через http.DefaultTransport. Он оборачивается транспортом, который узнает запросы подключения
+// по метке в заголовке User-Agent (Connection.UserAgent) и выполняет их с настройками этого подключения:
+// так несколько подключений к одному серверу не мешают друг другу. Остальные запросы процесса
+// передаются исходному транспорту без изменений
+var (
+	installTransport sync.Once
+	defaultTransport = http.DefaultTransport
+	connections      sync.Map // метка -> *connectionSettings
+	lastConnection   int64
+)
+
+const connectionMarker = "tfc-connection/"
+
+type connectionSettings struct {
+	marker    string
+	host      string
+	auth      Authenticator
+	transport http.RoundTripper
+	// Число выполненных запросов (для отображения прогресса)
+	requests int64
+}
+
+type connectionTransport struct{}
+
+func (t *connectionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	userAgent := req.Header.Get("User-Agent")
+	settings := findConnection(userAgent)
+	if settings == nil {
+		return defaultTransport.RoundTrip(req)
+	}
+	// RoundTripper не должен изменять исходный запрос
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", strings.TrimSpace(strings.TrimSuffix(userAgent, settings.marker)))
+	atomic.AddInt64(&settings.requests, 1)
+	// Заголовки аутентификации передаются только на хост подключения
+	if req.URL.Host == settings.host {
+		settings.auth.Authorize(req.Header)
+	}
+	return settings.transport.RoundTrip(req)
+}
+
+// findConnection возвращает настройки подключения по метке в конце User-Agent
+func findConnection(userAgent string) *connectionSettings {
+	i := strings.LastIndex(userAgent, connectionMarker)
+	if i < 0 {
+		return nil
+	}
+	settings, ok := connections.Load(userAgent[i:])
+	if !ok {
+		return nil
+	}
+	return settings.(*connectionSettings)
+}
+
+// registerConnection сохраняет настройки подключения и помечает его запросы
+func registerConnection(connection *azuredevops.Connection, auth Authenticator, transport http.RoundTripper) (*connectionSettings, error) {
+	u, err := url.Parse(connection.BaseUrl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("некорректный url подключения '%s'", connection.BaseUrl)
+	}
+	installTransport.Do(func() {
+		http.DefaultTransport = &connectionTransport{}
+	})
+	settings := &connectionSettings{
+		marker:    connectionMarker + strconv.FormatInt(atomic.AddInt64(&lastConnection, 1), 10),
+		host:      u.Host,
+		auth:      auth,
+		transport: transport,
+	}
+	connections.Store(settings.marker, settings)
+	connection.UserAgent = settings.marker
+	return settings, nil
+}
+
+// unregisterConnection удаляет настройки подключения (при повторном подключении)
+func unregisterConnection(settings *connectionSettings) {
+	if settings != nil {
+		connections.Delete(settings.marker)
+	}
+}
+
+// NewTransport создает HTTP-транспорт с заданными настройками
+func NewTransport(config TransportConfig) (http.RoundTripper, error) {
+	if config.isDefault() {
+		return defaultTransport, nil
+	}
+	base, ok := defaultTransport.(*http.Transport)
+	if !ok {
+		base = &http.Transport{Proxy: http.ProxyFromEnvironment}
+	}
+	transport := base.Clone()
+	if config.ProxyUrl != "" {
+		proxy, err := url.Parse(config.ProxyUrl)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный адрес прокси '%s': %w", config.ProxyUrl, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+	return transport, nil
+}
+
+func (c *TransportConfig) tlsConfig() (*tls.Config, error) {
+	if c.CAFile == "" && c.CertFile == "" && c.KeyFile == "" && !c.InsecureSkipVerify {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("в файле %s нет сертификатов в формате PEM", c.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, fmt.Errorf("для клиентского сертификата нужно указать и сертификат, и ключ")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if c.InsecureSkipVerify {
+		log.Printf("ВНИМАНИЕ: проверка сертификата сервера отключена (insecure-skip-verify), " +
+			"соединение не защищено от перехвата. Не используйте этот режим постоянно!")
+		config.InsecureSkipVerify = true
+	}
+	return config, nil
+}
@@ -0,0 +1,147 @@
+package azure
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzure_Connect_caFile(t *testing.T) {
+	requests := make(chan http.Header, 10)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Header.Clone()
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "transport")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	caPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caFile, caPem, 0600))
+
+	// без сертификата CA сервер не проходит проверку и запрос до него не доходит
+	config := Config{OrganizationUrl: server.URL, Token: "12345", Context: context.Background()}
+	azure := NewAzure(&config)
+	require.NoError(t, azure.Connect())
+	_, err = azure.ListOfProjects()
+	assert.Error(t, err)
+	assert.Empty(t, requests)
+
+	config.Transport.CAFile = caFile
+	require.NoError(t, azure.Connect())
+	_, err = azure.ListOfProjects()
+	assert.Error(t, err)
+	require.NotEmpty(t, requests)
+	assert.Equal(t, "Basic OjEyMzQ1", (<-requests).Get("Authorization"))
+}
+
+func TestAzure_Connect_proxy(t *testing.T) {
+	type proxied struct {
+		host          string
+		authorization string
+	}
+	requests := make(chan proxied, 10)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- proxied{host: r.URL.Host, authorization: r.Header.Get("Authorization")}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+
+	config := Config{
+		OrganizationUrl: "http://tfs.internal:8080/tfs/collection",
+		Token:           "12345",
+		Context:         context.Background(),
+		Transport:       TransportConfig{ProxyUrl: proxy.URL},
+	}
+	azure := NewAzure(&config)
+	require.NoError(t, azure.Connect())
+	_, err := azure.ListOfProjects()
+	assert.Error(t, err)
+	require.NotEmpty(t, requests)
+	assert.Equal(t, proxied{host: "tfs.internal:8080", authorization: "Basic OjEyMzQ1"}, <-requests)
+}
+
+func TestAzure_Connect_sameHost(t *testing.T) {
+	type request struct {
+		authorization string
+		userAgent     string
+	}
+	requests := make(chan request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- request{authorization: r.Header.Get("Authorization"), userAgent: r.Header.Get("User-Agent")}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	// профили на одном сервере с разными токенами не перезаписывают настройки друг друга
+	first := NewAzure(&Config{OrganizationUrl: server.URL, Token: "first", Context: context.Background()})
+	second := NewAzure(&Config{OrganizationUrl: server.URL, Token: "second", Context: context.Background()})
+	require.NoError(t, first.Connect())
+	require.NoError(t, second.Connect())
+
+	_, err := first.ListOfProjects()
+	assert.Error(t, err)
+	require.Len(t, requests, 1)
+	got := <-requests
+	assert.Equal(t, "Basic OmZpcnN0", got.authorization)
+	assert.NotContains(t, got.userAgent, connectionMarker)
+	assert.Equal(t, int64(1), first.Azure().Requests())
+	assert.Equal(t, int64(0), second.Azure().Requests())
+
+	_, err = second.ListOfProjects()
+	assert.Error(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "Basic OnNlY29uZA==", (<-requests).authorization)
+	assert.Equal(t, int64(1), second.Azure().Requests())
+
+	// остальные клиенты процесса ходят на тот же сервер без заголовков подключения
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Empty(t, (<-requests).authorization)
+	assert.Equal(t, int64(1), first.Azure().Requests())
+}
+
+func TestNewTransport(t *testing.T) {
+	transport, err := NewTransport(TransportConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, defaultTransport, transport)
+
+	transport, err = NewTransport(TransportConfig{MaxIdleConns: 5, MaxIdleConnsPerHost: 2, MaxConnsPerHost: 4, InsecureSkipVerify: true})
+	require.NoError(t, err)
+	httpTransport := transport.(*http.Transport)
+	assert.Equal(t, 5, httpTransport.MaxIdleConns)
+	assert.Equal(t, 2, httpTransport.MaxIdleConnsPerHost)
+	assert.Equal(t, 4, httpTransport.MaxConnsPerHost)
+	assert.True(t, httpTransport.TLSClientConfig.InsecureSkipVerify)
+
+	errors := []TransportConfig{
+		{ProxyUrl: "://proxy"},
+		{CAFile: "missing.pem"},
+		{CertFile: "client.pem"},
+	}
+	for _, config := range errors {
+		_, err = NewTransport(config)
+		assert.Error(t, err)
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	status := http.StatusUnauthorized
+	message := "unauthorized"
+	assert.Equal(t, status, StatusCode(&azuredevops.WrappedError{Message: &message, StatusCode: &status}))
+	assert.Equal(t, status, StatusCode(azuredevops.WrappedError{Message: &message, StatusCode: &status}))
+	assert.Equal(t, 0, StatusCode(errors.New("EOF")))
+}
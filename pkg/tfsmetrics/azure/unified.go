@@ -0,0 +1,102 @@
+package azure
+
+import (
+	"fmt"
+	"strings"
+)
+
+type diffLine struct {
+	op   byte // ' ', '-' или '+'
+	text string
+}
+
+// UnifiedDiff строит unified diff (как diff -u) по результату DiffChunks.
+// context - количество неизмененных строк вокруг каждого изменения.
+// Если файлы совпадают, возвращается пустая строка
+func UnifiedDiff(fromFile, toFile string, a, b []string, context int) string {
+	lines := []diffLine{}
+	changed := false
+	for _, chunk := range DiffChunks(a, b) {
+		for _, line := range chunk.Deleted {
+			lines = append(lines, diffLine{'-', line})
+			changed = true
+		}
+		for _, line := range chunk.Added {
+			lines = append(lines, diffLine{'+', line})
+			changed = true
+		}
+		for _, line := range chunk.Equal {
+			lines = append(lines, diffLine{' ', line})
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	// номера строк старого и нового файла перед каждой строкой diff
+	oldLine := make([]int, len(lines)+1)
+	newLine := make([]int, len(lines)+1)
+	for i, line := range lines {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if line.op != '+' {
+			oldLine[i+1]++
+		}
+		if line.op != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	buf := strings.Builder{}
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromFile, toFile)
+	for i := 0; i < len(lines); {
+		for i < len(lines) && lines[i].op == ' ' {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for {
+			for end < len(lines) && lines[end].op != ' ' {
+				end++
+			}
+			next := end
+			for next < len(lines) && lines[next].op == ' ' {
+				next++
+			}
+			// изменения, между которыми не больше 2*context строк, попадают в один блок
+			if next < len(lines) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end += context
+			if end > len(lines) {
+				end = len(lines)
+			}
+			break
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(oldLine[start], oldLine[end]-oldLine[start]),
+			hunkRange(newLine[start], newLine[end]-newLine[start]))
+		for _, line := range lines[start:end] {
+			buf.WriteByte(line.op)
+			buf.WriteString(line.text)
+			buf.WriteByte('\n')
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
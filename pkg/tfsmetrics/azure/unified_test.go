@@ -0,0 +1,85 @@
+package azure
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "1\n2",
+			b:    "1\n2",
+			want: "",
+		},
+		{
+			name: "two hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15",
+			b:    "1\n2x\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13y\n14\n15\n16",
+			want: `--- a
++++ b
+@@ -1,5 +1,5 @@
+ 1
+-2
++2x
+ 3
+ 4
+ 5
+@@ -10,6 +10,7 @@
+ 10
+ 11
+ 12
+-13
++13y
+ 14
+ 15
++16
+`,
+		},
+		{
+			name: "merged hunk",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8",
+			b:    "1\n2x\n3\n4\n5\n6\n7x\n8",
+			want: `--- a
++++ b
+@@ -1,8 +1,8 @@
+ 1
+-2
++2x
+ 3
+ 4
+ 5
+ 6
+-7
++7x
+ 8
+`,
+		},
+		{
+			name: "new file",
+			a:    "",
+			b:    "b\nc",
+			want: `--- a
++++ b
+@@ -1 +1,2 @@
+-
++b
++c
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff("a", "b", strings.Split(tt.a, "\n"), strings.Split(tt.b, "\n"), 3)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package combinator содержит функции для построения обработки коммитов из итераторов:
+// отбор, преобразование, ограничение, слияние нескольких итераторов и раздача одного прохода
+// нескольким потребителям. Ошибка исходного итератора (кроме ErrNoMoreItems) передается дальше без изменений
+package combinator
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+)
+
+type sliceIterator struct {
+	index   int
+	commits []*repointerface.Commit
+}
+
+// FromSlice возвращает итератор по коммитам из памяти
+func FromSlice(commits []*repointerface.Commit) repointerface.CommitIterator {
+	return &sliceIterator{commits: commits}
+}
+
+func (i *sliceIterator) Next() (*repointerface.Commit, error) {
+	if i.index < len(i.commits) {
+		i.index++
+		return i.commits[i.index-1], nil
+	}
+	return nil, repointerface.ErrNoMoreItems
+}
+
+// Collect вычитывает итератор целиком. Если итератор завершился не на ErrNoMoreItems,
+// возвращается ошибка вместе с уже прочитанными коммитами
+func Collect(it repointerface.CommitIterator) ([]*repointerface.Commit, error) {
+	commits := []*repointerface.Commit{}
+	commit, err := it.Next()
+	for ; err == nil; commit, err = it.Next() {
+		commits = append(commits, commit)
+	}
+	if err != repointerface.ErrNoMoreItems {
+		return commits, err
+	}
+	return commits, nil
+}
+
+type filterIterator struct {
+	iterator  repointerface.CommitIterator
+	predicate func(commit *repointerface.Commit) bool
+}
+
+// Filter возвращает только коммиты, для которых predicate возвращает true
+func Filter(it repointerface.CommitIterator, predicate func(commit *repointerface.Commit) bool) repointerface.CommitIterator {
+	return &filterIterator{
+		iterator:  it,
+		predicate: predicate,
+	}
+}
+
+func (i *filterIterator) Next() (*repointerface.Commit, error) {
+	for {
+		commit, err := i.iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if i.predicate(commit) {
+			return commit, nil
+		}
+	}
+}
+
+type mapIterator struct {
+	iterator repointerface.CommitIterator
+	fn       func(commit *repointerface.Commit) *repointerface.Commit
+}
+
+// Map возвращает коммиты, преобразованные функцией fn (например, с нормализованным именем автора).
+// Коммиты исходного итератора могут быть общими с другими потребителями, поэтому fn не должна их изменять
+func Map(it repointerface.CommitIterator, fn func(commit *repointerface.Commit) *repointerface.Commit) repointerface.CommitIterator {
+	return &mapIterator{
+		iterator: it,
+		fn:       fn,
+	}
+}
+
+func (i *mapIterator) Next() (*repointerface.Commit, error) {
+	commit, err := i.iterator.Next()
+	if err != nil {
+		return nil, err
+	}
+	return i.fn(commit), nil
+}
+
+type takeIterator struct {
+	iterator repointerface.CommitIterator
+	left     int
+}
+
+// Take возвращает не больше n первых коммитов. Ошибка ChangesetError передается дальше, но в n не засчитывается:
+// ограничивается число полученных коммитов, а не ченджсетов, поэтому пропущенный ченджсет не уменьшает выборку
+func Take(it repointerface.CommitIterator, n int) repointerface.CommitIterator {
+	return &takeIterator{
+		iterator: it,
+		left:     n,
+	}
+}
+
+func (i *takeIterator) Next() (*repointerface.Commit, error) {
+	if i.left <= 0 {
+		return nil, repointerface.ErrNoMoreItems
+	}
+	commit, err := i.iterator.Next()
+	if err == nil {
+		i.left--
+	}
+	return commit, err
+}
+
+// Итератор по группам коммитов
+type BatchIterator interface {
+	// Возвращает следующую группу (последняя может быть меньше size) или ErrNoMoreItems.
+	// Если исходный итератор вернул ошибку, она возвращается вместе с коммитами, прочитанными до нее
+	Next() ([]*repointerface.Commit, error)
+}
+
+type batchIterator struct {
+	iterator repointerface.CommitIterator
+	size     int
+	done     bool
+}
+
+// Batch группирует коммиты по size штук (например, чтобы записывать их в кеш одной транзакцией)
+func Batch(it repointerface.CommitIterator, size int) BatchIterator {
+	if size < 1 {
+		size = 1
+	}
+	return &batchIterator{
+		iterator: it,
+		size:     size,
+	}
+}
+
+func (i *batchIterator) Next() ([]*repointerface.Commit, error) {
+	if i.done {
+		return nil, repointerface.ErrNoMoreItems
+	}
+	batch := make([]*repointerface.Commit, 0, i.size)
+	for len(batch) < i.size {
+		commit, err := i.iterator.Next()
+		if err == repointerface.ErrNoMoreItems {
+			i.done = true
+			break
+		}
+		if err != nil {
+			return batch, err
+		}
+		batch = append(batch, commit)
+	}
+	if len(batch) == 0 {
+		return nil, repointerface.ErrNoMoreItems
+	}
+	return batch, nil
+}
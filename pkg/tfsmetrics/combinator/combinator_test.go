@@ -0,0 +1,136 @@
+package combinator
+
+import (
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var baseDate = time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// commits создает коммиты с заданными id, сделанные через id часов после baseDate
+func commits(ids ...int) []*repointerface.Commit {
+	res := make([]*repointerface.Commit, len(ids))
+	for i, id := range ids {
+		res[i] = &repointerface.Commit{
+			Id:        id,
+			Author:    "Ivan",
+			AddedRows: id,
+			Date:      baseDate.Add(time.Duration(id) * time.Hour),
+		}
+	}
+	return res
+}
+
+func ids(t *testing.T, it repointerface.CommitIterator) []int {
+	all, err := Collect(it)
+	require.NoError(t, err)
+	res := []int{}
+	for _, commit := range all {
+		res = append(res, commit.Id)
+	}
+	return res
+}
+
+// Итератор, который после заданных коммитов возвращает ошибку
+type failingIterator struct {
+	repointerface.CommitIterator
+	err error
+}
+
+func (i *failingIterator) Next() (*repointerface.Commit, error) {
+	commit, err := i.CommitIterator.Next()
+	if err == repointerface.ErrNoMoreItems {
+		return nil, i.err
+	}
+	return commit, err
+}
+
+func TestCollect(t *testing.T) {
+	all, err := Collect(FromSlice(commits(1, 2)))
+	assert.NoError(t, err)
+	assert.Equal(t, commits(1, 2), all)
+
+	all, err = Collect(&failingIterator{CommitIterator: FromSlice(commits(1)), err: errors.New("timeout")})
+	assert.EqualError(t, err, "timeout")
+	assert.Equal(t, commits(1), all)
+}
+
+func TestFilterMapTake(t *testing.T) {
+	even := func(commit *repointerface.Commit) bool {
+		return commit.Id%2 == 0
+	}
+	assert.Equal(t, []int{2, 4}, ids(t, Filter(FromSlice(commits(1, 2, 3, 4)), even)))
+	assert.Equal(t, []int{1, 2}, ids(t, Take(FromSlice(commits(1, 2, 3)), 2)))
+	assert.Equal(t, []int{}, ids(t, Take(FromSlice(commits(1, 2, 3)), 0)))
+
+	upper := Map(FromSlice(commits(1)), func(commit *repointerface.Commit) *repointerface.Commit {
+		res := *commit
+		res.Author = strings.ToUpper(res.Author)
+		return &res
+	})
+	all, err := Collect(upper)
+	require.NoError(t, err)
+	assert.Equal(t, "IVAN", all[0].Author)
+
+	// Ошибка исходного итератора передается дальше
+	_, err = Collect(Take(Filter(&failingIterator{CommitIterator: FromSlice(commits(1)), err: errors.New("timeout")}, even), 5))
+	assert.EqualError(t, err, "timeout")
+}
+
+func TestBatch(t *testing.T) {
+	batches := Batch(FromSlice(commits(1, 2, 3, 4, 5)), 2)
+	sizes := []int{}
+	batch, err := batches.Next()
+	for ; err == nil; batch, err = batches.Next() {
+		sizes = append(sizes, len(batch))
+	}
+	assert.Equal(t, repointerface.ErrNoMoreItems, err)
+	assert.Equal(t, []int{2, 2, 1}, sizes)
+
+	batches = Batch(&failingIterator{CommitIterator: FromSlice(commits(1, 2, 3)), err: errors.New("timeout")}, 2)
+	batch, err = batches.Next()
+	assert.NoError(t, err)
+	assert.Len(t, batch, 2)
+	batch, err = batches.Next()
+	assert.EqualError(t, err, "timeout")
+	assert.Equal(t, commits(3), batch)
+
+	_, err = Batch(FromSlice(nil), 2).Next()
+	assert.Equal(t, repointerface.ErrNoMoreItems, err)
+}
+
+// Итератор, возвращающий ChangesetError вместо коммита с id skip
+type skippingIterator struct {
+	repointerface.CommitIterator
+	skip int
+}
+
+func (i *skippingIterator) Next() (*repointerface.Commit, error) {
+	commit, err := i.CommitIterator.Next()
+	if err == nil && commit.Id == i.skip {
+		return nil, &repointerface.ChangesetError{Id: commit.Id, Err: errors.New("timeout")}
+	}
+	return commit, err
+}
+
+func TestTake_changesetError(t *testing.T) {
+	// Пропущенный ченджсет не засчитывается в n
+	it := Take(&skippingIterator{CommitIterator: FromSlice(commits(1, 2, 3, 4)), skip: 2}, 2)
+	commit, err := it.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 1, commit.Id)
+	_, err = it.Next()
+	var changesetErr *repointerface.ChangesetError
+	assert.ErrorAs(t, err, &changesetErr)
+	commit, err = it.Next()
+	require.NoError(t, err)
+	assert.Equal(t, 3, commit.Id)
+	_, err = it.Next()
+	assert.Equal(t, repointerface.ErrNoMoreItems, err)
+}
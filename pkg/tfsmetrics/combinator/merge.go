@@ -0,0 +1,93 @@
+package combinator
+
+import (
+	"container/heap"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+)
+
+// Порядок коммитов: возвращает true, если a должен идти раньше b
+type LessFunc func(a, b *repointerface.Commit) bool
+
+// ByDate упорядочивает коммиты по возрастанию даты, а коммиты с одинаковой датой - по id
+func ByDate(a, b *repointerface.Commit) bool {
+	if a.Date.Equal(b.Date) {
+		return a.Id < b.Id
+	}
+	return a.Date.Before(b.Date)
+}
+
+// Голова одного из сливаемых итераторов
+type mergeHead struct {
+	commit   *repointerface.Commit
+	iterator repointerface.CommitIterator
+}
+
+type mergeHeap struct {
+	heads []mergeHead
+	less  LessFunc
+}
+
+func (h *mergeHeap) Len() int           { return len(h.heads) }
+func (h *mergeHeap) Less(i, j int) bool { return h.less(h.heads[i].commit, h.heads[j].commit) }
+func (h *mergeHeap) Swap(i, j int)      { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+func (h *mergeHeap) Push(x interface{}) { h.heads = append(h.heads, x.(mergeHead)) }
+func (h *mergeHeap) Pop() interface{} {
+	last := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return last
+}
+
+type mergeIterator struct {
+	// Итераторы, первый коммит которых еще не прочитан
+	pending []repointerface.CommitIterator
+	heap    *mergeHeap
+}
+
+// Merge сливает итераторы, каждый из которых упорядочен по возрастанию даты (например, коммиты
+// нескольких проектов), в один итератор, упорядоченный по возрастанию даты
+func Merge(its ...repointerface.CommitIterator) repointerface.CommitIterator {
+	return MergeFunc(ByDate, its...)
+}
+
+// MergeFunc сливает итераторы, упорядоченные в порядке less, в один итератор в том же порядке.
+// Для убывающего порядка (сначала новые коммиты) передайте less с обратным сравнением
+func MergeFunc(less LessFunc, its ...repointerface.CommitIterator) repointerface.CommitIterator {
+	return &mergeIterator{
+		pending: its,
+		heap:    &mergeHeap{less: less},
+	}
+}
+
+func (i *mergeIterator) Next() (*repointerface.Commit, error) {
+	// Первые коммиты читаются при первом вызове, чтобы ошибка вернулась из Next.
+	// После ошибки итератор не отбрасывается: следующий вызов Next снова его прочитает
+	for len(i.pending) > 0 {
+		if err := i.push(i.pending[0]); err != nil {
+			return nil, err
+		}
+		i.pending = i.pending[1:]
+	}
+	if i.heap.Len() == 0 {
+		return nil, repointerface.ErrNoMoreItems
+	}
+	head := heap.Pop(i.heap).(mergeHead)
+	if err := i.push(head.iterator); err != nil {
+		// Коммит, уже снятый с кучи, возвращается при следующем вызове
+		heap.Push(i.heap, head)
+		return nil, err
+	}
+	return head.commit, nil
+}
+
+// push читает следующий коммит итератора и добавляет его в кучу
+func (i *mergeIterator) push(it repointerface.CommitIterator) error {
+	commit, err := it.Next()
+	if err == repointerface.ErrNoMoreItems {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	heap.Push(i.heap, mergeHead{commit: commit, iterator: it})
+	return nil
+}
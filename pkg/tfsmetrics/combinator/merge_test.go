@@ -0,0 +1,65 @@
+package combinator
+
+import (
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	merged := Merge(FromSlice(commits(1, 4, 7)), FromSlice(commits(2, 3)), FromSlice(nil), FromSlice(commits(5, 6, 8)))
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8}, ids(t, merged))
+
+	// Коммиты с одинаковой датой упорядочиваются по id
+	late, early := commits(3), commits(1)
+	late[0].Date = early[0].Date
+	assert.Equal(t, []int{1, 3}, ids(t, Merge(FromSlice(late), FromSlice(early))))
+
+	newestFirst := func(a, b *repointerface.Commit) bool {
+		return ByDate(b, a)
+	}
+	merged = MergeFunc(newestFirst, FromSlice(commits(7, 4, 1)), FromSlice(commits(3, 2)))
+	assert.Equal(t, []int{7, 4, 3, 2, 1}, ids(t, merged))
+}
+
+func TestMerge_error(t *testing.T) {
+	failure := &repointerface.ChangesetError{Id: 3, Err: errors.New("timeout")}
+	broken := &brokenIterator{CommitIterator: FromSlice(commits(2, 3, 4)), broken: 3, err: failure}
+	merged := Merge(FromSlice(commits(1, 5)), broken)
+
+	res := []int{}
+	var errs []error
+	for {
+		commit, err := merged.Next()
+		if err == repointerface.ErrNoMoreItems {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		res = append(res, commit.Id)
+	}
+	// После ошибки ченджсета слияние продолжается, коммиты не теряются
+	require.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], failure)
+	assert.Equal(t, []int{1, 2, 4, 5}, res)
+}
+
+// Итератор, который не может получить коммит с заданным id
+type brokenIterator struct {
+	repointerface.CommitIterator
+	broken int
+	err    error
+}
+
+func (i *brokenIterator) Next() (*repointerface.Commit, error) {
+	commit, err := i.CommitIterator.Next()
+	if err == nil && commit.Id == i.broken {
+		return nil, i.err
+	}
+	return commit, err
+}
@@ -0,0 +1,85 @@
+package combinator
+
+import (
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"sync"
+)
+
+// Сколько коммитов может накопиться для потребителя, пока он обрабатывает предыдущие
+const teeBuffer = 64
+
+// Потребитель коммитов для Tee (например, метрики экспортера, сводка CLI или запись в кеш)
+type Consumer func(it repointerface.CommitIterator) error
+
+type teeItem struct {
+	commit *repointerface.Commit
+	err    error
+}
+
+type teeIterator struct {
+	items <-chan teeItem
+}
+
+func (i *teeIterator) Next() (*repointerface.Commit, error) {
+	item, ok := <-i.items
+	if !ok {
+		return nil, repointerface.ErrNoMoreItems
+	}
+	return item.commit, item.err
+}
+
+// Tee читает исходный итератор один раз и передает каждый коммит всем потребителям, так что один проход
+// по сети может одновременно заполнить, например, метрики экспортера и сводку. Каждый потребитель выполняется
+// в своей горутине и получает свой итератор с теми же коммитами и ошибками. Ошибка ChangesetError не прерывает
+// чтение: потребители могут пропустить ченджсет. Потребитель может завершиться, не дочитав итератор, остальные
+// продолжат получать коммиты. Возвращает ошибку исходного итератора или первую ошибку потребителей
+func Tee(it repointerface.CommitIterator, consumers ...Consumer) error {
+	items := make([]chan teeItem, len(consumers))
+	done := make([]chan struct{}, len(consumers))
+	errs := make([]error, len(consumers))
+	wg := sync.WaitGroup{}
+	for k, consumer := range consumers {
+		items[k] = make(chan teeItem, teeBuffer)
+		done[k] = make(chan struct{})
+		wg.Add(1)
+		go func(k int, consumer Consumer) {
+			defer wg.Done()
+			defer close(done[k])
+			errs[k] = consumer(&teeIterator{items: items[k]})
+		}(k, consumer)
+	}
+
+	var sourceErr error
+	for {
+		commit, err := it.Next()
+		for k := range consumers {
+			select {
+			case items[k] <- teeItem{commit: commit, err: err}:
+			case <-done[k]:
+			}
+		}
+		var changesetErr *repointerface.ChangesetError
+		if err == repointerface.ErrNoMoreItems {
+			break
+		}
+		if err != nil && !errors.As(err, &changesetErr) {
+			sourceErr = err
+			break
+		}
+	}
+	for k := range consumers {
+		close(items[k])
+	}
+	wg.Wait()
+
+	if sourceErr != nil {
+		return sourceErr
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package combinator
+
+import (
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Итератор, который считает обращения к нему (как к сети)
+type countingIterator struct {
+	repointerface.CommitIterator
+	calls int
+}
+
+func (i *countingIterator) Next() (*repointerface.Commit, error) {
+	i.calls++
+	return i.CommitIterator.Next()
+}
+
+func TestTee(t *testing.T) {
+	source := &countingIterator{CommitIterator: FromSlice(commits(1, 2, 3, 4, 5))}
+	var all []*repointerface.Commit
+	added := 0
+	var first []*repointerface.Commit
+	err := Tee(source,
+		func(it repointerface.CommitIterator) (err error) {
+			all, err = Collect(it)
+			return err
+		},
+		func(it repointerface.CommitIterator) error {
+			for commit, err := it.Next(); err == nil; commit, err = it.Next() {
+				added += commit.AddedRows
+			}
+			return nil
+		},
+		// Потребитель, завершившийся раньше, не останавливает остальных
+		func(it repointerface.CommitIterator) (err error) {
+			first, err = Collect(Take(it, 1))
+			return err
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, source.calls)
+	assert.Equal(t, commits(1, 2, 3, 4, 5), all)
+	assert.Equal(t, 15, added)
+	assert.Equal(t, commits(1), first)
+}
+
+func TestTee_errors(t *testing.T) {
+	// Ошибка ченджсета передается всем потребителям, но чтение продолжается
+	failure := &repointerface.ChangesetError{Id: 2, Err: errors.New("timeout")}
+	var skipped, stopped []*repointerface.Commit
+	err := Tee(&brokenIterator{CommitIterator: FromSlice(commits(1, 2, 3)), broken: 2, err: failure},
+		func(it repointerface.CommitIterator) error {
+			for {
+				commit, err := it.Next()
+				if err == repointerface.ErrNoMoreItems {
+					return nil
+				}
+				if err == nil {
+					skipped = append(skipped, commit)
+				}
+			}
+		},
+		func(it repointerface.CommitIterator) (err error) {
+			stopped, err = Collect(it)
+			return err
+		},
+	)
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, commits(1, 3), skipped)
+	assert.Equal(t, commits(1), stopped)
+
+	// Остальные ошибки прерывают чтение
+	err = Tee(&failingIterator{CommitIterator: FromSlice(commits(1)), err: errors.New("connection reset")},
+		func(it repointerface.CommitIterator) error {
+			_, err := Collect(it)
+			return err
+		},
+	)
+	assert.EqualError(t, err, "connection reset")
+}
@@ -0,0 +1,146 @@
+// Package exclude исключает из обработки коммиты ботов, служебных учетных записей сборки и инструментов
+// миграции, которые делают тысячи автоматических коммитов и искажают метрики. Исключенные коммиты
+// можно собрать в отчет, чтобы они не пропадали незаметно
+package exclude
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Правила исключения коммитов
+type Rules struct {
+	// Идентификаторы учетных записей, почты или имена авторов (без учета регистра)
+	Authors []string `json:"authors"`
+	// Регулярные выражения для имени или почты автора, например (?i)^DOMAIN\\svc_
+	AuthorPatterns []string `json:"author_patterns"`
+	// Регулярные выражения для сообщения коммита, например \*\*\*NO_CI\*\*\*
+	MessagePatterns []string `json:"message_patterns"`
+}
+
+// ReadRules читает правила из файла. Если файла нет, возвращаются пустые правила
+func ReadRules(path string) (*Rules, error) {
+	rules := &Rules{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("файл правил исключения %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+type Matcher interface {
+	// Возвращает правило, по которому исключается коммит, или пустую строку, если коммит не исключается
+	Match(commit *repointerface.Commit) string
+	// Возвращает true, если не задано ни одного правила
+	Empty() bool
+}
+
+type pattern struct {
+	rule string
+	re   *regexp.Regexp
+}
+
+type matcher struct {
+	authors  map[string]string
+	authorRe []pattern
+	messages []pattern
+}
+
+func NewMatcher(rules *Rules) (Matcher, error) {
+	m := &matcher{authors: make(map[string]string)}
+	for _, author := range rules.Authors {
+		m.authors[strings.ToLower(author)] = "author:" + author
+	}
+	var err error
+	if m.authorRe, err = compile("author_pattern", rules.AuthorPatterns); err != nil {
+		return nil, err
+	}
+	if m.messages, err = compile("message_pattern", rules.MessagePatterns); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func compile(kind string, expressions []string) ([]pattern, error) {
+	patterns := make([]pattern, 0, len(expressions))
+	for _, expr := range expressions {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("неверное регулярное выражение %s '%s': %v", kind, expr, err)
+		}
+		patterns = append(patterns, pattern{rule: kind + ":" + expr, re: re})
+	}
+	return patterns, nil
+}
+
+func (m *matcher) Match(commit *repointerface.Commit) string {
+	for _, author := range []string{commit.AuthorId, commit.Email, commit.Author} {
+		if author == "" {
+			continue
+		}
+		if rule, ok := m.authors[strings.ToLower(author)]; ok {
+			return rule
+		}
+	}
+	for _, p := range m.authorRe {
+		if p.re.MatchString(commit.Author) || p.re.MatchString(commit.Email) {
+			return p.rule
+		}
+	}
+	for _, p := range m.messages {
+		if p.re.MatchString(commit.Message) {
+			return p.rule
+		}
+	}
+	return ""
+}
+
+func (m *matcher) Empty() bool {
+	return len(m.authors) == 0 && len(m.authorRe) == 0 && len(m.messages) == 0
+}
+
+type iterator struct {
+	iterator  repointerface.CommitIterator
+	matcher   Matcher
+	onExclude func(commit *repointerface.Commit, rule string)
+}
+
+// Filter возвращает итератор без исключенных коммитов. О каждом исключенном коммите сообщается
+// в onExclude (если она задана), например, чтобы добавить его в отчет
+func Filter(it repointerface.CommitIterator, matcher Matcher, onExclude func(commit *repointerface.Commit, rule string)) repointerface.CommitIterator {
+	if matcher.Empty() {
+		return it
+	}
+	return &iterator{
+		iterator:  it,
+		matcher:   matcher,
+		onExclude: onExclude,
+	}
+}
+
+func (i *iterator) Next() (*repointerface.Commit, error) {
+	for {
+		commit, err := i.iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		rule := i.matcher.Match(commit)
+		if rule == "" {
+			return commit, nil
+		}
+		if i.onExclude != nil {
+			i.onExclude(commit, rule)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package exclude
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var rules = &Rules{
+	Authors:         []string{`DOMAIN\tfsbuild`, "id-bot"},
+	AuthorPatterns:  []string{`(?i)^svc[-_]`},
+	MessagePatterns: []string{`\*\*\*NO_CI\*\*\*`},
+}
+
+func TestMatcher(t *testing.T) {
+	matcher, err := NewMatcher(rules)
+	require.NoError(t, err)
+	assert.False(t, matcher.Empty())
+
+	tests := []struct {
+		name   string
+		commit repointerface.Commit
+		want   string
+	}{
+		{name: "by email", commit: repointerface.Commit{Author: "Build", Email: `domain\TfsBuild`}, want: `author:DOMAIN\tfsbuild`},
+		{name: "by id", commit: repointerface.Commit{Author: "Migration", AuthorId: "ID-BOT"}, want: "author:id-bot"},
+		{name: "by author pattern", commit: repointerface.Commit{Author: "SVC_deploy"}, want: "author_pattern:(?i)^svc[-_]"},
+		{name: "by message", commit: repointerface.Commit{Author: "Ivan", Message: "Update version ***NO_CI***"}, want: `message_pattern:\*\*\*NO_CI\*\*\*`},
+		{name: "person", commit: repointerface.Commit{Author: "Ivan", Email: "ivan@corp.ru", Message: "fix"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matcher.Match(&tt.commit))
+		})
+	}
+
+	_, err = NewMatcher(&Rules{MessagePatterns: []string{"("}})
+	assert.Error(t, err)
+	empty, err := NewMatcher(&Rules{})
+	require.NoError(t, err)
+	assert.True(t, empty.Empty())
+}
+
+func TestFilter(t *testing.T) {
+	matcher, err := NewMatcher(rules)
+	require.NoError(t, err)
+	commits := []*repointerface.Commit{
+		{Id: 1, Author: "Ivan", Email: "ivan@corp.ru", AddedRows: 1},
+		{Id: 2, Author: "Build", Email: `DOMAIN\tfsbuild`, AddedRows: 100},
+		{Id: 3, Author: "Build", Email: `DOMAIN\tfsbuild`, AddedRows: 50, DeletedRows: 5},
+		{Id: 4, Author: "Ivan", Email: "ivan@corp.ru", Message: "***NO_CI***"},
+	}
+	report := NewReport()
+	all, err := combinator.Collect(Filter(combinator.FromSlice(commits), matcher, func(commit *repointerface.Commit, rule string) {
+		report.Add("project", commit, rule)
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, commits[:1], all)
+	assert.Equal(t, 3, report.Commits())
+	assert.Equal(t, []Entry{
+		{Project: "project", Author: "Build", Email: `DOMAIN\tfsbuild`, Rule: `author:DOMAIN\tfsbuild`, Commits: 2, AddedRows: 150, DeletedRows: 5},
+		{Project: "project", Author: "Ivan", Email: "ivan@corp.ru", Rule: `message_pattern:\*\*\*NO_CI\*\*\*`, Commits: 1},
+	}, report.Entries())
+}
+
+func TestReadRules(t *testing.T) {
+	dir := t.TempDir()
+	read, err := ReadRules(filepath.Join(dir, "exclude.json"))
+	require.NoError(t, err)
+	assert.Equal(t, &Rules{}, read)
+
+	path := filepath.Join(dir, "exclude.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"authors": ["DOMAIN\\tfsbuild"], "message_patterns": ["\\*\\*\\*NO_CI\\*\\*\\*"]}`), 0600))
+	read, err = ReadRules(path)
+	require.NoError(t, err)
+	assert.Equal(t, &Rules{Authors: []string{`DOMAIN\tfsbuild`}, MessagePatterns: []string{`\*\*\*NO_CI\*\*\*`}}, read)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"authors": "bot"}`), 0600))
+	_, err = ReadRules(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,74 @@
+package exclude
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"sort"
+	"sync"
+)
+
+// Исключенные коммиты одного автора в проекте по одному правилу
+type Entry struct {
+	Project     string
+	Author      string
+	Email       string
+	Rule        string
+	Commits     int
+	AddedRows   int
+	DeletedRows int
+}
+
+// Report собирает исключенные коммиты. Методы можно вызывать из нескольких горутин
+type Report struct {
+	mu      sync.Mutex
+	entries map[[4]string]*Entry
+}
+
+func NewReport() *Report {
+	return &Report{entries: make(map[[4]string]*Entry)}
+}
+
+func (r *Report) Add(project string, commit *repointerface.Commit, rule string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := [4]string{project, commit.Author, commit.Email, rule}
+	entry, ok := r.entries[key]
+	if !ok {
+		entry = &Entry{Project: project, Author: commit.Author, Email: commit.Email, Rule: rule}
+		r.entries[key] = entry
+	}
+	entry.Commits++
+	entry.AddedRows += commit.AddedRows
+	entry.DeletedRows += commit.DeletedRows
+}
+
+// Entries возвращает исключенные коммиты, упорядоченные по проекту, автору и правилу
+func (r *Report) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := make([]Entry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		res = append(res, *entry)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		if a.Author != b.Author {
+			return a.Author < b.Author
+		}
+		return a.Rule < b.Rule
+	})
+	return res
+}
+
+// Commits возвращает общее число исключенных коммитов
+func (r *Report) Commits() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	commits := 0
+	for _, entry := range r.entries {
+		commits += entry.Commits
+	}
+	return commits
+}
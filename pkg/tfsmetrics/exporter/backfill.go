@@ -0,0 +1,137 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"io"
+	"sort"
+	"strings"
+)
+
+type Backfill interface {
+	// Добавляет в историю коммиты проекта. При ошибке итератора коммиты, полученные до нее, остаются в истории
+	Add(iterator repointerface.CommitIterator, project string) error
+	// Записывает историю счетчиков в формате OpenMetrics (для promtool tsdb create-blocks-from openmetrics)
+	WriteOpenMetrics(w io.Writer) error
+}
+
+type backfillSample struct {
+	timestamp   int64
+	commits     int
+	addedRows   int
+	deletedRows int
+}
+
+type backfillSeries struct {
+	project string
+	author  string
+	email   string
+	team    string
+	key     string
+	samples []backfillSample
+}
+
+type backfill struct {
+	series map[[5]string]*backfillSeries
+}
+
+func NewBackfill() Backfill {
+	return &backfill{
+		series: make(map[[5]string]*backfillSeries),
+	}
+}
+
+// Коммиты разделяются на серии так же, как в PrometheusMetrics: по автору (см. identity.Key) и его команде на дату коммита
+func (b *backfill) Add(iterator repointerface.CommitIterator, project string) error {
+	index, commits, err := indexAuthors(iterator)
+	for _, commit := range commits {
+		author := index.Find(commit)
+		key := [5]string{project, author.Name, author.Email, commit.Team, author.Key}
+		s, ok := b.series[key]
+		if !ok {
+			s = &backfillSeries{project: project, author: author.Name, email: author.Email, team: commit.Team, key: author.Key}
+			b.series[key] = s
+		}
+		s.samples = append(s.samples, backfillSample{
+			timestamp:   commit.Date.Unix(),
+			commits:     1,
+			addedRows:   commit.AddedRows,
+			deletedRows: commit.DeletedRows,
+		})
+	}
+	return err
+}
+
+// cumulative возвращает нарастающие значения счетчиков, по одному на каждую секунду,
+// в которую были коммиты (promtool не принимает повторяющиеся метки времени)
+func (s *backfillSeries) cumulative() []backfillSample {
+	sort.SliceStable(s.samples, func(i, j int) bool {
+		return s.samples[i].timestamp < s.samples[j].timestamp
+	})
+	res := []backfillSample{}
+	total := backfillSample{}
+	for _, sample := range s.samples {
+		total.commits += sample.commits
+		total.addedRows += sample.addedRows
+		total.deletedRows += sample.deletedRows
+		total.timestamp = sample.timestamp
+		if len(res) > 0 && res[len(res)-1].timestamp == sample.timestamp {
+			res[len(res)-1] = total
+		} else {
+			res = append(res, total)
+		}
+	}
+	return res
+}
+
+func (b *backfill) WriteOpenMetrics(w io.Writer) error {
+	keys := make([][5]string, 0, len(b.series))
+	for key := range b.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		for k := range keys[i] {
+			if keys[i][k] != keys[j][k] {
+				return keys[i][k] < keys[j][k]
+			}
+		}
+		return false
+	})
+	samples := make([][]backfillSample, len(keys))
+	for i, key := range keys {
+		samples[i] = b.series[key].cumulative()
+	}
+
+	families := []struct {
+		name  string
+		value func(backfillSample) int
+	}{
+		{CommitsMetric, func(s backfillSample) int { return s.commits }},
+		{AddedRowsMetric, func(s backfillSample) int { return s.addedRows }},
+		{DeletedRowsMetric, func(s backfillSample) int { return s.deletedRows }},
+	}
+
+	buf := bufio.NewWriter(w)
+	for _, family := range families {
+		fmt.Fprintf(buf, "# TYPE %s counter\n", family.name)
+		for i, key := range keys {
+			labels := openMetricsLabels(key)
+			for _, sample := range samples[i] {
+				fmt.Fprintf(buf, "%s_total{%s} %d %d\n", family.name, labels, family.value(sample), sample.timestamp)
+			}
+		}
+	}
+	fmt.Fprintln(buf, "# EOF")
+	return buf.Flush()
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func openMetricsLabels(values [5]string) string {
+	pairs := make([]string, len(metricLabels))
+	for i, name := range metricLabels {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelValueEscaper.Replace(values[i]))
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,70 @@
+package exporter
+
+import (
+	"bytes"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_backfill_WriteOpenMetrics(t *testing.T) {
+	date := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	iter := testItertor{
+		index: 0,
+		commits: []repointerface.Commit{
+			{
+				Author:      "Ivan",
+				Email:       "ivan@email.com",
+				AddedRows:   5,
+				DeletedRows: 1,
+				Date:        date.Add(time.Hour),
+			},
+			{
+				Author:      "Ivan",
+				Email:       "ivan@email.com",
+				AddedRows:   3,
+				DeletedRows: 2,
+				Date:        date,
+			},
+			{
+				Author:      "Ivan",
+				Email:       "ivan@email.com",
+				AddedRows:   1,
+				DeletedRows: 0,
+				Date:        date.Add(time.Hour),
+			},
+			{
+				Author:      `Pety "P"`,
+				Email:       "pety@email.com",
+				AddedRows:   7,
+				DeletedRows: 0,
+				Date:        date,
+			},
+		},
+	}
+
+	bf := NewBackfill()
+	assert.NoError(t, bf.Add(&iter, "project"))
+	buf := bytes.Buffer{}
+	err := bf.WriteOpenMetrics(&buf)
+	assert.NoError(t, err)
+
+	ivan := `project="project",author="Ivan",email="ivan@email.com",team="",author_id="email:ivan@email.com"`
+	pety := `project="project",author="Pety \"P\"",email="pety@email.com",team="",author_id="email:pety@email.com"`
+	assert.Equal(t, `# TYPE commits counter
+commits_total{`+ivan+`} 1 1609502400
+commits_total{`+ivan+`} 3 1609506000
+commits_total{`+pety+`} 1 1609502400
+# TYPE added_rows counter
+added_rows_total{`+ivan+`} 3 1609502400
+added_rows_total{`+ivan+`} 9 1609506000
+added_rows_total{`+pety+`} 7 1609502400
+# TYPE deleted_rows counter
+deleted_rows_total{`+ivan+`} 2 1609502400
+deleted_rows_total{`+ivan+`} 3 1609506000
+deleted_rows_total{`+pety+`} 0 1609502400
+# EOF
+`, buf.String())
+}
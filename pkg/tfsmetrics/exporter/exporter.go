@@ -1,113 +1,179 @@
 package exporter
 
 import (
+	"go-marathon-team-3/pkg/tfsmetrics/aggregate"
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/exclude"
+	"go-marathon-team-3/pkg/tfsmetrics/identity"
 	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Если итератор завершается ошибкой (не ErrNoMoreItems), методы возвращают ее вместе
+// с данными по уже полученным коммитам: такие данные неполные
 type Exporter interface {
 	// Возвращяет данные по проекту
-	GetDataByProject(iterator repointerface.CommitIterator) map[string]*ByProject
-	// Возвращает данные по автору
-	GetDataByAuthor(iterator repointerface.CommitIterator, author string, project string) map[string]*ByAuthor
+	GetDataByProject(iterator repointerface.CommitIterator) (map[string]*ByProject, error)
+	// Возвращяет данные по проекту в разрезе команд (коммиты авторов вне состава команд - под пустым ключом)
+	GetDataByTeam(iterator repointerface.CommitIterator) (map[string]*ByTeam, error)
+	// Возвращает данные по коммитам автора (nil, если коммитов автора нет)
+	GetDataByAuthor(iterator repointerface.CommitIterator, author string) (*ByAuthor, error)
 	// Принимает итератор и создает по нему метрики Prometheus
-	PrometheusMetrics(iterator repointerface.CommitIterator, project string)
+	PrometheusMetrics(iterator repointerface.CommitIterator, project string) error
+	// Публикует коммиты, исключенные правилами исключения, отдельной метрикой
+	ExcludedMetrics(entries []exclude.Entry)
 }
 
+// Имена метрик, под которыми экспортер публикует данные
+const (
+	CommitsMetric     = "commits"
+	AddedRowsMetric   = "added_rows"
+	DeletedRowsMetric = "deleted_rows"
+	// Коммиты ботов и служебных учетных записей, не вошедшие в остальные метрики
+	ExcludedCommitsMetric = "excluded_commits"
+)
+
+// Метки, которыми размечается каждая метрика. Имя и почта автора определяются по коммитам проекта и могут
+// различаться между проектами и меняться при переименовании учетной записи, поэтому суммировать метрики
+// по автору нужно по метке author_id (см. identity.Key)
+var metricLabels = []string{"project", "author", "email", "team", "author_id"}
+
 type metrics struct {
-	commits     prometheus.CounterVec
-	addedRows   prometheus.CounterVec
-	deletedRows prometheus.CounterVec
+	commits         prometheus.CounterVec
+	addedRows       prometheus.CounterVec
+	deletedRows     prometheus.CounterVec
+	excludedCommits prometheus.CounterVec
 }
 
-func newMetrics() *metrics {
+func newMetrics(registerer prometheus.Registerer) *metrics {
 	m := &metrics{
 		commits: *prometheus.NewCounterVec(prometheus.CounterOpts{
-			Name: "commits",
-		}, []string{"project", "author", "email"}),
+			Name: CommitsMetric,
+		}, metricLabels),
 		addedRows: *prometheus.NewCounterVec(prometheus.CounterOpts{
-			Name: "added_rows",
-		}, []string{"project", "author", "email"}),
+			Name: AddedRowsMetric,
+		}, metricLabels),
 		deletedRows: *prometheus.NewCounterVec(prometheus.CounterOpts{
-			Name: "deleted_rows",
-		}, []string{"project", "author", "email"}),
+			Name: DeletedRowsMetric,
+		}, metricLabels),
+		excludedCommits: *prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: ExcludedCommitsMetric,
+		}, []string{"project", "author", "email", "rule"}),
 	}
-	prometheus.MustRegister(m.commits, m.addedRows, m.deletedRows)
+	registerer.MustRegister(m.commits, m.addedRows, m.deletedRows, m.excludedCommits)
 	return m
 }
 
 type exporter struct {
-	metrics      *metrics
-	dataByAuthor map[string]*ByAuthor
+	metrics *metrics
 }
 
 func NewExporter() Exporter {
 	return &exporter{
-		metrics:      newMetrics(),
-		dataByAuthor: make(map[string]*ByAuthor),
+		metrics: newMetrics(prometheus.DefaultRegisterer),
+	}
+}
+
+// Коммиты одного автора (см. identity.Key) попадают в одну серию с последними в проекте именем и почтой автора,
+// даже если его учетная запись была переименована. Коммиты автора, сменившего команду, разделяются по командам
+func (e *exporter) PrometheusMetrics(iterator repointerface.CommitIterator, project string) error {
+	res, err := aggregate.Aggregate(aggregate.Spec{By: []aggregate.Dimension{aggregate.Author, aggregate.Team}}, project, iterator)
+	for _, group := range res.Groups {
+		labels := prometheus.Labels{"project": project, "author": group.Identity.Name, "email": group.Identity.Email,
+			"team": res.Key(group, aggregate.Team), "author_id": group.Identity.Key}
+		e.metrics.commits.With(labels).Add(float64(group.Commits))
+		e.metrics.addedRows.With(labels).Add(float64(group.AddedRows.Sum))
+		e.metrics.deletedRows.With(labels).Add(float64(group.DeletedRows.Sum))
 	}
+	return err
 }
 
-func (e *exporter) PrometheusMetrics(iterator repointerface.CommitIterator, project string) {
-	for commit, err := iterator.Next(); err == nil; commit, err = iterator.Next() {
-		e.metrics.commits.With(prometheus.Labels{"project": project,
-			"author": commit.Author, "email": commit.Email}).Inc()
-		e.metrics.addedRows.With(prometheus.Labels{"project": project,
-			"author": commit.Author, "email": commit.Email}).Add(float64(commit.AddedRows))
-		e.metrics.deletedRows.With(prometheus.Labels{"project": project,
-			"author": commit.Author, "email": commit.Email}).Add(float64(commit.DeletedRows))
+func (e *exporter) ExcludedMetrics(entries []exclude.Entry) {
+	for _, entry := range entries {
+		e.metrics.excludedCommits.With(prometheus.Labels{"project": entry.Project,
+			"author": entry.Author, "email": entry.Email, "rule": entry.Rule}).Add(float64(entry.Commits))
+	}
+}
+
+// indexAuthors вычитывает итератор и группирует коммиты по авторам
+func indexAuthors(iterator repointerface.CommitIterator) (*identity.Index, []*repointerface.Commit, error) {
+	index := identity.NewIndex()
+	commits := []*repointerface.Commit{}
+	commit, err := iterator.Next()
+	for ; err == nil; commit, err = iterator.Next() {
+		index.Add(commit)
+		commits = append(commits, commit)
+	}
+	return index, commits, iterationError(err)
+}
 
+// iterationError отличает конец данных от ошибки получения коммитов
+func iterationError(err error) error {
+	if err == repointerface.ErrNoMoreItems {
+		return nil
 	}
+	return err
 }
 
-type ByAuthor struct {
+// Итоги по группе коммитов
+type Totals struct {
 	Commits     int
 	AddedRows   int
 	DeletedRows int
 }
 
-type ByProject struct {
+func totals(group *aggregate.Group) *Totals {
+	return &Totals{
+		Commits:     group.Commits,
+		AddedRows:   group.AddedRows.Sum,
+		DeletedRows: group.DeletedRows.Sum,
+	}
+}
+
+type ByAuthor = Totals
+
+type ByProject = Totals
+
+type ByTeam struct {
 	Commits     int
 	AddedRows   int
 	DeletedRows int
+	// Число авторов, делавших коммиты в составе команды
+	Authors int
 }
 
-func (e *exporter) GetDataByProject(iterator repointerface.CommitIterator) map[string]*ByProject {
-	res := make(map[string]*ByProject)
-	for commit, err := iterator.Next(); err == nil; commit, err = iterator.Next() {
-		if author, ok := res[commit.Author]; ok {
-			author.Commits += 1
-			author.AddedRows += commit.AddedRows
-			author.DeletedRows += commit.DeletedRows
-		} else {
-			res[commit.Author] = &ByProject{
-				Commits:     1,
-				AddedRows:   commit.AddedRows,
-				DeletedRows: commit.DeletedRows,
-			}
-		}
+// Ключ результата - последнее имя автора, а для разных людей с одинаковым именем - имя с почтой
+func (e *exporter) GetDataByProject(iterator repointerface.CommitIterator) (map[string]*ByProject, error) {
+	res, err := aggregate.Aggregate(aggregate.Spec{By: []aggregate.Dimension{aggregate.Author}}, "", iterator)
+	data := make(map[string]*ByProject)
+	for _, group := range res.Groups {
+		data[group.Keys[0]] = totals(group)
+	}
+	return data, err
+}
+
+func (e *exporter) GetDataByAuthor(iterator repointerface.CommitIterator, author string) (*ByAuthor, error) {
+	iterator = combinator.Filter(iterator, func(commit *repointerface.Commit) bool {
+		return commit.Author == author
+	})
+	res, err := aggregate.Aggregate(aggregate.Spec{}, "", iterator)
+	if len(res.Groups) == 0 {
+		return nil, err
 	}
-	return res
+	return totals(res.Groups[0]), err
 }
 
-func (e *exporter) GetDataByAuthor(iterator repointerface.CommitIterator, author string, project string) map[string]*ByAuthor {
-	for commit, err := iterator.Next(); err == nil; commit, err = iterator.Next() {
-		if auth, ok := e.dataByAuthor[project]; ok {
-			if commit.Author == author {
-				auth.Commits += 1
-				auth.AddedRows += commit.AddedRows
-				auth.DeletedRows += commit.DeletedRows
-			}
-		} else {
-			if commit.Author == author {
-				e.dataByAuthor[project] = &ByAuthor{
-					Commits:     1,
-					AddedRows:   commit.AddedRows,
-					DeletedRows: commit.DeletedRows,
-				}
-			}
+func (e *exporter) GetDataByTeam(iterator repointerface.CommitIterator) (map[string]*ByTeam, error) {
+	res, err := aggregate.Aggregate(aggregate.Spec{By: []aggregate.Dimension{aggregate.Team}}, "", iterator)
+	data := make(map[string]*ByTeam)
+	for _, group := range res.Groups {
+		data[group.Keys[0]] = &ByTeam{
+			Commits:     group.Commits,
+			AddedRows:   group.AddedRows.Sum,
+			DeletedRows: group.DeletedRows.Sum,
+			Authors:     group.Authors,
 		}
 	}
-	return e.dataByAuthor
+	return data, err
 }
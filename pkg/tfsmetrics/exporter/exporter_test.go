@@ -1,6 +1,8 @@
 package exporter
 
 import (
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/exclude"
 	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
 	"testing"
 	"time"
@@ -13,6 +15,8 @@ import (
 type testItertor struct {
 	index   int
 	commits []repointerface.Commit
+	// Ошибка, которой завершается итератор вместо ErrNoMoreItems
+	err error
 }
 
 func (ti *testItertor) Next() (*repointerface.Commit, error) {
@@ -20,6 +24,9 @@ func (ti *testItertor) Next() (*repointerface.Commit, error) {
 		ti.index++
 		return &ti.commits[ti.index-1], nil
 	}
+	if ti.err != nil {
+		return nil, ti.err
+	}
 	return nil, repointerface.ErrNoMoreItems
 }
 
@@ -46,15 +53,15 @@ func Test_exporter_PrometheusMetrics(t *testing.T) {
 	}
 
 	exporter := exporter{
-		metrics: newMetrics(),
+		metrics: newMetrics(prometheus.DefaultRegisterer),
 	}
-	exporter.PrometheusMetrics(&iter1, project1)
+	assert.NoError(t, exporter.PrometheusMetrics(&iter1, project1))
 	assert.Equal(t, float64(2), testutil.ToFloat64(exporter.metrics.commits.With(prometheus.Labels{"project": project1,
-		"author": "Ivan", "email": "ivan@email.com"})))
+		"author": "Ivan", "email": "ivan@email.com", "team": "", "author_id": "email:ivan@email.com"})))
 	assert.Equal(t, float64(10), testutil.ToFloat64(exporter.metrics.addedRows.With(prometheus.Labels{"project": project1,
-		"author": "Ivan", "email": "ivan@email.com"})))
+		"author": "Ivan", "email": "ivan@email.com", "team": "", "author_id": "email:ivan@email.com"})))
 	assert.Equal(t, float64(20), testutil.ToFloat64(exporter.metrics.deletedRows.With(prometheus.Labels{"project": project1,
-		"author": "Ivan", "email": "ivan@email.com"})))
+		"author": "Ivan", "email": "ivan@email.com", "team": "", "author_id": "email:ivan@email.com"})))
 
 	project2 := "project2"
 	iter2 := testItertor{
@@ -84,21 +91,21 @@ func Test_exporter_PrometheusMetrics(t *testing.T) {
 		},
 	}
 
-	exporter.PrometheusMetrics(&iter2, project2)
+	assert.NoError(t, exporter.PrometheusMetrics(&iter2, project2))
 	assert.Equal(t, float64(1), testutil.ToFloat64(exporter.metrics.commits.With(prometheus.Labels{"project": project2,
-		"author": "Ivan", "email": "ivan@email.com"})))
+		"author": "Ivan", "email": "ivan@email.com", "team": "", "author_id": "email:ivan@email.com"})))
 	assert.Equal(t, float64(2), testutil.ToFloat64(exporter.metrics.commits.With(prometheus.Labels{"project": project2,
-		"author": "Pety", "email": "pety@email.com"})))
+		"author": "Pety", "email": "pety@email.com", "team": "", "author_id": "email:pety@email.com"})))
 
 	assert.Equal(t, float64(5), testutil.ToFloat64(exporter.metrics.addedRows.With(prometheus.Labels{"project": project2,
-		"author": "Ivan", "email": "ivan@email.com"})))
+		"author": "Ivan", "email": "ivan@email.com", "team": "", "author_id": "email:ivan@email.com"})))
 	assert.Equal(t, float64(10), testutil.ToFloat64(exporter.metrics.addedRows.With(prometheus.Labels{"project": project2,
-		"author": "Pety", "email": "pety@email.com"})))
+		"author": "Pety", "email": "pety@email.com", "team": "", "author_id": "email:pety@email.com"})))
 
 	assert.Equal(t, float64(10), testutil.ToFloat64(exporter.metrics.deletedRows.With(prometheus.Labels{"project": project2,
-		"author": "Ivan", "email": "ivan@email.com"})))
+		"author": "Ivan", "email": "ivan@email.com", "team": "", "author_id": "email:ivan@email.com"})))
 	assert.Equal(t, float64(20), testutil.ToFloat64(exporter.metrics.deletedRows.With(prometheus.Labels{"project": project2,
-		"author": "Pety", "email": "pety@email.com"})))
+		"author": "Pety", "email": "pety@email.com", "team": "", "author_id": "email:pety@email.com"})))
 }
 
 func Test_exporter_GetDataByProject(t *testing.T) {
@@ -129,7 +136,8 @@ func Test_exporter_GetDataByProject(t *testing.T) {
 		},
 	}
 	exporter := exporter{}
-	data := exporter.GetDataByProject(&iter1)
+	data, err := exporter.GetDataByProject(&iter1)
+	assert.NoError(t, err)
 	assert.Equal(t, &ByProject{
 		Commits:     2,
 		AddedRows:   10,
@@ -144,7 +152,6 @@ func Test_exporter_GetDataByProject(t *testing.T) {
 }
 
 func Test_exporter_GetDataByAuthor(t *testing.T) {
-	project1 := "project1"
 	iter1 := testItertor{
 		index: 0,
 		commits: []repointerface.Commit{
@@ -171,17 +178,16 @@ func Test_exporter_GetDataByAuthor(t *testing.T) {
 			},
 		},
 	}
-	exporter := exporter{
-		dataByAuthor: make(map[string]*ByAuthor),
-	}
-	data := exporter.GetDataByAuthor(&iter1, "Ivan", project1)
+	exporter := exporter{}
+	data, err := exporter.GetDataByAuthor(&iter1, "Ivan")
+	assert.NoError(t, err)
 	assert.Equal(t, &ByAuthor{
 		Commits:     2,
 		AddedRows:   10,
 		DeletedRows: 20,
-	}, data[project1])
+	}, data)
 
-	project2 := "project2"
+	// Данные по разным проектам не накапливаются между вызовами
 	iter2 := testItertor{
 		index: 0,
 		commits: []repointerface.Commit{
@@ -201,10 +207,114 @@ func Test_exporter_GetDataByAuthor(t *testing.T) {
 			},
 		},
 	}
-	exporter.GetDataByAuthor(&iter2, "Ivan", project2)
+	data, err = exporter.GetDataByAuthor(&iter2, "Ivan")
+	assert.NoError(t, err)
 	assert.Equal(t, &ByAuthor{
 		Commits:     1,
 		AddedRows:   5,
 		DeletedRows: 10,
-	}, data[project2])
+	}, data)
+
+	data, err = exporter.GetDataByAuthor(&testItertor{commits: iter2.commits}, "Sergey")
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+}
+
+func Test_exporter_partialData(t *testing.T) {
+	commits := []repointerface.Commit{
+		{
+			Author:      "Ivan",
+			Email:       "ivan@email.com",
+			AddedRows:   5,
+			DeletedRows: 10,
+			Date:        time.Now(),
+		},
+	}
+	failure := &repointerface.ChangesetError{Id: 2, Err: errors.New("timeout")}
+	exporter := exporter{
+		metrics: newMetrics(prometheus.NewRegistry()),
+	}
+
+	// Ошибка итератора возвращается вместе с данными по полученным до нее коммитам
+	byProject, err := exporter.GetDataByProject(&testItertor{commits: commits, err: failure})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 1, byProject["Ivan"].Commits)
+
+	byAuthor, err := exporter.GetDataByAuthor(&testItertor{commits: commits, err: failure}, "Ivan")
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 1, byAuthor.Commits)
+
+	err = exporter.PrometheusMetrics(&testItertor{commits: commits, err: failure}, "project")
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, float64(1), testutil.ToFloat64(exporter.metrics.commits.With(prometheus.Labels{"project": "project",
+		"author": "Ivan", "email": "ivan@email.com", "team": "", "author_id": "email:ivan@email.com"})))
+}
+
+func Test_exporter_identities(t *testing.T) {
+	date := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	commits := []repointerface.Commit{
+		// Учетная запись переименована: коммиты одного автора
+		{Author: "Ivanov I.", AuthorId: "id-1", Email: "DOMAIN\\iivanov", AddedRows: 1, Date: date},
+		{Author: "Ivan Ivanov", AuthorId: "id-1", Email: "DOMAIN\\iivanov", AddedRows: 2, Date: date.Add(time.Hour)},
+		// Другой человек с тем же именем
+		{Author: "Ivan Ivanov", AuthorId: "id-2", Email: "ivanov@corp.ru", AddedRows: 4, Date: date},
+	}
+	exporter := exporter{
+		metrics: newMetrics(prometheus.NewRegistry()),
+	}
+
+	byProject, err := exporter.GetDataByProject(&testItertor{commits: commits})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*ByProject{
+		"Ivan Ivanov <DOMAIN\\iivanov>": {Commits: 2, AddedRows: 3},
+		"Ivan Ivanov <ivanov@corp.ru>":  {Commits: 1, AddedRows: 4},
+	}, byProject)
+
+	assert.NoError(t, exporter.PrometheusMetrics(&testItertor{commits: commits}, "project"))
+	assert.Equal(t, 2, testutil.CollectAndCount(exporter.metrics.commits))
+	assert.Equal(t, float64(3), testutil.ToFloat64(exporter.metrics.addedRows.With(prometheus.Labels{"project": "project",
+		"author": "Ivan Ivanov", "email": "DOMAIN\\iivanov", "team": "", "author_id": "id-1"})))
+
+	// В другом проекте у автора другое имя, но тот же author_id
+	assert.NoError(t, exporter.PrometheusMetrics(&testItertor{commits: commits[:1]}, "other"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(exporter.metrics.addedRows.With(prometheus.Labels{"project": "other",
+		"author": "Ivanov I.", "email": "DOMAIN\\iivanov", "team": "", "author_id": "id-1"})))
+}
+
+func Test_exporter_teams(t *testing.T) {
+	date := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	commits := []repointerface.Commit{
+		// Автор перешел из одной команды в другую
+		{Author: "Ivan", AuthorId: "id-1", Email: "ivan@corp.ru", AddedRows: 1, Date: date, Team: "Backend"},
+		{Author: "Ivan", AuthorId: "id-1", Email: "ivan@corp.ru", AddedRows: 2, Date: date.Add(time.Hour), Team: "Frontend"},
+		{Author: "Petr", AuthorId: "id-2", Email: "petr@corp.ru", AddedRows: 4, Date: date, Team: "Backend"},
+		{Author: "Guest", AuthorId: "id-3", Email: "guest@corp.ru", AddedRows: 8, Date: date},
+	}
+	exporter := exporter{
+		metrics: newMetrics(prometheus.NewRegistry()),
+	}
+
+	byTeam, err := exporter.GetDataByTeam(&testItertor{commits: commits})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]*ByTeam{
+		"Backend":  {Commits: 2, AddedRows: 5, Authors: 2},
+		"Frontend": {Commits: 1, AddedRows: 2, Authors: 1},
+		"":         {Commits: 1, AddedRows: 8, Authors: 1},
+	}, byTeam)
+
+	assert.NoError(t, exporter.PrometheusMetrics(&testItertor{commits: commits}, "project"))
+	assert.Equal(t, 4, testutil.CollectAndCount(exporter.metrics.commits))
+	assert.Equal(t, float64(2), testutil.ToFloat64(exporter.metrics.addedRows.With(prometheus.Labels{"project": "project",
+		"author": "Ivan", "email": "ivan@corp.ru", "team": "Frontend", "author_id": "id-1"})))
+}
+
+func Test_exporter_ExcludedMetrics(t *testing.T) {
+	exporter := exporter{
+		metrics: newMetrics(prometheus.NewRegistry()),
+	}
+	exporter.ExcludedMetrics([]exclude.Entry{
+		{Project: "project", Author: "Build", Email: "build@corp.ru", Rule: "author:build@corp.ru", Commits: 20},
+	})
+	assert.Equal(t, float64(20), testutil.ToFloat64(exporter.metrics.excludedCommits.With(prometheus.Labels{"project": "project",
+		"author": "Build", "email": "build@corp.ru", "rule": "author:build@corp.ru"})))
 }
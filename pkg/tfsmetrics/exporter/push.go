@@ -0,0 +1,113 @@
+package exporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type PrometheusPusher interface {
+	// Отправляет метрики в Pushgateway, для каждого проекта своей группой (grouping key project)
+	Push() error
+}
+
+type pusher struct {
+	url        string
+	job        string
+	retries    int
+	retryDelay time.Duration
+	gatherer   prometheus.Gatherer
+}
+
+// retries - количество повторных попыток отправки при ошибке
+func NewPrometheusPusher(url, job string, retries int, retryDelay time.Duration) PrometheusPusher {
+	return &pusher{
+		url:        url,
+		job:        job,
+		retries:    retries,
+		retryDelay: retryDelay,
+		gatherer:   prometheus.DefaultGatherer,
+	}
+}
+
+func (p *pusher) Push() error {
+	mfs, err := p.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	byProject := splitByProject(mfs)
+	projects := make([]string, 0, len(byProject))
+	for project := range byProject {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+
+	failed := []string{}
+	for _, project := range projects {
+		if err := p.pushProject(project, byProject[project]); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", project, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("не удалось отправить метрики проектов:\n%s", strings.Join(failed, "\n"))
+	}
+	return nil
+}
+
+func (p *pusher) pushProject(project string, mfs []*dto.MetricFamily) error {
+	gatherer := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		return mfs, nil
+	})
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = push.New(p.url, p.job).Gatherer(gatherer).Grouping("project", project).Push()
+		if err == nil || attempt >= p.retries {
+			return err
+		}
+		time.Sleep(p.retryDelay)
+	}
+}
+
+// splitByProject раскладывает метрики по значению метки project, убирая саму метку:
+// Pushgateway не принимает метрики, метки которых совпадают с grouping key
+func splitByProject(mfs []*dto.MetricFamily) map[string][]*dto.MetricFamily {
+	res := make(map[string][]*dto.MetricFamily)
+	for _, mf := range mfs {
+		families := make(map[string]*dto.MetricFamily)
+		for _, m := range mf.GetMetric() {
+			project := ""
+			labels := make([]*dto.LabelPair, 0, len(m.GetLabel()))
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "project" {
+					project = l.GetValue()
+					continue
+				}
+				labels = append(labels, l)
+			}
+			if project == "" {
+				continue
+			}
+			family, ok := families[project]
+			if !ok {
+				family = &dto.MetricFamily{Name: mf.Name, Help: mf.Help, Type: mf.Type}
+				families[project] = family
+				res[project] = append(res[project], family)
+			}
+			family.Metric = append(family.Metric, &dto.Metric{
+				Label:       labels,
+				Counter:     m.Counter,
+				Gauge:       m.Gauge,
+				Untyped:     m.Untyped,
+				Summary:     m.Summary,
+				Histogram:   m.Histogram,
+				TimestampMs: m.TimestampMs,
+			})
+		}
+	}
+	return res
+}
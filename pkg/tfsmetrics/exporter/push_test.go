@@ -0,0 +1,79 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_pusher_Push(t *testing.T) {
+	mu := sync.Mutex{}
+	received := make(map[string][]*dto.MetricFamily)
+	failures := 1
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		// первая попытка завершается ошибкой, чтобы проверить повтор
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.Equal(t, http.MethodPut, r.Method)
+		decoder := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+		for {
+			mf := &dto.MetricFamily{}
+			if err := decoder.Decode(mf); err != nil {
+				break
+			}
+			received[r.URL.Path] = append(received[r.URL.Path], mf)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	registry := prometheus.NewRegistry()
+	commits := prometheus.NewCounterVec(prometheus.CounterOpts{Name: CommitsMetric}, metricLabels)
+	registry.MustRegister(commits)
+	commits.With(prometheus.Labels{"project": "project1", "author": "Ivan", "email": "ivan@email.com", "team": "", "author_id": "email:ivan@email.com"}).Add(2)
+	commits.With(prometheus.Labels{"project": "project2", "author": "Pety", "email": "pety@email.com", "team": "", "author_id": "email:pety@email.com"}).Add(3)
+
+	p := &pusher{
+		url:        receiver.URL,
+		job:        "cli-metrics",
+		retries:    1,
+		retryDelay: time.Millisecond,
+		gatherer:   registry,
+	}
+	require.NoError(t, p.Push())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, received, 2)
+	project1 := received["/metrics/job/cli-metrics/project/project1"]
+	require.Len(t, project1, 1)
+	assert.Equal(t, CommitsMetric, project1[0].GetName())
+	require.Len(t, project1[0].GetMetric(), 1)
+	metric := project1[0].GetMetric()[0]
+	assert.Equal(t, float64(2), metric.GetCounter().GetValue())
+	for _, l := range metric.GetLabel() {
+		assert.NotEqual(t, "project", l.GetName())
+	}
+	project2 := received["/metrics/job/cli-metrics/project/project2"]
+	require.Len(t, project2, 1)
+	assert.Equal(t, float64(3), project2[0].GetMetric()[0].GetCounter().GetValue())
+
+	// закончились попытки
+	failures = 10
+	mu.Unlock()
+	assert.Error(t, p.Push())
+	mu.Lock()
+}
@@ -2,8 +2,16 @@ package exporter
 
 import (
 	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,46 +20,195 @@ import (
 )
 
 type PrometheusServer interface {
-	// Запускает сервер на переданном порте. Порт передается в виде ":8080"
-	Start(addr string)
+	// Запускает сервер на переданном адресе. Адрес передается в виде ":8080" или "127.0.0.1:8080".
+	// Ошибки настройки TLS и занятый порт возвращаются сразу
+	Start(addr string) error
 	// Останавливает сервер с заданным таймаутом. После проверки ошибки надо дождатся WaitGroup.Wait()
 	Stop() error
+	// Возвращает канал, в который передается ошибка, если сервер после Start завершился сам, а не через Stop
+	Errors() <-chan error
+	// Включает эндпоинт /probe?project=X, отдающий метрики одного проекта, посчитанные в момент запроса.
+	// Вызывать до Start
+	EnableProbe(iterators ProjectIterators)
+}
+
+// Возвращает итератор по коммитам проекта для /probe
+type ProjectIterators func(project string) (repointerface.CommitIterator, error)
+
+// Настройки защиты эндпоинта /metrics. Пустые поля отключают соответствующую защиту
+type ServerConfig struct {
+	CertFile     string // сертификат сервера, вместе с KeyFile включает TLS
+	KeyFile      string
+	ClientCAFile string // сертификаты CA, которыми должен быть подписан сертификат клиента (mutual TLS)
+	Username     string // basic auth
+	Password     string
+	BearerToken  string
 }
 
 type server struct {
 	exiteDoneWG      *sync.WaitGroup
 	context          context.Context
 	timeout          time.Duration
+	config           ServerConfig
+	probe            ProjectIterators
+	listener         net.Listener
 	prometheusServer *http.Server
+	errors           chan error
 }
 
-func NewPrometheusServer(exiteDoneWaitGroup *sync.WaitGroup, timeout time.Duration) PrometheusServer {
+func NewPrometheusServer(exiteDoneWaitGroup *sync.WaitGroup, timeout time.Duration, config ServerConfig) PrometheusServer {
 	return &server{
 		exiteDoneWG: exiteDoneWaitGroup,
 		context:     context.Background(),
 		timeout:     timeout,
+		config:      config,
+		errors:      make(chan error, 1),
 	}
 }
 
-func (s *server) Start(addr string) {
-	server := &http.Server{Addr: addr}
-	http.Handle("/metrics", promhttp.HandlerFor(prometheus.DefaultGatherer,
-		promhttp.HandlerOpts{EnableOpenMetrics: true}))
+func (s *server) Start(addr string) error {
+	tlsConfig, err := s.config.tlsConfig()
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.config.authHandler(promhttp.HandlerFor(prometheus.DefaultGatherer,
+		promhttp.HandlerOpts{EnableOpenMetrics: true})))
+	if s.probe != nil {
+		mux.Handle("/probe", s.config.authHandler(http.HandlerFunc(s.handleProbe)))
+	}
+	server := &http.Server{Addr: addr, Handler: mux, TLSConfig: tlsConfig}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
 
 	s.exiteDoneWG.Add(1)
 
 	go func() {
 		defer s.exiteDoneWG.Done()
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			log.Fatalf("ListenAndServe(): %v", err)
+		var err error
+		if s.config.TLSEnabled() {
+			// сертификат уже загружен в TLSConfig
+			err = server.ServeTLS(listener, "", "")
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != http.ErrServerClosed {
+			log.Printf("Serve(): %v", err)
+			s.errors <- err
 		}
 	}()
 
+	s.listener = listener
 	s.prometheusServer = server
+	return nil
 }
 
 func (s *server) Stop() error {
+	if s.prometheusServer == nil {
+		return nil
+	}
 	cxt, cancel := context.WithTimeout(s.context, s.timeout)
 	defer cancel()
 	return s.prometheusServer.Shutdown(cxt)
 }
+
+func (s *server) Errors() <-chan error {
+	return s.errors
+}
+
+func (s *server) EnableProbe(iterators ProjectIterators) {
+	s.probe = iterators
+}
+
+func (s *server) handleProbe(w http.ResponseWriter, r *http.Request) {
+	project := r.URL.Query().Get("project")
+	if project == "" {
+		http.Error(w, "параметр project не задан", http.StatusBadRequest)
+		return
+	}
+	iter, err := s.probe(project)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// метрики проекта собираются в отдельный реестр, чтобы не смешиваться с /metrics
+	registry := prometheus.NewRegistry()
+	exp := &exporter{metrics: newMetrics(registry)}
+	if err = exp.PrometheusMetrics(iter, project); err != nil {
+		// Неполные счетчики выглядели бы для Prometheus как сброс, поэтому данные не отдаются
+		http.Error(w, "данные проекта получены не полностью: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true}).ServeHTTP(w, r)
+}
+
+func (c *ServerConfig) TLSEnabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+func (c *ServerConfig) tlsConfig() (*tls.Config, error) {
+	if !c.TLSEnabled() {
+		if c.ClientCAFile != "" {
+			return nil, errors.New("для проверки клиентских сертификатов нужно задать сертификат и ключ сервера")
+		}
+		return nil, nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("для TLS нужно задать и сертификат, и ключ сервера")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	if c.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("в файле " + c.ClientCAFile + " нет сертификатов")
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// authHandler пропускает запрос, если он прошел basic auth или содержит bearer-токен.
+// Если ни то, ни другое не настроено, запросы пропускаются без проверки
+func (c *ServerConfig) authHandler(next http.Handler) http.Handler {
+	if c.Username == "" && c.BearerToken == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.Username != "" {
+			user, password, ok := r.BasicAuth()
+			if ok && secureEqual(user, c.Username) && secureEqual(password, c.Password) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if c.BearerToken != "" {
+			auth := r.Header.Get("Authorization")
+			if strings.HasPrefix(auth, "Bearer ") && secureEqual(strings.TrimPrefix(auth, "Bearer "), c.BearerToken) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if c.Username != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+		}
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
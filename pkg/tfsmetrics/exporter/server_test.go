@@ -0,0 +1,257 @@
+package exporter
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerConfig_authHandler(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	tests := []struct {
+		name    string
+		config  ServerConfig
+		prepare func(r *http.Request)
+		want    int
+	}{
+		{
+			name:    "no auth",
+			config:  ServerConfig{},
+			prepare: func(r *http.Request) {},
+			want:    http.StatusOK,
+		},
+		{
+			name:    "basic ok",
+			config:  ServerConfig{Username: "user", Password: "secret"},
+			prepare: func(r *http.Request) { r.SetBasicAuth("user", "secret") },
+			want:    http.StatusOK,
+		},
+		{
+			name:    "basic wrong password",
+			config:  ServerConfig{Username: "user", Password: "secret"},
+			prepare: func(r *http.Request) { r.SetBasicAuth("user", "wrong") },
+			want:    http.StatusUnauthorized,
+		},
+		{
+			name:    "bearer ok",
+			config:  ServerConfig{BearerToken: "token"},
+			prepare: func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") },
+			want:    http.StatusOK,
+		},
+		{
+			name:    "bearer missing",
+			config:  ServerConfig{BearerToken: "token"},
+			prepare: func(r *http.Request) {},
+			want:    http.StatusUnauthorized,
+		},
+		{
+			name:    "bearer when both configured",
+			config:  ServerConfig{Username: "user", Password: "secret", BearerToken: "token"},
+			prepare: func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") },
+			want:    http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			tt.prepare(r)
+			w := httptest.NewRecorder()
+			tt.config.authHandler(ok).ServeHTTP(w, r)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestServerConfig_tlsConfig(t *testing.T) {
+	config := ServerConfig{}
+	tlsConfig, err := config.tlsConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	config = ServerConfig{ClientCAFile: "ca.pem"}
+	_, err = config.tlsConfig()
+	assert.Error(t, err)
+
+	certFile, keyFile := testCertificate(t)
+	config = ServerConfig{CertFile: certFile}
+	_, err = config.tlsConfig()
+	assert.Error(t, err)
+
+	config = ServerConfig{CertFile: certFile, KeyFile: "unknown.pem"}
+	_, err = config.tlsConfig()
+	assert.Error(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0600))
+	config = ServerConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile}
+	_, err = config.tlsConfig()
+	assert.Error(t, err)
+
+	config = ServerConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: certFile}
+	tlsConfig, err = config.tlsConfig()
+	assert.NoError(t, err)
+	assert.Len(t, tlsConfig.Certificates, 1)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+}
+
+func Test_server_Start(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+
+	// порт занят - ошибка возвращается сразу
+	wg := sync.WaitGroup{}
+	serv := NewPrometheusServer(&wg, time.Second, ServerConfig{})
+	assert.Error(t, serv.Start(busy.Addr().String()))
+	assert.NoError(t, serv.Stop())
+
+	// два сервера работают одновременно
+	first := NewPrometheusServer(&wg, time.Second, ServerConfig{})
+	require.NoError(t, first.Start("127.0.0.1:0"))
+	second := NewPrometheusServer(&wg, time.Second, ServerConfig{BearerToken: "token"})
+	require.NoError(t, second.Start("127.0.0.1:0"))
+
+	resp, err := http.Get("http://" + first.(*server).listener.Addr().String() + "/metrics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get("http://" + second.(*server).listener.Addr().String() + "/metrics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	assert.NoError(t, first.Stop())
+	assert.NoError(t, second.Stop())
+	wg.Wait()
+	assert.Empty(t, first.Errors(), "остановка через Stop не считается ошибкой")
+}
+
+func Test_server_Errors(t *testing.T) {
+	wg := sync.WaitGroup{}
+	serv := NewPrometheusServer(&wg, time.Second, ServerConfig{})
+	require.NoError(t, serv.Start("127.0.0.1:0"))
+
+	// сервер завершается сам, если слушающий сокет закрылся
+	require.NoError(t, serv.(*server).listener.Close())
+	select {
+	case err := <-serv.Errors():
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ошибка сервера не получена")
+	}
+	wg.Wait()
+}
+
+func Test_server_Probe(t *testing.T) {
+	wg := sync.WaitGroup{}
+	serv := NewPrometheusServer(&wg, time.Second, ServerConfig{})
+	serv.EnableProbe(func(project string) (repointerface.CommitIterator, error) {
+		if project == "broken" {
+			return &testItertor{
+				commits: []repointerface.Commit{{Author: "Ivan", Email: "ivan@email.com", Date: time.Now()}},
+				err:     &repointerface.ChangesetError{Id: 2, Err: errors.New("timeout")},
+			}, nil
+		}
+		if project != "project1" {
+			return nil, errors.New("unknown project")
+		}
+		return &testItertor{
+			commits: []repointerface.Commit{
+				{Author: "Ivan", Email: "ivan@email.com", AddedRows: 5, DeletedRows: 1, Date: time.Now()},
+				{Author: "Ivan", Email: "ivan@email.com", AddedRows: 2, DeletedRows: 3, Date: time.Now()},
+			},
+		}, nil
+	})
+	require.NoError(t, serv.Start("127.0.0.1:0"))
+	defer func() {
+		assert.NoError(t, serv.Stop())
+		wg.Wait()
+	}()
+	probeUrl := "http://" + serv.(*server).listener.Addr().String() + "/probe"
+
+	resp, err := http.Get(probeUrl + "?project=project1")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	labels := `{author="Ivan",author_id="email:ivan@email.com",email="ivan@email.com",project="project1",team=""}`
+	assert.Contains(t, string(body), "commits"+labels+" 2")
+	assert.Contains(t, string(body), "added_rows"+labels+" 7")
+	assert.Contains(t, string(body), "deleted_rows"+labels+" 4")
+
+	// повторный запрос считает метрики заново, а не накапливает их
+	resp, err = http.Get(probeUrl + "?project=project1")
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Contains(t, string(body), "commits"+labels+" 2")
+
+	resp, err = http.Get(probeUrl)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(probeUrl + "?project=unknown")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	// неполные данные не отдаются, чтобы Prometheus не принял их за сброс счетчиков
+	resp, err = http.Get(probeUrl + "?project=broken")
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Contains(t, string(body), "ченджсет 2: timeout")
+}
+
+// testCertificate создает самоподписанный сертификат для localhost и возвращает пути к сертификату и ключу
+func testCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
@@ -0,0 +1,206 @@
+package filter
+
+import (
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Условие отбора коммита
+type Predicate func(commit *repointerface.Commit) bool
+
+// Author отбирает коммиты, имя автора которых содержит name (без учета регистра)
+func Author(name string) Predicate {
+	name = strings.ToLower(name)
+	return func(commit *repointerface.Commit) bool {
+		return strings.Contains(strings.ToLower(commit.Author), name)
+	}
+}
+
+// Email отбирает коммиты, почта автора которых содержит email (без учета регистра)
+func Email(email string) Predicate {
+	email = strings.ToLower(email)
+	return func(commit *repointerface.Commit) bool {
+		return strings.Contains(strings.ToLower(commit.Email), email)
+	}
+}
+
+// Team отбирает коммиты, сделанные автором в составе команды team (без учета регистра)
+func Team(team string) Predicate {
+	return func(commit *repointerface.Commit) bool {
+		return strings.EqualFold(commit.Team, team)
+	}
+}
+
+// Since отбирает коммиты, сделанные не раньше date
+func Since(date time.Time) Predicate {
+	return func(commit *repointerface.Commit) bool {
+		return !commit.Date.Before(date)
+	}
+}
+
+// Until отбирает коммиты, сделанные не позже date
+func Until(date time.Time) Predicate {
+	return func(commit *repointerface.Commit) bool {
+		return !commit.Date.After(date)
+	}
+}
+
+// Grep отбирает коммиты, сообщение которых подходит под регулярное выражение
+func Grep(re *regexp.Regexp) Predicate {
+	return func(commit *repointerface.Commit) bool {
+		return re.MatchString(commit.Message)
+	}
+}
+
+// Path отбирает коммиты, затронувшие файл pattern или файл внутри каталога pattern
+// ($/project/src не включает $/project/src2), либо файл, подходящий под шаблон pattern (если в нем есть *, ? или [)
+func Path(pattern string) Predicate {
+	glob := strings.ContainsAny(pattern, "*?[")
+	dir := strings.TrimSuffix(pattern, "/")
+	return func(commit *repointerface.Commit) bool {
+		for _, file := range commit.Files {
+			if glob {
+				if ok, _ := path.Match(pattern, file); ok {
+					return true
+				}
+			} else if file == dir || strings.HasPrefix(file, dir+"/") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MinLines отбирает коммиты, в которых добавлено и удалено в сумме не меньше lines строк
+func MinLines(lines int) Predicate {
+	return func(commit *repointerface.Commit) bool {
+		return commit.AddedRows+commit.DeletedRows >= lines
+	}
+}
+
+// Параметры отбора коммитов. Пустые поля не ограничивают выборку
+type Options struct {
+	Author   string
+	Email    string
+	Team     string
+	Since    time.Time
+	Until    time.Time
+	Grep     *regexp.Regexp
+	Path     string
+	MinLines int
+}
+
+// Predicates возвращает условия для всех заданных параметров
+func (o *Options) Predicates() []Predicate {
+	predicates := []Predicate{}
+	if o.Author != "" {
+		predicates = append(predicates, Author(o.Author))
+	}
+	if o.Email != "" {
+		predicates = append(predicates, Email(o.Email))
+	}
+	if o.Team != "" {
+		predicates = append(predicates, Team(o.Team))
+	}
+	if !o.Since.IsZero() {
+		predicates = append(predicates, Since(o.Since))
+	}
+	if !o.Until.IsZero() {
+		predicates = append(predicates, Until(o.Until))
+	}
+	if o.Grep != nil {
+		predicates = append(predicates, Grep(o.Grep))
+	}
+	if o.Path != "" {
+		predicates = append(predicates, Path(o.Path))
+	}
+	if o.MinLines > 0 {
+		predicates = append(predicates, MinLines(o.MinLines))
+	}
+	return predicates
+}
+
+// Apply оборачивает итератор фильтром по всем заданным параметрам
+func (o *Options) Apply(iterator repointerface.CommitIterator) repointerface.CommitIterator {
+	return New(iterator, o.Predicates()...)
+}
+
+// New возвращает итератор, пропускающий коммиты, которые не удовлетворяют хотя бы одному условию
+func New(it repointerface.CommitIterator, predicates ...Predicate) repointerface.CommitIterator {
+	if len(predicates) == 0 {
+		return it
+	}
+	return combinator.Filter(it, func(commit *repointerface.Commit) bool {
+		for _, predicate := range predicates {
+			if !predicate(commit) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Limit возвращает не больше n первых коммитов (пропущенные ченджсеты не засчитываются, см. combinator.Take)
+func Limit(it repointerface.CommitIterator, n int) repointerface.CommitIterator {
+	return combinator.Take(it, n)
+}
+
+// Preload вычитывает итератор целиком в память. Так загрузку коммитов можно выполнить заранее
+// (например, параллельно по нескольким проектам), а обработать их позже. Если итератор завершился
+// не на ErrNoMoreItems, возвращается ошибка вместе с итератором по уже прочитанным коммитам
+func Preload(it repointerface.CommitIterator) (repointerface.CommitIterator, error) {
+	commits, err := combinator.Collect(it)
+	return combinator.FromSlice(commits), err
+}
+
+// Reverse вычитывает итератор целиком и возвращает коммиты в обратном порядке.
+// При ошибке, как и Preload, возвращает ее вместе с уже прочитанными коммитами
+func Reverse(it repointerface.CommitIterator) (repointerface.CommitIterator, error) {
+	commits, err := combinator.Collect(it)
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return combinator.FromSlice(commits), err
+}
+
+type skipIterator struct {
+	iterator repointerface.CommitIterator
+	onSkip   func(err *repointerface.ChangesetError)
+}
+
+// SkipFailed возвращает итератор, который пропускает ченджсеты, которые не удалось получить
+// (ошибка ChangesetError), и сообщает о каждом из них в onSkip. Остальные ошибки возвращаются как есть
+func SkipFailed(it repointerface.CommitIterator, onSkip func(err *repointerface.ChangesetError)) repointerface.CommitIterator {
+	return &skipIterator{
+		iterator: it,
+		onSkip:   onSkip,
+	}
+}
+
+func (i *skipIterator) Next() (*repointerface.Commit, error) {
+	for {
+		commit, err := i.iterator.Next()
+		var changesetErr *repointerface.ChangesetError
+		if !errors.As(err, &changesetErr) {
+			return commit, err
+		}
+		i.onSkip(changesetErr)
+	}
+}
+
+// ParseDate разбирает дату в формате 2006-01-02 или RFC3339. Для даты без времени
+// при endOfDay = true возвращается последний момент дня, чтобы граница включала весь день
+func ParseDate(value string, endOfDay bool) (time.Time, error) {
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			date = date.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,224 @@
+package filter
+
+import (
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"go-marathon-team-3/pkg/tfsmetrics/store"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCommits() []repointerface.Commit {
+	date := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	return []repointerface.Commit{
+		{
+			Id:          1,
+			Author:      "Ivan Ivanov",
+			Email:       "Ivan@email.com",
+			AddedRows:   10,
+			DeletedRows: 5,
+			Date:        date,
+			Message:     "Fix bug #12",
+			Files:       []string{"$/project/src/main.go", "$/project/README.md"},
+		},
+		{
+			Id:          2,
+			Author:      "Pety",
+			Email:       "pety@email.com",
+			AddedRows:   1,
+			DeletedRows: 0,
+			Date:        date.AddDate(0, 0, 1),
+			Message:     "typo",
+			Files:       []string{"$/project/docs/index.md", "$/project/src2/main.go"},
+		},
+		{
+			Id:          3,
+			Author:      "Ivan Ivanov",
+			Email:       "ivan@email.com",
+			AddedRows:   100,
+			DeletedRows: 50,
+			Date:        date.AddDate(0, 0, 2),
+			Message:     "Refactoring",
+		},
+	}
+}
+
+func ids(t *testing.T, iter repointerface.CommitIterator) []int {
+	res := []int{}
+	commit, err := iter.Next()
+	for ; err == nil; commit, err = iter.Next() {
+		res = append(res, commit.Id)
+	}
+	require.ErrorIs(t, err, repointerface.ErrNoMoreItems)
+	return res
+}
+
+func TestOptions_Apply(t *testing.T) {
+	date := time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		options Options
+		want    []int
+	}{
+		{
+			name:    "no filters",
+			options: Options{},
+			want:    []int{1, 2, 3},
+		},
+		{
+			name:    "author",
+			options: Options{Author: "ivan"},
+			want:    []int{1, 3},
+		},
+		{
+			name:    "email",
+			options: Options{Email: "IVAN@"},
+			want:    []int{1, 3},
+		},
+		{
+			name:    "since and until",
+			options: Options{Since: date.AddDate(0, 0, 1), Until: date.AddDate(0, 0, 1)},
+			want:    []int{2},
+		},
+		{
+			name:    "grep",
+			options: Options{Grep: regexp.MustCompile(`(?i)fix|typo`)},
+			want:    []int{1, 2},
+		},
+		{
+			name:    "path prefix",
+			options: Options{Path: "$/project/src"},
+			want:    []int{1},
+		},
+		{
+			name:    "path prefix with slash",
+			options: Options{Path: "$/project/src/"},
+			want:    []int{1},
+		},
+		{
+			name:    "path prefix is not part of name",
+			options: Options{Path: "$/project/sr"},
+			want:    []int{},
+		},
+		{
+			name:    "path file",
+			options: Options{Path: "$/project/README.md"},
+			want:    []int{1},
+		},
+		{
+			name:    "path glob",
+			options: Options{Path: "$/project/*/*.md"},
+			want:    []int{2},
+		},
+		{
+			name:    "min lines",
+			options: Options{MinLines: 15},
+			want:    []int{1, 3},
+		},
+		{
+			name:    "combined",
+			options: Options{Author: "ivan", MinLines: 100},
+			want:    []int{3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			iter := tt.options.Apply(&store.TestIterator{Commits: testCommits()})
+			assert.Equal(t, tt.want, ids(t, iter))
+		})
+	}
+}
+
+func TestLimitReverse(t *testing.T) {
+	iter := Limit(&store.TestIterator{Commits: testCommits()}, 2)
+	assert.Equal(t, []int{1, 2}, ids(t, iter))
+
+	iter, err := Reverse(&store.TestIterator{Commits: testCommits()})
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 2, 1}, ids(t, iter))
+
+	iter, err = Reverse(Limit(&store.TestIterator{Commits: testCommits()}, 2))
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 1}, ids(t, iter))
+}
+
+// Итератор, который после заданных коммитов возвращает ошибку
+type failingIterator struct {
+	store.TestIterator
+	err error
+}
+
+func (i *failingIterator) Next() (*repointerface.Commit, error) {
+	commit, err := i.TestIterator.Next()
+	if err == repointerface.ErrNoMoreItems {
+		return nil, i.err
+	}
+	return commit, err
+}
+
+func TestPreload(t *testing.T) {
+	iter, err := Preload(&store.TestIterator{Commits: testCommits()})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, ids(t, iter))
+
+	// При ошибке возвращаются уже прочитанные коммиты
+	iter, err = Preload(&failingIterator{TestIterator: store.TestIterator{Commits: testCommits()}, err: errors.New("timeout")})
+	assert.EqualError(t, err, "timeout")
+	assert.Equal(t, []int{1, 2, 3}, ids(t, iter))
+
+	iter, err = Reverse(&failingIterator{TestIterator: store.TestIterator{Commits: testCommits()[:2]}, err: errors.New("timeout")})
+	assert.EqualError(t, err, "timeout")
+	assert.Equal(t, []int{2, 1}, ids(t, iter))
+}
+
+// Итератор, который не может получить ченджсеты с заданными id
+type brokenIterator struct {
+	store.TestIterator
+	broken map[int]bool
+}
+
+func (i *brokenIterator) Next() (*repointerface.Commit, error) {
+	commit, err := i.TestIterator.Next()
+	if err == nil && i.broken[commit.Id] {
+		return nil, &repointerface.ChangesetError{Id: commit.Id, Err: errors.New("timeout")}
+	}
+	return commit, err
+}
+
+func TestSkipFailed(t *testing.T) {
+	skipped := []int{}
+	iter := SkipFailed(&brokenIterator{
+		TestIterator: store.TestIterator{Commits: testCommits()},
+		broken:       map[int]bool{1: true, 3: true},
+	}, func(err *repointerface.ChangesetError) {
+		skipped = append(skipped, err.Id)
+	})
+	assert.Equal(t, []int{2}, ids(t, iter))
+	assert.Equal(t, []int{1, 3}, skipped)
+
+	// Ошибки, не относящиеся к отдельному ченджсету, не пропускаются
+	iter = SkipFailed(&failingIterator{err: errors.New("timeout")}, func(*repointerface.ChangesetError) {})
+	_, err := iter.Next()
+	assert.EqualError(t, err, "timeout")
+}
+
+func TestParseDate(t *testing.T) {
+	date, err := ParseDate("2021-10-01", false)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 10, 1, 0, 0, 0, 0, time.Local), date)
+
+	date, err = ParseDate("2021-10-01", true)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 10, 1, 23, 59, 59, 999999999, time.Local), date)
+
+	date, err = ParseDate("2021-10-01T10:00:00Z", true)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC), date)
+
+	_, err = ParseDate("01.10.2021", false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,138 @@
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Префикс ключа автора, объединенного файлом псевдонимов
+const AliasPrefix = "alias:"
+
+// Человек из файла псевдонимов
+type Person struct {
+	// Имя и почта, под которыми выводятся коммиты всех учетных записей человека
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	// Идентификаторы учетных записей, почты или имена, под которыми встречаются коммиты человека
+	// (без учета регистра). Имя может совпадать у разных людей, поэтому по имени сопоставляются только коммиты
+	// без идентификатора учетной записи. Имя Name само по себе псевдонимом не считается
+	Aliases []string `json:"aliases"`
+}
+
+// Содержимое файла псевдонимов
+type Aliases struct {
+	Authors []Person `json:"authors"`
+}
+
+// ReadAliases читает файл псевдонимов. Если файла нет, возвращаются пустые псевдонимы
+func ReadAliases(path string) (*Aliases, error) {
+	aliases := &Aliases{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return aliases, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, aliases); err != nil {
+		return nil, fmt.Errorf("файл псевдонимов %s: %w", path, err)
+	}
+	return aliases, nil
+}
+
+type Resolver interface {
+	// Возвращает копию коммита с именем, почтой и ключом человека из файла псевдонимов
+	// или сам коммит, если его автора нет в файле
+	Resolve(commit *repointerface.Commit) *repointerface.Commit
+	// Оборачивает итератор, заменяя авторов коммитов
+	Apply(it repointerface.CommitIterator) repointerface.CommitIterator
+	// Возвращает ключ, который получают коммиты человека с именем или псевдонимом alias,
+	// или пустую строку, если такого человека нет в файле
+	Key(alias string) string
+}
+
+type resolver struct {
+	people map[string]*Person
+	// Основные имена людей (для Key)
+	names map[string]*Person
+}
+
+// NewResolver проверяет псевдонимы: один псевдоним не может относиться к разным людям
+func NewResolver(aliases *Aliases) (Resolver, error) {
+	r := &resolver{people: make(map[string]*Person), names: make(map[string]*Person)}
+	for k := range aliases.Authors {
+		person := &aliases.Authors[k]
+		if person.Name == "" {
+			return nil, fmt.Errorf("в файле псевдонимов не указано имя автора с псевдонимами %s", strings.Join(person.Aliases, ", "))
+		}
+		r.names[strings.ToLower(person.Name)] = person
+		names := append([]string{person.Email}, person.Aliases...)
+		for _, alias := range names {
+			alias = strings.ToLower(alias)
+			if alias == "" {
+				continue
+			}
+			if other, ok := r.people[alias]; ok && other != person {
+				return nil, fmt.Errorf("псевдоним '%s' указан у двух авторов: '%s' и '%s'", alias, other.Name, person.Name)
+			}
+			r.people[alias] = person
+		}
+	}
+	return r, nil
+}
+
+func (r *resolver) Resolve(commit *repointerface.Commit) *repointerface.Commit {
+	person := r.find(commit)
+	if person == nil {
+		return commit
+	}
+	res := *commit
+	res.Author = person.Name
+	if person.Email != "" {
+		res.Email = person.Email
+	}
+	res.AuthorId = AliasPrefix + person.Name
+	return &res
+}
+
+// find ищет автора сначала по идентификатору, затем по почте. По имени ищутся только коммиты без идентификатора:
+// у другой учетной записи с тем же отображаемым именем идентификатор будет свой
+func (r *resolver) find(commit *repointerface.Commit) *Person {
+	aliases := []string{commit.AuthorId, commit.Email}
+	if commit.AuthorId == "" {
+		aliases = append(aliases, commit.Author)
+	}
+	for _, alias := range aliases {
+		if alias == "" {
+			continue
+		}
+		if person, ok := r.people[strings.ToLower(alias)]; ok {
+			return person
+		}
+	}
+	return nil
+}
+
+func (r *resolver) Key(alias string) string {
+	alias = strings.ToLower(alias)
+	person, ok := r.people[alias]
+	if !ok {
+		person, ok = r.names[alias]
+	}
+	if !ok {
+		return ""
+	}
+	return AliasPrefix + person.Name
+}
+
+func (r *resolver) Apply(it repointerface.CommitIterator) repointerface.CommitIterator {
+	if len(r.people) == 0 {
+		return it
+	}
+	return combinator.Map(it, r.Resolve)
+}
@@ -0,0 +1,87 @@
+package identity
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAliases(t *testing.T) {
+	dir := t.TempDir()
+	aliases, err := ReadAliases(filepath.Join(dir, "aliases.json"))
+	require.NoError(t, err)
+	assert.Empty(t, aliases.Authors)
+
+	path := filepath.Join(dir, "broken.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+	_, err = ReadAliases(path)
+	assert.Error(t, err)
+
+	path = filepath.Join(dir, "aliases.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"authors": [{"name": "Иван Иванов", "aliases": ["DOMAIN\\iivanov"]}]}`), 0600))
+	aliases, err = ReadAliases(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Person{{Name: "Иван Иванов", Aliases: []string{`DOMAIN\iivanov`}}}, aliases.Authors)
+}
+
+func TestResolver(t *testing.T) {
+	resolver, err := NewResolver(&Aliases{Authors: []Person{
+		{Name: "Иван Иванов", Email: "ivanov@corp.ru", Aliases: []string{"id-1", `domain\iivanov`, "Ivanov I."}},
+	}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		commit   *repointerface.Commit
+		resolved bool
+	}{
+		{name: "by id", commit: commit("Ivan", "ID-1", "ivan@mail.ru", 0), resolved: true},
+		{name: "by email", commit: commit("Ivan", "", `DOMAIN\iivanov`, 0), resolved: true},
+		{name: "by name", commit: commit("ivanov i.", "", "", 0), resolved: true},
+		{name: "namesake with other id", commit: commit("Ivanov I.", "id-5", "", 0)},
+		{name: "by canonical email", commit: commit("Ivan", "id-6", "ivanov@corp.ru", 0), resolved: true},
+		{name: "canonical name is not alias", commit: commit("Иван Иванов", "", "", 0)},
+		{name: "other author", commit: commit("Petr", "id-2", "petr@corp.ru", 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := *tt.commit
+			res := resolver.Resolve(tt.commit)
+			assert.Equal(t, before, *tt.commit, "исходный коммит не должен меняться")
+			if !tt.resolved {
+				assert.Same(t, tt.commit, res)
+				return
+			}
+			assert.Equal(t, "Иван Иванов", res.Author)
+			assert.Equal(t, "ivanov@corp.ru", res.Email)
+			assert.Equal(t, AliasPrefix+"Иван Иванов", Key(res))
+		})
+	}
+
+	all, err := combinator.Collect(resolver.Apply(combinator.FromSlice([]*repointerface.Commit{
+		commit("Ivan", "id-1", "", 0), commit("Petr", "id-2", "", 0),
+	})))
+	require.NoError(t, err)
+	assert.Equal(t, "Иван Иванов", all[0].Author)
+	assert.Equal(t, "Petr", all[1].Author)
+
+	assert.Equal(t, AliasPrefix+"Иван Иванов", resolver.Key("DOMAIN\\iivanov"))
+	assert.Equal(t, AliasPrefix+"Иван Иванов", resolver.Key("иван иванов"))
+	assert.Empty(t, resolver.Key("Petr"))
+}
+
+func TestNewResolver_errors(t *testing.T) {
+	_, err := NewResolver(&Aliases{Authors: []Person{
+		{Name: "Иван Иванов", Aliases: []string{"Ivanov"}},
+		{Name: "Петр Иванов", Aliases: []string{"ivanov"}},
+	}})
+	assert.EqualError(t, err, "псевдоним 'ivanov' указан у двух авторов: 'Иван Иванов' и 'Петр Иванов'")
+
+	_, err = NewResolver(&Aliases{Authors: []Person{{Aliases: []string{"Ivanov"}}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,165 @@
+// Package identity определяет, какие коммиты сделаны одним человеком. Авторы различаются по идентификатору
+// учетной записи (IdentityRef.Id), а не по отображаемому имени: имя меняется при переименовании учетной записи
+// и может совпадать у разных людей. Файл псевдонимов позволяет объединить несколько учетных записей одного человека
+package identity
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Key возвращает ключ автора коммита: идентификатор учетной записи, а для коммитов без него
+// (например, из кеша старых версий) - почту или, если нет и ее, имя
+func Key(commit *repointerface.Commit) string {
+	if commit.AuthorId != "" {
+		return commit.AuthorId
+	}
+	if commit.Email != "" {
+		return emailKey(commit.Email)
+	}
+	return "name:" + strings.ToLower(commit.Author)
+}
+
+func emailKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+// Автор и его коммиты
+type Identity struct {
+	Key string
+	// Имя и почта из самого нового коммита
+	Name  string
+	Email string
+	// Все имена и почты, под которыми встречались коммиты автора
+	Names  []string
+	Emails []string
+
+	Commits     int
+	AddedRows   int
+	DeletedRows int
+
+	last time.Time
+}
+
+func (i *Identity) add(commit *repointerface.Commit) {
+	if i.Commits == 0 || !commit.Date.Before(i.last) {
+		i.Name = commit.Author
+		i.Email = commit.Email
+		i.last = commit.Date
+	}
+	i.Names = appendUnique(i.Names, commit.Author)
+	i.Emails = appendUnique(i.Emails, commit.Email)
+	i.Commits++
+	i.AddedRows += commit.AddedRows
+	i.DeletedRows += commit.DeletedRows
+}
+
+func (i *Identity) merge(other *Identity) {
+	if other.last.After(i.last) {
+		i.Name, i.Email, i.last = other.Name, other.Email, other.last
+	}
+	for _, name := range other.Names {
+		i.Names = appendUnique(i.Names, name)
+	}
+	for _, email := range other.Emails {
+		i.Emails = appendUnique(i.Emails, email)
+	}
+	i.Commits += other.Commits
+	i.AddedRows += other.AddedRows
+	i.DeletedRows += other.DeletedRows
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// Index группирует коммиты по авторам
+type Index struct {
+	identities map[string]*Identity
+	// Ключи по почте, которые объединены с ключом учетной записи
+	redirects map[string]string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		identities: make(map[string]*Identity),
+		redirects:  make(map[string]string),
+	}
+}
+
+// Add добавляет коммит к его автору. Коммиты без идентификатора учетной записи относятся к учетной записи
+// с той же почтой, если она встречалась, в том числе если коммит с идентификатором встретится позже
+func (x *Index) Add(commit *repointerface.Commit) *Identity {
+	key := x.key(commit)
+	identity, ok := x.identities[key]
+	if !ok {
+		identity = &Identity{Key: key}
+		x.identities[key] = identity
+	}
+	identity.add(commit)
+	if commit.AuthorId != "" && commit.Email != "" {
+		byEmail := emailKey(commit.Email)
+		if _, ok := x.redirects[byEmail]; !ok {
+			x.redirects[byEmail] = key
+			if other, ok := x.identities[byEmail]; ok {
+				identity.merge(other)
+				delete(x.identities, byEmail)
+			}
+		}
+	}
+	return identity
+}
+
+// Find возвращает автора коммита, уже добавленного в индекс
+func (x *Index) Find(commit *repointerface.Commit) *Identity {
+	return x.identities[x.key(commit)]
+}
+
+func (x *Index) key(commit *repointerface.Commit) string {
+	key := Key(commit)
+	if redirect, ok := x.redirects[key]; ok {
+		return redirect
+	}
+	return key
+}
+
+// Identities возвращает авторов, упорядоченных по имени
+func (x *Index) Identities() []*Identity {
+	res := make([]*Identity, 0, len(x.identities))
+	for _, identity := range x.identities {
+		res = append(res, identity)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Name != res[j].Name {
+			return res[i].Name < res[j].Name
+		}
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
+
+// Labels возвращает имена авторов для вывода: имя, а для разных людей с одинаковым именем - имя с почтой
+func (x *Index) Labels() map[*Identity]string {
+	count := make(map[string]int)
+	for _, identity := range x.identities {
+		count[identity.Name]++
+	}
+	res := make(map[*Identity]string, len(x.identities))
+	for _, identity := range x.identities {
+		res[identity] = identity.Name
+		if count[identity.Name] > 1 {
+			res[identity] = identity.Name + " <" + identity.Email + ">"
+		}
+	}
+	return res
+}
@@ -0,0 +1,77 @@
+package identity
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var baseDate = time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func commit(author, authorId, email string, day int) *repointerface.Commit {
+	return &repointerface.Commit{
+		Author:    author,
+		AuthorId:  authorId,
+		Email:     email,
+		AddedRows: 1,
+		Date:      baseDate.AddDate(0, 0, day),
+	}
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "id-1", Key(commit("Ivan", "id-1", "ivan@mail.ru", 0)))
+	assert.Equal(t, "email:ivan@mail.ru", Key(commit("Ivan", "", "Ivan@Mail.ru", 0)))
+	assert.Equal(t, "name:ivan", Key(commit("Ivan", "", "", 0)))
+}
+
+func TestIndex(t *testing.T) {
+	index := NewIndex()
+	// Коммит из старого кеша без идентификатора относится к учетной записи с той же почтой
+	index.Add(commit("Ivanov I.", "", `DOMAIN\iivanov`, 0))
+	index.Add(commit("Ivan Ivanov", "id-1", `DOMAIN\iivanov`, 2))
+	index.Add(commit("Ivanov I.", "id-1", `DOMAIN\iivanov`, 1))
+	index.Add(commit("Ivanov I.", "", `domain\iivanov`, 0))
+	// Другой человек с тем же именем
+	index.Add(commit("Ivan Ivanov", "id-2", "ivanov@corp.ru", 0))
+
+	authors := index.Identities()
+	require.Len(t, authors, 2)
+	assert.Equal(t, "id-1", authors[0].Key)
+	assert.Equal(t, "Ivan Ivanov", authors[0].Name)
+	assert.ElementsMatch(t, []string{"Ivanov I.", "Ivan Ivanov"}, authors[0].Names)
+	assert.Equal(t, 4, authors[0].Commits)
+	assert.Equal(t, 4, authors[0].AddedRows)
+	assert.Equal(t, "id-2", authors[1].Key)
+	assert.Equal(t, authors[0], index.Find(commit("", "", `DOMAIN\iivanov`, 0)))
+
+	labels := index.Labels()
+	assert.Equal(t, `Ivan Ivanov <DOMAIN\iivanov>`, labels[authors[0]])
+	assert.Equal(t, "Ivan Ivanov <ivanov@corp.ru>", labels[authors[1]])
+
+	index = NewIndex()
+	petr := index.Add(commit("Petr", "id-3", "petr@corp.ru", 0))
+	assert.Equal(t, map[*Identity]string{petr: "Petr"}, index.Labels())
+}
+
+func TestSuggest(t *testing.T) {
+	index := NewIndex()
+	index.Add(commit("Ivanov I.", "id-1", `DOMAIN\iivanov`, 0))
+	index.Add(commit("Ivan Ivanov", "id-2", "Ivan.Ivanov@corp.ru", 0))
+	index.Add(commit("Ivan Ivanov", "id-2", "Ivan.Ivanov@corp.ru", 1))
+	index.Add(commit("Ivan", "", "iivanov@mail.ru", 0))
+	index.Add(commit("Petr", "id-3", "petr@corp.ru", 0))
+	index.Add(commit("Petr Petrov", "id-4", "ppetrov@corp.ru", 0))
+
+	suggestions := Suggest(index.Identities())
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "iivanov", suggestions[0].Login)
+	assert.Len(t, suggestions[0].Identities, 3)
+	// Имя и почта берутся у автора с наибольшим числом коммитов
+	person := suggestions[0].Person()
+	assert.Equal(t, "Ivan Ivanov", person.Name)
+	assert.Equal(t, "Ivan.Ivanov@corp.ru", person.Email)
+	assert.ElementsMatch(t, []string{"id-1", `DOMAIN\iivanov`, "id-2", "Ivan.Ivanov@corp.ru", "iivanov@mail.ru"}, person.Aliases)
+}
@@ -0,0 +1,119 @@
+package identity
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Предложение объединить авторов, почты которых похожи
+type Suggestion struct {
+	Login      string
+	Identities []*Identity
+}
+
+// Person возвращает запись для файла псевдонимов, объединяющую авторов предложения
+// под именем и почтой самого активного из них
+func (s *Suggestion) Person() Person {
+	main := s.Identities[0]
+	for _, identity := range s.Identities[1:] {
+		if identity.Commits > main.Commits {
+			main = identity
+		}
+	}
+	person := Person{Name: main.Name, Email: main.Email}
+	for _, identity := range s.Identities {
+		if !strings.HasPrefix(identity.Key, "email:") && !strings.HasPrefix(identity.Key, "name:") &&
+			!strings.HasPrefix(identity.Key, AliasPrefix) {
+			person.Aliases = appendUnique(person.Aliases, identity.Key)
+		}
+		for _, email := range identity.Emails {
+			person.Aliases = appendUnique(person.Aliases, email)
+		}
+	}
+	return person
+}
+
+// Suggest предлагает объединить авторов, у которых совпадает логин почты без учета домена,
+// регистра и разделителей (DOMAIN\iivanov, iivanov@corp.ru, i.ivanov@corp.ru), в том числе
+// если один логин - инициал и фамилия из другого (ivan.ivanov и iivanov)
+func Suggest(identities []*Identity) []Suggestion {
+	groups := make(map[string][]*Identity)
+	for _, identity := range identities {
+		seen := map[string]bool{}
+		for _, email := range identity.Emails {
+			for _, variant := range loginVariants(email) {
+				if !seen[variant] {
+					seen[variant] = true
+					groups[variant] = append(groups[variant], identity)
+				}
+			}
+		}
+	}
+	// Авторы из нескольких групп объединяются в одно предложение под самым коротким логином
+	logins := make([]string, 0, len(groups))
+	for login, group := range groups {
+		if len(group) > 1 {
+			logins = append(logins, login)
+		}
+	}
+	sort.Slice(logins, func(i, j int) bool {
+		if len(logins[i]) != len(logins[j]) {
+			return len(logins[i]) < len(logins[j])
+		}
+		return logins[i] < logins[j]
+	})
+	suggested := map[*Identity]*Suggestion{}
+	res := []*Suggestion{}
+	for _, login := range logins {
+		var suggestion *Suggestion
+		for _, identity := range groups[login] {
+			if s, ok := suggested[identity]; ok {
+				suggestion = s
+				break
+			}
+		}
+		if suggestion == nil {
+			suggestion = &Suggestion{Login: login}
+			res = append(res, suggestion)
+		}
+		for _, identity := range groups[login] {
+			if _, ok := suggested[identity]; !ok {
+				suggested[identity] = suggestion
+				suggestion.Identities = append(suggestion.Identities, identity)
+			}
+		}
+	}
+
+	suggestions := []Suggestion{}
+	for _, suggestion := range res {
+		if len(suggestion.Identities) > 1 {
+			suggestions = append(suggestions, *suggestion)
+		}
+	}
+	return suggestions
+}
+
+// loginVariants возвращает логин почты без разделителей и, если логин состоит из нескольких частей,
+// инициал первой части с последней частью
+func loginVariants(email string) []string {
+	login := strings.ToLower(email)
+	if i := strings.LastIndex(login, `\`); i >= 0 {
+		login = login[i+1:]
+	}
+	if i := strings.Index(login, "@"); i >= 0 {
+		login = login[:i]
+	}
+	parts := strings.FieldsFunc(login, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(parts) == 0 {
+		return nil
+	}
+	variants := []string{strings.Join(parts, "")}
+	if len(parts) > 1 {
+		first := []rune(parts[0])
+		variants = append(variants, string(first[0])+parts[len(parts)-1])
+	}
+	return variants
+}
@@ -4,6 +4,7 @@ import (
 	"go-marathon-team-3/pkg/tfsmetrics/azure"
 	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
 	"go-marathon-team-3/pkg/tfsmetrics/store"
+	"log"
 )
 
 type commitsCollection struct {
@@ -14,12 +15,12 @@ type commitsCollection struct {
 	store store.Store
 }
 
-// Если cache = false, то в store передаем nil
+// Если cache = false, то в store передаем nil. Без хранилища кеш не используется
 func NewCommitCollection(nameOfProject string, azure azure.AzureInterface, cache bool, store store.Store) repointerface.Repository {
 	return &commitsCollection{
 		nameOfProject: nameOfProject,
 		azure:         azure,
-		cache:         cache,
+		cache:         cache && store != nil,
 		store:         store,
 	}
 }
@@ -40,7 +41,7 @@ func (c *commitsCollection) GetCommitIterator() (repointerface.CommitIterator, e
 		index:         0,
 		commits:       changeSets,
 		nameOfProject: c.nameOfProject,
-		azure:         c.azure.Azure(),
+		azure:         c.azure,
 		cache:         c.cache,
 		store:         c.store,
 	}, nil
@@ -55,37 +56,55 @@ type iterator struct {
 
 	cache bool
 	store store.Store
+	// Запись в кеш не удалась, дальше коммиты только читаются из кеша
+	writeFailed bool
 }
 
+// Next возвращает коммит из кеша или из Azure. Коммит, сохраненный старой версией без списка файлов,
+// загружается заново и перезаписывается в кеше. Ошибка записи в кеш не прерывает обход: коммит возвращается
+// без ошибки, а в лог выводится предупреждение, после которого коммиты проекта в кеш не записываются
 func (i *iterator) Next() (*repointerface.Commit, error) {
 	if i.index < len(i.commits) {
 		i.index++
 		if i.cache {
 			changeSet, err := i.store.FindOne(*i.commits[i.index-1], i.nameOfProject)
-			if err == nil {
+			if err == nil && !store.Stale(changeSet) {
 				return changeSet, err
 			}
 		}
 		changeSet, err := i.azure.GetChangesetChanges(i.commits[i.index-1], i.nameOfProject)
 		if err != nil {
-			return nil, err
+			return nil, &repointerface.ChangesetError{Id: *i.commits[i.index-1], Err: err}
 		}
-		commit := repointerface.Commit{
-			Id:          changeSet.Id,
-			Author:      changeSet.Author,
-			Email:       changeSet.Email,
-			AddedRows:   changeSet.AddedRows,
-			DeletedRows: changeSet.DeletedRows,
-			Date:        changeSet.Date,
-			Message:     changeSet.Message,
-			Hash:        changeSet.Hash,
-		}
-		if i.cache {
-			if err := i.store.Write(&commit, i.nameOfProject); err != nil {
-				return &commit, err
+		commit := commitFromChangeSet(changeSet)
+		if i.cache && !i.writeFailed {
+			if err := i.store.Write(commit, i.nameOfProject); err != nil {
+				log.Printf("Внимание: ченджсет %d проекта %s не сохранен в кеш: %v. Следующие ченджсеты проекта не кешируются",
+					commit.Id, i.nameOfProject, err)
+				i.writeFailed = true
 			}
 		}
-		return &commit, nil
+		return commit, nil
 	}
 	return nil, repointerface.ErrNoMoreItems
 }
+
+func commitFromChangeSet(changeSet *azure.ChangeSet) *repointerface.Commit {
+	// Пустой список файлов отличает ченджсет без файлов от устаревшей записи кеша
+	files := changeSet.Files
+	if files == nil {
+		files = []string{}
+	}
+	return &repointerface.Commit{
+		Id:          changeSet.Id,
+		Author:      changeSet.Author,
+		AuthorId:    changeSet.AuthorId,
+		Email:       changeSet.Email,
+		AddedRows:   changeSet.AddedRows,
+		DeletedRows: changeSet.DeletedRows,
+		Date:        changeSet.Date,
+		Message:     changeSet.Message,
+		Hash:        changeSet.Hash,
+		Files:       files,
+	}
+}
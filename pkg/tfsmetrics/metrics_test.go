@@ -61,6 +61,7 @@ func Test_iterator_Next_cahche_false(t *testing.T) {
 		Date:        time.Now(),
 		Message:     "hello world",
 		Hash:        "",
+		Files:       []string{"$/project/main.go"},
 	}
 
 	iter := iterator{
@@ -86,6 +87,7 @@ func Test_iterator_Next_cahche_false(t *testing.T) {
 			Date:        c.Date,
 			Message:     c.Message,
 			Hash:        c.Hash,
+			Files:       c.Files,
 		}, nil)
 
 	commit, err := iter.Next()
@@ -101,7 +103,10 @@ func Test_iterator_Next_cahche_false(t *testing.T) {
 		Return(nil, errors.New("error"))
 
 	commit, err = iter.Next()
-	assert.Error(t, err)
+	assert.EqualError(t, err, "ченджсет 3: error")
+	var changesetErr *repointerface.ChangesetError
+	assert.ErrorAs(t, err, &changesetErr)
+	assert.Equal(t, 3, changesetErr.Id)
 	assert.Nil(t, commit)
 }
 
@@ -126,6 +131,7 @@ func Test_iterator_Next_cahche_true(t *testing.T) {
 		Date:        time.Now(),
 		Message:     "hello world",
 		Hash:        "",
+		Files:       []string{"$/project/main.go"},
 	}
 	c2 := c
 	c2.Id = 2
@@ -153,6 +159,7 @@ func Test_iterator_Next_cahche_true(t *testing.T) {
 			Date:        c.Date,
 			Message:     c.Message,
 			Hash:        c.Hash,
+			Files:       c.Files,
 		}, nil)
 
 	mockedStore.
@@ -180,3 +187,61 @@ func Test_iterator_Next_cahche_true(t *testing.T) {
 	assert.Equal(t, &c2, commit)
 
 }
+
+func Test_iterator_Next_cacheWriteError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedStore := mock.NewMockStore(ctrl)
+	mockedAzure := mock_azure.NewMockAzureInterface(ctrl)
+
+	project := "project"
+	ids := []int{1, 2}
+	iter := iterator{
+		commits:       []*int{&ids[0], &ids[1]},
+		nameOfProject: project,
+		azure:         mockedAzure,
+		cache:         true,
+		store:         mockedStore,
+	}
+
+	// коммит получен, поэтому ошибка записи в кеш не возвращается
+	mockedStore.EXPECT().FindOne(1, project).Return(nil, errors.New("no item"))
+	mockedAzure.EXPECT().GetChangesetChanges(&ids[0], project).Return(&azure.ChangeSet{Id: 1, Author: "Ivan"}, nil)
+	mockedStore.EXPECT().Write(gomock.Any(), project).Return(errors.New("disk full"))
+	commit, err := iter.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, commit.Id)
+
+	// после ошибки коммиты только читаются из кеша, записи больше не выполняются
+	mockedStore.EXPECT().FindOne(2, project).Return(nil, errors.New("no item"))
+	mockedAzure.EXPECT().GetChangesetChanges(&ids[1], project).Return(&azure.ChangeSet{Id: 2, Author: "Petr"}, nil)
+	commit, err = iter.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, commit.Id)
+}
+
+func Test_iterator_Next_staleCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedStore := mock.NewMockStore(ctrl)
+	mockedAzure := mock_azure.NewMockAzureInterface(ctrl)
+
+	project := "project"
+	id := 1
+	iter := iterator{
+		commits:       []*int{&id},
+		nameOfProject: project,
+		azure:         mockedAzure,
+		cache:         true,
+		store:         mockedStore,
+	}
+
+	// коммит из кеша старой версии без списка файлов загружается заново и перезаписывается
+	mockedStore.EXPECT().FindOne(1, project).Return(&repointerface.Commit{Id: 1, Author: "Ivan"}, nil)
+	mockedAzure.EXPECT().GetChangesetChanges(&id, project).
+		Return(&azure.ChangeSet{Id: 1, Author: "Ivan", Files: []string{"$/project/main.go"}}, nil)
+	mockedStore.EXPECT().Write(gomock.Any(), project).Return(nil)
+	commit, err := iter.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"$/project/main.go"}, commit.Files)
+}
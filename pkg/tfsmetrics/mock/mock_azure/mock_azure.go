@@ -65,9 +65,11 @@ func (mr *MockAzureInterfaceMockRecorder) ChangedRows(currentFilePath, version i
 }
 
 // Connect mocks base method.
-func (m *MockAzureInterface) Connect() {
+func (m *MockAzureInterface) Connect() error {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "Connect")
+	ret := m.ctrl.Call(m, "Connect")
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // Connect indicates an expected call of Connect.
@@ -91,6 +93,21 @@ func (mr *MockAzureInterfaceMockRecorder) GetChangesetChanges(id, project interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChangesetChanges", reflect.TypeOf((*MockAzureInterface)(nil).GetChangesetChanges), id, project)
 }
 
+// GetChangesetDetails mocks base method.
+func (m *MockAzureInterface) GetChangesetDetails(id *int, project string) (*azure.ChangeSetDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChangesetDetails", id, project)
+	ret0, _ := ret[0].(*azure.ChangeSetDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChangesetDetails indicates an expected call of GetChangesetDetails.
+func (mr *MockAzureInterfaceMockRecorder) GetChangesetDetails(id, project interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChangesetDetails", reflect.TypeOf((*MockAzureInterface)(nil).GetChangesetDetails), id, project)
+}
+
 // GetChangesets mocks base method.
 func (m *MockAzureInterface) GetChangesets(nameOfProject string) ([]*int, error) {
 	m.ctrl.T.Helper()
@@ -106,6 +123,36 @@ func (mr *MockAzureInterfaceMockRecorder) GetChangesets(nameOfProject interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChangesets", reflect.TypeOf((*MockAzureInterface)(nil).GetChangesets), nameOfProject)
 }
 
+// GetItemContentAt mocks base method.
+func (m *MockAzureInterface) GetItemContentAt(path string, changeset int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetItemContentAt", path, changeset)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetItemContentAt indicates an expected call of GetItemContentAt.
+func (mr *MockAzureInterfaceMockRecorder) GetItemContentAt(path, changeset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemContentAt", reflect.TypeOf((*MockAzureInterface)(nil).GetItemContentAt), path, changeset)
+}
+
+// GetItemHistory mocks base method.
+func (m *MockAzureInterface) GetItemHistory(path string) ([]azure.ItemVersion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetItemHistory", path)
+	ret0, _ := ret[0].([]azure.ItemVersion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetItemHistory indicates an expected call of GetItemHistory.
+func (mr *MockAzureInterfaceMockRecorder) GetItemHistory(path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItemHistory", reflect.TypeOf((*MockAzureInterface)(nil).GetItemHistory), path)
+}
+
 // ListOfProjects mocks base method.
 func (m *MockAzureInterface) ListOfProjects() ([]*string, error) {
 	m.ctrl.T.Helper()
@@ -34,6 +34,35 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// Check mocks base method.
+func (m *MockStore) Check() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockStoreMockRecorder) Check() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockStore)(nil).Check))
+}
+
+// Checkpoint mocks base method.
+func (m *MockStore) Checkpoint(projectName string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Checkpoint", projectName)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Checkpoint indicates an expected call of Checkpoint.
+func (mr *MockStoreMockRecorder) Checkpoint(projectName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Checkpoint", reflect.TypeOf((*MockStore)(nil).Checkpoint), projectName)
+}
+
 // Close mocks base method.
 func (m *MockStore) Close() error {
 	m.ctrl.T.Helper()
@@ -48,6 +77,36 @@ func (mr *MockStoreMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStore)(nil).Close))
 }
 
+// CommitIterator mocks base method.
+func (m *MockStore) CommitIterator(projectName string) (repointerface.CommitIterator, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CommitIterator", projectName)
+	ret0, _ := ret[0].(repointerface.CommitIterator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CommitIterator indicates an expected call of CommitIterator.
+func (mr *MockStoreMockRecorder) CommitIterator(projectName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitIterator", reflect.TypeOf((*MockStore)(nil).CommitIterator), projectName)
+}
+
+// FindContent mocks base method.
+func (m *MockStore) FindContent(path string, changeset int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindContent", path, changeset)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindContent indicates an expected call of FindContent.
+func (mr *MockStoreMockRecorder) FindContent(path, changeset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindContent", reflect.TypeOf((*MockStore)(nil).FindContent), path, changeset)
+}
+
 // FindOne mocks base method.
 func (m *MockStore) FindOne(id int, projectName string) (*repointerface.Commit, error) {
 	m.ctrl.T.Helper()
@@ -77,6 +136,21 @@ func (mr *MockStoreMockRecorder) InitProject(projectName interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitProject", reflect.TypeOf((*MockStore)(nil).InitProject), projectName)
 }
 
+// Projects mocks base method.
+func (m *MockStore) Projects() ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Projects")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Projects indicates an expected call of Projects.
+func (mr *MockStoreMockRecorder) Projects() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Projects", reflect.TypeOf((*MockStore)(nil).Projects))
+}
+
 // Write mocks base method.
 func (m *MockStore) Write(commit *repointerface.Commit, projectName string) error {
 	m.ctrl.T.Helper()
@@ -90,3 +164,31 @@ func (mr *MockStoreMockRecorder) Write(commit, projectName interface{}) *gomock.
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStore)(nil).Write), commit, projectName)
 }
+
+// WriteCheckpoint mocks base method.
+func (m *MockStore) WriteCheckpoint(projectName string, changeset int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteCheckpoint", projectName, changeset)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteCheckpoint indicates an expected call of WriteCheckpoint.
+func (mr *MockStoreMockRecorder) WriteCheckpoint(projectName, changeset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteCheckpoint", reflect.TypeOf((*MockStore)(nil).WriteCheckpoint), projectName, changeset)
+}
+
+// WriteContent mocks base method.
+func (m *MockStore) WriteContent(path string, changeset int, content string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteContent", path, changeset, content)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteContent indicates an expected call of WriteContent.
+func (mr *MockStoreMockRecorder) WriteContent(path, changeset, content interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteContent", reflect.TypeOf((*MockStore)(nil).WriteContent), path, changeset, content)
+}
@@ -2,27 +2,49 @@ package repointerface
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
+// Возвращается итератором, когда коммиты закончились. Любая другая ошибка означает,
+// что данные получены не полностью
 var ErrNoMoreItems error = errors.New("no more items")
 
+// Ошибка получения отдельного ченджсета. После нее итератор можно продолжать:
+// следующий вызов Next вернет следующий коммит
+type ChangesetError struct {
+	Id  int
+	Err error
+}
+
+func (e *ChangesetError) Error() string {
+	return fmt.Sprintf("ченджсет %d: %v", e.Id, e.Err)
+}
+
+func (e *ChangesetError) Unwrap() error {
+	return e.Err
+}
+
 type Repository interface {
 	Open() error // Вызывать для каждого проекта, если включен кэш
 	GetCommitIterator() (CommitIterator, error)
 }
 
 type CommitIterator interface {
+	// Возвращает следующий коммит, ErrNoMoreItems в конце или ошибку, если коммит не удалось получить
 	Next() (*Commit, error)
 }
 
 type Commit struct {
 	Id          int
 	Author      string // обязательное поле
+	AuthorId    string // идентификатор учетной записи автора (в кеше старых версий может отсутствовать)
 	Email       string
 	AddedRows   int       // обязательное поле
 	DeletedRows int       // обязательное поле
 	Date        time.Time // обязательное поле
 	Message     string
 	Hash        string
+	Files       []string // пути измененных файлов (в кеше старых версий может отсутствовать)
+	Team        string   // команда автора на дату коммита (заполняется по составу команд при обработке коммитов)
 }
@@ -4,7 +4,11 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"path/filepath"
+	"strings"
+	"time"
 
 	bolt "go.etcd.io/bbolt"
 )
@@ -14,14 +18,47 @@ type Store interface {
 	Close() error
 	FindOne(id int, projectName string) (*repointerface.Commit, error)
 	Write(commit *repointerface.Commit, projectName string) error
+	// Возвращает названия всех проектов, для которых есть кеш
+	Projects() ([]string, error)
+	// Возвращает итератор по всем закешированным коммитам проекта в порядке их id
+	CommitIterator(projectName string) (repointerface.CommitIterator, error)
+	// Возвращает закешированное содержимое файла в версии ченджсета
+	FindContent(path string, changeset int) (string, error)
+	WriteContent(path string, changeset int, content string) error
+	// Проверяет целостность файла кеша
+	Check() error
+	// Возвращает контрольную точку синхронизации проекта: id ченджсета, до которого (включительно)
+	// все ченджсеты уже загружены в кеш. Если синхронизация не выполнялась, возвращает 0
+	Checkpoint(projectName string) (int, error)
+	WriteCheckpoint(projectName string, changeset int) error
 }
 
+// Сколько ждать, пока файл кеша освободит другой процесс (например, запущенный экспортер)
+const openTimeout = time.Second
+
+// Служебные бакеты начинаются с "_": в Azure DevOps название проекта не может начинаться с подчеркивания
+const (
+	contentsBucket    = "_contents"
+	checkpointsBucket = "_checkpoints"
+)
+
 type DB struct {
 	DB *bolt.DB
 }
 
 func NewStore() (Store, error) {
-	db, err := bolt.Open("assets.db", 0600, nil)
+	return NewNamespacedStore("", "")
+}
+
+// NewNamespacedStore открывает в каталоге dir отдельный кеш для пространства имен (например, профиля подключения),
+// чтобы проекты с одинаковыми названиями в разных организациях не смешивались.
+// Пустое пространство имен соответствует общему кешу assets.db, пустой каталог - текущему каталогу
+func NewNamespacedStore(dir, namespace string) (Store, error) {
+	path := filepath.Join(dir, FileName(namespace))
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("файл кеша %s занят другим процессом", path)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -29,6 +66,14 @@ func NewStore() (Store, error) {
 	return &DB{DB: db}, nil
 }
 
+// FileName возвращает имя файла кеша для пространства имен
+func FileName(namespace string) string {
+	if namespace == "" {
+		return "assets.db"
+	}
+	return "assets-" + namespace + ".db"
+}
+
 func (db *DB) InitProject(projectName string) error {
 	return db.DB.Update(func(tx *bolt.Tx) error {
 		_, err := tx.CreateBucketIfNotExists([]byte(projectName))
@@ -63,6 +108,13 @@ func (db *DB) FindOne(id int, projectName string) (*repointerface.Commit, error)
 	return res, nil
 }
 
+// Stale возвращает true для коммита, сохраненного в кеш старой версией без списка измененных файлов.
+// Такой коммит нужно загрузить заново, иначе фильтр по пути и группировка по файлам его не увидят
+func Stale(commit *repointerface.Commit) bool {
+	return commit.Files == nil
+}
+
+// Write сохраняет коммит. Уже сохраненный коммит не перезаписывается, если только он не устарел (см. Stale)
 func (db *DB) Write(commit *repointerface.Commit, projectName string) error {
 	err := db.DB.Update(func(tx *bolt.Tx) error {
 		b, err := tx.CreateBucketIfNotExists([]byte(projectName))
@@ -72,7 +124,10 @@ func (db *DB) Write(commit *repointerface.Commit, projectName string) error {
 		v := b.Get(itob(commit.Id))
 
 		if v != nil {
-			return nil
+			cached := &repointerface.Commit{}
+			if err := json.Unmarshal(v, cached); err == nil && !Stale(cached) {
+				return nil
+			}
 		}
 
 		buf, err := json.Marshal(commit)
@@ -89,8 +144,135 @@ func (db *DB) Write(commit *repointerface.Commit, projectName string) error {
 	return nil
 }
 
+func (db *DB) Projects() ([]string, error) {
+	projects := []string{}
+	err := db.DB.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if !strings.HasPrefix(string(name), "_") {
+				projects = append(projects, string(name))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (db *DB) CommitIterator(projectName string) (repointerface.CommitIterator, error) {
+	commits := []repointerface.Commit{}
+	err := db.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(projectName))
+		if b == nil {
+			return errors.New("no project")
+		}
+		return b.ForEach(func(_, v []byte) error {
+			commit := repointerface.Commit{}
+			if err := json.Unmarshal(v, &commit); err != nil {
+				return err
+			}
+			commits = append(commits, commit)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &iterator{commits: commits}, nil
+}
+
+func (db *DB) FindContent(path string, changeset int) (string, error) {
+	var content string
+	err := db.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(contentsBucket))
+		if b == nil {
+			return errors.New("no item")
+		}
+		v := b.Get(contentKey(path, changeset))
+		if v == nil {
+			return errors.New("no item")
+		}
+		content = string(v)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return content, nil
+}
+
+func (db *DB) WriteContent(path string, changeset int, content string) error {
+	return db.DB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(contentsBucket))
+		if err != nil {
+			return err
+		}
+		return b.Put(contentKey(path, changeset), []byte(content))
+	})
+}
+
+func (db *DB) Check() error {
+	return db.DB.View(func(tx *bolt.Tx) error {
+		// Канал нужно вычитать до конца, иначе проверка не завершит транзакцию
+		var first error
+		for err := range tx.Check() {
+			if first == nil {
+				first = err
+			}
+		}
+		return first
+	})
+}
+
+func (db *DB) Checkpoint(projectName string) (int, error) {
+	checkpoint := 0
+	err := db.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(checkpointsBucket))
+		if b == nil {
+			return nil
+		}
+		if v := b.Get([]byte(projectName)); v != nil {
+			checkpoint = btoi(v)
+		}
+		return nil
+	})
+	return checkpoint, err
+}
+
+func (db *DB) WriteCheckpoint(projectName string, changeset int) error {
+	return db.DB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(checkpointsBucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(projectName), itob(changeset))
+	})
+}
+
+func contentKey(path string, changeset int) []byte {
+	return append(itob(changeset), []byte(path)...)
+}
+
+type iterator struct {
+	index   int
+	commits []repointerface.Commit
+}
+
+func (i *iterator) Next() (*repointerface.Commit, error) {
+	if i.index < len(i.commits) {
+		i.index++
+		return &i.commits[i.index-1], nil
+	}
+	return nil, repointerface.ErrNoMoreItems
+}
+
 func itob(v int) []byte {
 	b := make([]byte, 8)
 	binary.BigEndian.PutUint64(b, uint64(v))
 	return b
 }
+
+func btoi(b []byte) int {
+	return int(binary.BigEndian.Uint64(b))
+}
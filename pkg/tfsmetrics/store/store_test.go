@@ -2,7 +2,9 @@ package store
 
 import (
 	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -110,3 +112,153 @@ func TestDB_Write(t *testing.T) {
 		})
 	}
 }
+
+func TestDB_Write_stale(t *testing.T) {
+	store, err := TestStore()
+	require.NoError(t, err)
+	defer store.Close()
+	defer func() {
+		os.Remove(store.DB.Path())
+	}()
+
+	// Коммит без списка файлов (кеш старой версии) перезаписывается, остальные - нет
+	require.NoError(t, store.Write(&repointerface.Commit{Id: 1, Author: "old"}, "project"))
+	assert.True(t, Stale(&repointerface.Commit{Id: 1}))
+	require.NoError(t, store.Write(&repointerface.Commit{Id: 1, Author: "ivan", Files: []string{}}, "project"))
+	require.NoError(t, store.Write(&repointerface.Commit{Id: 1, Author: "petr", Files: []string{"$/a.go"}}, "project"))
+	commit, err := store.FindOne(1, "project")
+	require.NoError(t, err)
+	assert.Equal(t, "ivan", commit.Author)
+	assert.False(t, Stale(commit))
+}
+
+func TestDB_CommitIterator(t *testing.T) {
+	store, err := TestStore()
+	require.NoError(t, err)
+	defer store.Close()
+	defer func() {
+		os.Remove(store.DB.Path())
+	}()
+	commits := []repointerface.Commit{
+		{Id: 2, Author: "ivan", Date: time.Time{}},
+		{Id: 1, Author: "pety", Date: time.Time{}},
+	}
+	for i := range commits {
+		require.NoError(t, store.Write(&commits[i], "project"))
+	}
+	require.NoError(t, store.InitProject("empty"))
+
+	projects, err := store.Projects()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"empty", "project"}, projects)
+
+	iter, err := store.CommitIterator("project")
+	require.NoError(t, err)
+	for _, want := range []repointerface.Commit{commits[1], commits[0]} {
+		c, err := iter.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, &want, c)
+	}
+	_, err = iter.Next()
+	assert.ErrorIs(t, err, repointerface.ErrNoMoreItems)
+
+	_, err = store.CommitIterator("unknown")
+	assert.Error(t, err)
+}
+
+func TestDB_Content(t *testing.T) {
+	store, err := TestStore()
+	require.NoError(t, err)
+	defer store.Close()
+	defer func() {
+		os.Remove(store.DB.Path())
+	}()
+
+	_, err = store.FindContent("$/project/main.go", 1)
+	assert.Error(t, err)
+
+	require.NoError(t, store.WriteContent("$/project/main.go", 1, "first"))
+	require.NoError(t, store.WriteContent("$/project/main.go", 2, "second"))
+	content, err := store.FindContent("$/project/main.go", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", content)
+
+	// служебный бакет не считается проектом
+	projects, err := store.Projects()
+	assert.NoError(t, err)
+	assert.Empty(t, projects)
+}
+
+func TestNewNamespacedStore(t *testing.T) {
+	assert.Equal(t, "assets.db", FileName(""))
+	assert.Equal(t, "assets-work.db", FileName("work"))
+
+	dir, err := ioutil.TempDir("", "store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	work, err := NewNamespacedStore(dir, "work")
+	require.NoError(t, err)
+	defer work.Close()
+	home, err := NewNamespacedStore(dir, "home")
+	require.NoError(t, err)
+	defer home.Close()
+	assert.FileExists(t, filepath.Join(dir, "assets-work.db"))
+
+	require.NoError(t, work.Write(&repointerface.Commit{Id: 1}, "project"))
+	projects, err := home.Projects()
+	assert.NoError(t, err)
+	assert.Empty(t, projects)
+}
+
+func TestDB_Check(t *testing.T) {
+	store, err := TestStore()
+	require.NoError(t, err)
+	defer store.Close()
+	defer func() {
+		os.Remove(store.DB.Path())
+	}()
+
+	require.NoError(t, store.Write(&repointerface.Commit{Id: 1}, "project"))
+	assert.NoError(t, store.Check())
+}
+
+func TestDB_Checkpoint(t *testing.T) {
+	store, err := TestStore()
+	require.NoError(t, err)
+	defer store.Close()
+	defer func() {
+		os.Remove(store.DB.Path())
+	}()
+
+	checkpoint, err := store.Checkpoint("project")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, checkpoint)
+
+	require.NoError(t, store.WriteCheckpoint("project", 42))
+	require.NoError(t, store.WriteCheckpoint("other", 7))
+	checkpoint, err = store.Checkpoint("project")
+	assert.NoError(t, err)
+	assert.Equal(t, 42, checkpoint)
+
+	// Контрольные точки хранятся в служебном бакете и не считаются проектом
+	projects, err := store.Projects()
+	assert.NoError(t, err)
+	assert.Empty(t, projects)
+}
+
+func TestNewNamespacedStore_locked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	first, err := NewNamespacedStore(dir, "locked")
+	require.NoError(t, err)
+	defer first.Close()
+
+	second, err := NewNamespacedStore(dir, "locked")
+	assert.Nil(t, second)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "занят другим процессом")
+	}
+}
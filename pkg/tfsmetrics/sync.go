@@ -0,0 +1,131 @@
+package tfsmetrics
+
+import (
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"go-marathon-team-3/pkg/tfsmetrics/store"
+	"sort"
+	"time"
+)
+
+// Как часто (в ченджсетах) сохраняется контрольная точка синхронизации
+const checkpointInterval = 10
+
+// Состояние синхронизации проекта, которое передается в ProgressFunc
+type SyncProgress struct {
+	Project string
+	// Обработано ченджсетов, включая загруженные в прошлых запусках
+	Done  int
+	Total int
+	// Сколько ченджсетов пропущено по контрольной точке прошлого запуска
+	Resumed int
+	// Сколько ченджсетов загружено из Azure в этом запуске (остальные уже были в кеше)
+	Fetched  int
+	APICalls int64
+	Elapsed  time.Duration
+}
+
+// ETA оценивает оставшееся время по скорости обработки ченджсетов в этом запуске
+func (p *SyncProgress) ETA() time.Duration {
+	processed := p.Done - p.Resumed
+	if processed <= 0 || p.Done >= p.Total {
+		return 0
+	}
+	return p.Elapsed / time.Duration(processed) * time.Duration(p.Total-p.Done)
+}
+
+// Вызывается в начале синхронизации и после обработки каждого ченджсета
+type ProgressFunc func(progress SyncProgress)
+
+type Syncer interface {
+	// Загружает в кеш все ченджсеты проекта, которых там еще нет
+	Sync(nameOfProject string) (SyncProgress, error)
+}
+
+type syncer struct {
+	azure    azure.AzureInterface
+	store    store.Store
+	progress ProgressFunc
+}
+
+// NewSyncer создает синхронизацию кеша с Azure. Ченджсеты обрабатываются по возрастанию id,
+// а id последнего обработанного сохраняется в store как контрольная точка: прерванная
+// синхронизация продолжается с нее, а повторная загружает только новые ченджсеты.
+// progress может быть nil
+func NewSyncer(azure azure.AzureInterface, store store.Store, progress ProgressFunc) Syncer {
+	if progress == nil {
+		progress = func(SyncProgress) {}
+	}
+	return &syncer{
+		azure:    azure,
+		store:    store,
+		progress: progress,
+	}
+}
+
+func (s *syncer) Sync(nameOfProject string) (SyncProgress, error) {
+	state := SyncProgress{Project: nameOfProject}
+	start := time.Now()
+	startCalls := s.azure.Azure().Requests()
+	update := func() {
+		state.Elapsed = time.Since(start)
+		state.APICalls = s.azure.Azure().Requests() - startCalls
+		s.progress(state)
+	}
+
+	if err := s.store.InitProject(nameOfProject); err != nil {
+		return state, err
+	}
+	if err := s.azure.TfvcClientConnection(); err != nil {
+		return state, err
+	}
+	checkpoint, err := s.store.Checkpoint(nameOfProject)
+	if err != nil {
+		return state, err
+	}
+	changeSets, err := s.azure.GetChangesets(nameOfProject)
+	if err != nil {
+		return state, err
+	}
+	ids := make([]int, 0, len(changeSets))
+	for _, id := range changeSets {
+		ids = append(ids, *id)
+	}
+	sort.Ints(ids)
+	state.Total = len(ids)
+	pending := []int{}
+	for _, id := range ids {
+		if id <= checkpoint {
+			state.Done++
+			state.Resumed++
+		} else {
+			pending = append(pending, id)
+		}
+	}
+	update()
+
+	for i, id := range pending {
+		if cached, err := s.store.FindOne(id, nameOfProject); err != nil || store.Stale(cached) {
+			changeSet, err := s.azure.GetChangesetChanges(&id, nameOfProject)
+			if err == nil {
+				err = s.store.Write(commitFromChangeSet(changeSet), nameOfProject)
+			}
+			if err != nil {
+				if i > 0 {
+					// Сохраняем то, что успели загрузить, ошибка записи контрольной точки здесь вторична
+					_ = s.store.WriteCheckpoint(nameOfProject, pending[i-1])
+				}
+				return state, fmt.Errorf("ченджсет %d: %w", id, err)
+			}
+			state.Fetched++
+		}
+		state.Done++
+		if (i+1)%checkpointInterval == 0 || i == len(pending)-1 {
+			if err := s.store.WriteCheckpoint(nameOfProject, id); err != nil {
+				return state, err
+			}
+		}
+		update()
+	}
+	return state, nil
+}
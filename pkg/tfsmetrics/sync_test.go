@@ -0,0 +1,113 @@
+package tfsmetrics
+
+import (
+	"errors"
+	"go-marathon-team-3/pkg/tfsmetrics/azure"
+	"go-marathon-team-3/pkg/tfsmetrics/mock/mock_azure"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"go-marathon-team-3/pkg/tfsmetrics/store"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func changesetIds(ids ...int) []*int {
+	res := make([]*int, len(ids))
+	for i := range ids {
+		res[i] = &ids[i]
+	}
+	return res
+}
+
+func expectChangeset(mockedAzure *mock_azure.MockAzureInterface, id int, project string) *gomock.Call {
+	return mockedAzure.EXPECT().
+		GetChangesetChanges(gomock.Eq(&id), project).
+		Return(&azure.ChangeSet{ProjectName: project, Id: id, Author: "Ivan"}, nil)
+}
+
+func Test_syncer_Sync(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockedAzure := mock_azure.NewMockAzureInterface(ctrl)
+	mockedAzure.EXPECT().Azure().Return(&azure.Azure{}).AnyTimes()
+	mockedAzure.EXPECT().TfvcClientConnection().Return(nil).AnyTimes()
+
+	db, err := store.TestStore()
+	require.NoError(t, err)
+	defer db.Close()
+	defer func() {
+		os.Remove(db.DB.Path())
+	}()
+	project := "project"
+
+	// Первый запуск прерывается на ченджсете 3
+	mockedAzure.EXPECT().GetChangesets(project).Return(changesetIds(3, 2, 1), nil)
+	expectChangeset(mockedAzure, 1, project)
+	expectChangeset(mockedAzure, 2, project)
+	mockedAzure.EXPECT().GetChangesetChanges(gomock.Any(), project).Return(nil, errors.New("timeout"))
+
+	var updates []SyncProgress
+	syncer := NewSyncer(mockedAzure, db, func(progress SyncProgress) {
+		updates = append(updates, progress)
+	})
+	state, err := syncer.Sync(project)
+	assert.EqualError(t, err, "ченджсет 3: timeout")
+	assert.Equal(t, 2, state.Done)
+	require.Len(t, updates, 3)
+	assert.Equal(t, project, updates[0].Project)
+	assert.Equal(t, 0, updates[0].Done)
+	assert.Equal(t, 3, updates[0].Total)
+	assert.Equal(t, 2, updates[2].Done)
+	checkpoint, err := db.Checkpoint(project)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, checkpoint)
+
+	// Повторный запуск продолжает с контрольной точки
+	mockedAzure.EXPECT().GetChangesets(project).Return(changesetIds(3, 2, 1), nil)
+	expectChangeset(mockedAzure, 3, project)
+	state, err = NewSyncer(mockedAzure, db, nil).Sync(project)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, state.Done)
+	assert.Equal(t, 3, state.Total)
+	assert.Equal(t, 2, state.Resumed)
+	assert.Equal(t, 1, state.Fetched)
+	checkpoint, err = db.Checkpoint(project)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, checkpoint)
+
+	// Следующая синхронизация загружает только новые ченджсеты, которых нет в кеше
+	require.NoError(t, db.Write(&repointerface.Commit{Id: 5, Author: "Petr", Files: []string{}}, project))
+	mockedAzure.EXPECT().GetChangesets(project).Return(changesetIds(5, 4, 3, 2, 1), nil)
+	expectChangeset(mockedAzure, 4, project)
+	state, err = NewSyncer(mockedAzure, db, nil).Sync(project)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, state.Done)
+	assert.Equal(t, 3, state.Resumed)
+	assert.Equal(t, 1, state.Fetched)
+
+	commit, err := db.FindOne(4, project)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ivan", commit.Author)
+}
+
+func TestSyncProgress_ETA(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress SyncProgress
+		want     time.Duration
+	}{
+		{"not started", SyncProgress{Total: 10}, 0},
+		{"half", SyncProgress{Done: 5, Total: 10, Elapsed: 10 * time.Second}, 10 * time.Second},
+		{"resumed", SyncProgress{Done: 6, Resumed: 4, Total: 10, Elapsed: 4 * time.Second}, 8 * time.Second},
+		{"done", SyncProgress{Done: 10, Total: 10, Elapsed: time.Minute}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.progress.ETA())
+		})
+	}
+}
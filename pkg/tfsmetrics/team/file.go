@@ -0,0 +1,136 @@
+package team
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go-marathon-team-3/pkg/tfsmetrics/filter"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadMemberships читает состав команд из файла в формате YAML или CSV (по расширению .csv)
+func ReadMemberships(path string) ([]Membership, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var memberships []Membership
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		memberships, err = ParseCSV(file)
+	} else {
+		memberships, err = ParseYAML(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("состав команд %s: %w", path, err)
+	}
+	return memberships, nil
+}
+
+// ParseCSV разбирает состав команд в формате CSV с колонками member, team, since, until
+// (since и until можно не заполнять). Первая строка с заголовком member пропускается:
+//
+//	member,team,since,until
+//	ivanov@corp.ru,Backend,,2021-06-30
+//	ivanov@corp.ru,Frontend,2021-07-01,
+func ParseCSV(r io.Reader) ([]Membership, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	memberships := []Membership{}
+	for k, record := range records {
+		if k == 0 && len(record) > 0 && strings.EqualFold(record[0], "member") {
+			continue
+		}
+		if len(record) < 2 || len(record) > 4 {
+			return nil, fmt.Errorf("строка %d: ожидаются колонки member, team, since, until", k+1)
+		}
+		for len(record) < 4 {
+			record = append(record, "")
+		}
+		membership, err := newMembership(record[0], record[1], record[2], record[3])
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", k+1, err)
+		}
+		memberships = append(memberships, membership)
+	}
+	return memberships, nil
+}
+
+type yamlMember struct {
+	Member string `yaml:"member"`
+	Since  string `yaml:"since"`
+	Until  string `yaml:"until"`
+}
+
+// Участника можно задать строкой, если он был в команде все время
+func (m *yamlMember) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		m.Member = value.Value
+		return nil
+	}
+	type plain yamlMember
+	return value.Decode((*plain)(m))
+}
+
+// ParseYAML разбирает состав команд в формате YAML:
+//
+//	teams:
+//	  Backend:
+//	    - petrov@corp.ru
+//	    - member: ivanov@corp.ru
+//	      until: 2021-06-30
+//	  Frontend:
+//	    - member: ivanov@corp.ru
+//	      since: 2021-07-01
+func ParseYAML(r io.Reader) ([]Membership, error) {
+	var file struct {
+		Teams map[string][]yamlMember `yaml:"teams"`
+	}
+	err := yaml.NewDecoder(r).Decode(&file)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	memberships := []Membership{}
+	for team, members := range file.Teams {
+		for _, member := range members {
+			membership, err := newMembership(member.Member, team, member.Since, member.Until)
+			if err != nil {
+				return nil, fmt.Errorf("команда %s: %w", team, err)
+			}
+			memberships = append(memberships, membership)
+		}
+	}
+	return memberships, nil
+}
+
+func newMembership(member, team, since, until string) (Membership, error) {
+	membership := Membership{Member: strings.TrimSpace(member), Team: strings.TrimSpace(team)}
+	if membership.Member == "" || membership.Team == "" {
+		return membership, errors.New("не указан участник или команда")
+	}
+	var err error
+	if since = strings.TrimSpace(since); since != "" {
+		if membership.Since, err = filter.ParseDate(since, false); err != nil {
+			return membership, fmt.Errorf("неверная дата since: %s", since)
+		}
+	}
+	if until = strings.TrimSpace(until); until != "" {
+		if membership.Until, err = filter.ParseDate(until, true); err != nil {
+			return membership, fmt.Errorf("неверная дата until: %s", until)
+		}
+	}
+	if !membership.Since.IsZero() && !membership.Until.IsZero() && membership.Until.Before(membership.Since) {
+		return membership, fmt.Errorf("участие %s в команде %s заканчивается раньше, чем начинается", membership.Member, membership.Team)
+	}
+	return membership, nil
+}
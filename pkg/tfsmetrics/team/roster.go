@@ -0,0 +1,101 @@
+// Package team определяет команду автора коммита по составу команд (roster). Состав задает, в какой
+// команде и в какие даты был человек, поэтому коммиты автора, перешедшего в другую команду,
+// учитываются в той команде, в которой он был на дату коммита
+package team
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Участие человека в команде
+type Membership struct {
+	// Идентификатор учетной записи, почта или имя автора (без учета регистра), как в файле псевдонимов.
+	// По имени, как и в псевдонимах, определяются только коммиты без идентификатора учетной записи
+	Member string
+	Team   string
+	// Границы участия включительно. Нулевое время - без ограничения
+	Since time.Time
+	Until time.Time
+}
+
+func (m *Membership) contains(date time.Time) bool {
+	return (m.Since.IsZero() || !date.Before(m.Since)) && (m.Until.IsZero() || !date.After(m.Until))
+}
+
+type Roster interface {
+	// Возвращает команду автора на дату коммита или пустую строку, если автора нет в составе
+	Team(commit *repointerface.Commit) string
+	// Оборачивает итератор, заполняя команду коммитов
+	Apply(it repointerface.CommitIterator) repointerface.CommitIterator
+	// Возвращает названия всех команд по алфавиту
+	Teams() []string
+}
+
+type roster struct {
+	members map[string][]Membership
+}
+
+// NewRoster создает состав команд. Если периоды участия одного человека пересекаются,
+// выбирается участие, начавшееся позже
+func NewRoster(memberships []Membership) Roster {
+	r := &roster{members: make(map[string][]Membership)}
+	for _, membership := range memberships {
+		member := strings.ToLower(membership.Member)
+		r.members[member] = append(r.members[member], membership)
+	}
+	for _, memberships := range r.members {
+		sort.SliceStable(memberships, func(i, j int) bool {
+			return memberships[i].Since.After(memberships[j].Since)
+		})
+	}
+	return r
+}
+
+func (r *roster) Team(commit *repointerface.Commit) string {
+	members := []string{commit.AuthorId, commit.Email}
+	// У разных людей может быть одинаковое имя, поэтому имя используется, только если идентификатора нет
+	if commit.AuthorId == "" {
+		members = append(members, commit.Author)
+	}
+	for _, member := range members {
+		if member == "" {
+			continue
+		}
+		for _, membership := range r.members[strings.ToLower(member)] {
+			if membership.contains(commit.Date) {
+				return membership.Team
+			}
+		}
+	}
+	return ""
+}
+
+func (r *roster) Apply(it repointerface.CommitIterator) repointerface.CommitIterator {
+	if len(r.members) == 0 {
+		return it
+	}
+	return combinator.Map(it, func(commit *repointerface.Commit) *repointerface.Commit {
+		res := *commit
+		res.Team = r.Team(commit)
+		return &res
+	})
+}
+
+func (r *roster) Teams() []string {
+	seen := map[string]bool{}
+	teams := []string{}
+	for _, memberships := range r.members {
+		for _, membership := range memberships {
+			if !seen[membership.Team] {
+				seen[membership.Team] = true
+				teams = append(teams, membership.Team)
+			}
+		}
+	}
+	sort.Strings(teams)
+	return teams
+}
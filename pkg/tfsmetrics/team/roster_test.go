@@ -0,0 +1,112 @@
+package team
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(value string) time.Time {
+	date, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return date
+}
+
+const rosterYAML = `teams:
+  Backend:
+    - petrov@corp.ru
+    - member: ivanov@corp.ru
+      until: 2021-06-30
+  Frontend:
+    - member: IVANOV@corp.ru
+      since: 2021-07-01
+    - member: id-3
+`
+
+const rosterCSV = `member,team,since,until
+petrov@corp.ru,Backend,,
+ivanov@corp.ru,Backend,,2021-06-30
+IVANOV@corp.ru, Frontend,2021-07-01,
+id-3,Frontend
+`
+
+func TestRoster(t *testing.T) {
+	fromYAML, err := ParseYAML(strings.NewReader(rosterYAML))
+	require.NoError(t, err)
+	fromCSV, err := ParseCSV(strings.NewReader(rosterCSV))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, fromYAML, fromCSV)
+
+	roster := NewRoster(fromCSV)
+	assert.Equal(t, []string{"Backend", "Frontend"}, roster.Teams())
+	tests := []struct {
+		name   string
+		commit repointerface.Commit
+		want   string
+	}{
+		{name: "always in team", commit: repointerface.Commit{Email: "petrov@corp.ru", Date: day("2020-01-01 10:00")}, want: "Backend"},
+		{name: "before move", commit: repointerface.Commit{Email: "ivanov@corp.ru", Date: day("2021-06-30 23:00")}, want: "Backend"},
+		{name: "after move", commit: repointerface.Commit{Email: "Ivanov@Corp.ru", Date: day("2021-07-01 09:00")}, want: "Frontend"},
+		{name: "by id", commit: repointerface.Commit{AuthorId: "id-3", Email: "sidorov@corp.ru"}, want: "Frontend"},
+		{name: "not in roster", commit: repointerface.Commit{Email: "guest@corp.ru"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, roster.Team(&tt.commit))
+		})
+	}
+
+	all, err := combinator.Collect(roster.Apply(combinator.FromSlice([]*repointerface.Commit{&tests[0].commit})))
+	require.NoError(t, err)
+	assert.Equal(t, "Backend", all[0].Team)
+	assert.Empty(t, tests[0].commit.Team, "исходный коммит не должен меняться")
+}
+
+func TestRoster_byName(t *testing.T) {
+	roster := NewRoster([]Membership{{Member: "Ivan Ivanov", Team: "Backend"}})
+	// По имени определяются только коммиты без идентификатора учетной записи
+	assert.Equal(t, "Backend", roster.Team(&repointerface.Commit{Author: "ivan ivanov"}))
+	assert.Empty(t, roster.Team(&repointerface.Commit{Author: "Ivan Ivanov", AuthorId: "id-namesake"}))
+}
+
+func TestReadMemberships(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "roster.csv")
+	require.NoError(t, ioutil.WriteFile(path, []byte(rosterCSV), 0600))
+	memberships, err := ReadMemberships(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Backend", "Frontend"}, NewRoster(memberships).Teams())
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		err     string
+	}{
+		{name: "wrong date", file: "roster.csv", content: "ivanov,Backend,01.07.2021",
+			err: "строка 1: неверная дата since: 01.07.2021"},
+		{name: "no team", file: "roster.csv", content: "ivanov",
+			err: "строка 1: ожидаются колонки member, team, since, until"},
+		{name: "wrong range", file: "roster.yml", content: "teams:\n  Backend:\n    - {member: ivanov, since: 2021-07-01, until: 2021-01-01}\n",
+			err: "команда Backend: участие ivanov в команде Backend заканчивается раньше, чем начинается"},
+		{name: "broken yaml", file: "roster.yaml", content: "teams: [", err: "yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			require.NoError(t, ioutil.WriteFile(path, []byte(tt.content), 0600))
+			_, err := ReadMemberships(path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+// Package worktime определяет по рабочему календарю (рабочие дни недели, рабочие часы, праздники
+// и перенесенные рабочие дни), сделан ли коммит в рабочее время, и строит тепловую карту коммитов
+// по дням недели и часам с долей коммитов вне рабочего времени
+package worktime
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const dateLayout = "2006-01-02"
+
+// Рабочий календарь
+type Calendar struct {
+	// Часовой пояс, в котором определяются дни и часы коммитов
+	Location *time.Location
+	// Рабочие дни недели
+	Weekdays map[time.Weekday]bool
+	// Рабочие часы: с Start (включительно) до End (не включая), целыми часами
+	Start int
+	End   int
+	// Праздничные дни (2006-01-02), нерабочие даже в рабочий день недели
+	Holidays map[string]bool
+	// Перенесенные рабочие дни (2006-01-02), рабочие даже в выходной день недели
+	Workdays map[string]bool
+}
+
+// DefaultCalendar возвращает календарь с рабочими днями с понедельника по пятницу
+// и рабочими часами с 9 до 18 в локальном часовом поясе
+func DefaultCalendar() *Calendar {
+	return &Calendar{
+		Location: time.Local,
+		Weekdays: map[time.Weekday]bool{time.Monday: true, time.Tuesday: true, time.Wednesday: true,
+			time.Thursday: true, time.Friday: true},
+		Start:    9,
+		End:      18,
+		Holidays: map[string]bool{},
+		Workdays: map[string]bool{},
+	}
+}
+
+// WorkingDay возвращает true, если день даты (в часовом поясе календаря) рабочий
+func (c *Calendar) WorkingDay(date time.Time) bool {
+	day := date.In(c.Location).Format(dateLayout)
+	if c.Workdays[day] {
+		return true
+	}
+	return c.Weekdays[date.In(c.Location).Weekday()] && !c.Holidays[day]
+}
+
+// Working возвращает true, если дата попадает в рабочие часы рабочего дня
+func (c *Calendar) Working(date time.Time) bool {
+	return c.WorkingDay(date) && c.workingHour(date.In(c.Location).Hour())
+}
+
+func (c *Calendar) workingHour(hour int) bool {
+	return hour >= c.Start && hour < c.End
+}
+
+// Названия дней недели в файле календаря
+var weekdayNames = map[string]time.Weekday{
+	"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+	"пн": time.Monday, "вт": time.Tuesday, "ср": time.Wednesday, "чт": time.Thursday,
+	"пт": time.Friday, "сб": time.Saturday, "вс": time.Sunday,
+}
+
+// ReadCalendar читает рабочий календарь из файла в формате YAML (см. ParseCalendar)
+func ReadCalendar(path string) (*Calendar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	calendar, err := ParseCalendar(file)
+	if err != nil {
+		return nil, fmt.Errorf("рабочий календарь %s: %w", path, err)
+	}
+	return calendar, nil
+}
+
+// ParseCalendar разбирает рабочий календарь в формате YAML. Незаданные параметры берутся из DefaultCalendar:
+//
+//	timezone: Europe/Moscow
+//	weekdays: [mon, tue, wed, thu, fri]
+//	hours: 09-18
+//	holidays: [2021-11-04, 2021-12-31]
+//	workdays: [2021-02-20]
+func ParseCalendar(r io.Reader) (*Calendar, error) {
+	var file struct {
+		Timezone string   `yaml:"timezone"`
+		Weekdays []string `yaml:"weekdays"`
+		Hours    string   `yaml:"hours"`
+		Holidays []string `yaml:"holidays"`
+		Workdays []string `yaml:"workdays"`
+	}
+	err := yaml.NewDecoder(r).Decode(&file)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	calendar := DefaultCalendar()
+	if file.Timezone != "" {
+		if calendar.Location, err = time.LoadLocation(file.Timezone); err != nil {
+			return nil, fmt.Errorf("неизвестный часовой пояс '%s'", file.Timezone)
+		}
+	}
+	if len(file.Weekdays) > 0 {
+		calendar.Weekdays = make(map[time.Weekday]bool)
+		for _, name := range file.Weekdays {
+			weekday, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return nil, fmt.Errorf("неизвестный день недели '%s', ожидается mon, tue, wed, thu, fri, sat или sun", name)
+			}
+			calendar.Weekdays[weekday] = true
+		}
+	}
+	if file.Hours != "" {
+		if calendar.Start, calendar.End, err = parseHours(file.Hours); err != nil {
+			return nil, err
+		}
+	}
+	if calendar.Holidays, err = parseDates("holidays", file.Holidays); err != nil {
+		return nil, err
+	}
+	if calendar.Workdays, err = parseDates("workdays", file.Workdays); err != nil {
+		return nil, err
+	}
+	return calendar, nil
+}
+
+// parseHours разбирает рабочие часы вида 09-18 или 09:00-18:00
+func parseHours(value string) (int, int, error) {
+	invalid := fmt.Errorf("неверные рабочие часы '%s', ожидается, например, 09-18 или 09:00-18:00", value)
+	parts := strings.Split(value, "-")
+	if len(parts) != 2 {
+		return 0, 0, invalid
+	}
+	hours := [2]int{}
+	for i, part := range parts {
+		part = strings.TrimSuffix(strings.TrimSpace(part), ":00")
+		hour, err := strconv.Atoi(part)
+		if err != nil || hour < 0 || hour > 24 {
+			return 0, 0, invalid
+		}
+		hours[i] = hour
+	}
+	if hours[0] >= hours[1] {
+		return 0, 0, errors.New("рабочие часы должны заканчиваться позже, чем начинаются: " + value)
+	}
+	return hours[0], hours[1], nil
+}
+
+func parseDates(name string, values []string) (map[string]bool, error) {
+	dates := make(map[string]bool)
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if _, err := time.Parse(dateLayout, value); err != nil {
+			return nil, fmt.Errorf("%s: неверная дата '%s', ожидается 2006-01-02", name, value)
+		}
+		dates[value] = true
+	}
+	return dates, nil
+}
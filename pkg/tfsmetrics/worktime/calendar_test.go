@@ -0,0 +1,81 @@
+package worktime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCalendar = `
+timezone: Europe/Moscow
+weekdays: [mon, tue, wed, thu, fri]
+hours: "10:00-19:00"
+holidays: [2021-11-04]
+workdays: [2021-02-20]
+`
+
+func TestParseCalendar(t *testing.T) {
+	calendar, err := ParseCalendar(strings.NewReader(testCalendar))
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Moscow", calendar.Location.String())
+	assert.Equal(t, 10, calendar.Start)
+	assert.Equal(t, 19, calendar.End)
+
+	moscow := calendar.Location
+	tests := []struct {
+		name string
+		date time.Time
+		want bool
+	}{
+		{name: "working hours", date: time.Date(2021, 11, 3, 10, 0, 0, 0, moscow), want: true},
+		{name: "evening", date: time.Date(2021, 11, 3, 19, 0, 0, 0, moscow)},
+		{name: "holiday", date: time.Date(2021, 11, 4, 12, 0, 0, 0, moscow)},
+		{name: "weekend", date: time.Date(2021, 11, 6, 12, 0, 0, 0, moscow)},
+		{name: "working saturday", date: time.Date(2021, 2, 20, 12, 0, 0, 0, moscow), want: true},
+		// 06:00 UTC - 09:00 по Москве, еще не рабочее время
+		{name: "time zone", date: time.Date(2021, 11, 3, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, calendar.Working(tt.date))
+		})
+	}
+
+	// Пустой файл - календарь по умолчанию
+	calendar, err = ParseCalendar(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCalendar(), calendar)
+}
+
+func TestParseCalendar_errors(t *testing.T) {
+	for _, data := range []string{
+		"timezone: Mars/Olympus",
+		"weekdays: [monday]",
+		"hours: 18-09",
+		"hours: 09:30-18:00",
+		"hours: 9",
+		"holidays: [04.11.2021]",
+		"workdays: [2021-02-30]",
+	} {
+		_, err := ParseCalendar(strings.NewReader(data))
+		assert.Error(t, err, data)
+	}
+}
+
+func TestReadCalendar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.yaml")
+	_, err := ReadCalendar(path)
+	assert.True(t, os.IsNotExist(err))
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("weekdays: [пн, вт, ср, чт, пт, сб]\n"), 0600))
+	calendar, err := ReadCalendar(path)
+	require.NoError(t, err)
+	assert.Len(t, calendar.Weekdays, 6)
+	assert.True(t, calendar.WorkingDay(time.Date(2021, 11, 6, 12, 0, 0, 0, time.Local)))
+}
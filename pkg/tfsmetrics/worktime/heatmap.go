@@ -0,0 +1,94 @@
+package worktime
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/aggregate"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"strconv"
+	"time"
+)
+
+// Число коммитов по дням недели (0 - понедельник, 6 - воскресенье) и часам
+type Heatmap [7][24]int
+
+// Активность ряда коммитов (например, автора или команды)
+type Activity struct {
+	// Значения признаков Spec.By
+	Keys    []string
+	Heatmap Heatmap
+	Commits int
+	// Коммиты вне рабочего времени: в нерабочие часы рабочих дней, в выходные и праздники
+	Outside int
+	// Из них коммиты в выходные и праздники
+	DaysOff int
+}
+
+// OutsideShare возвращает долю коммитов вне рабочего времени (от 0 до 1)
+func (a *Activity) OutsideShare() float64 {
+	if a.Commits == 0 {
+		return 0
+	}
+	return float64(a.Outside) / float64(a.Commits)
+}
+
+// Параметры анализа
+type Spec struct {
+	// Признаки, по которым коммиты разделяются на ряды (например, автор или команда).
+	// Без них строится один ряд по всем коммитам
+	By       []aggregate.Dimension
+	Calendar *Calendar
+}
+
+type Analyzer interface {
+	// Add вычитывает коммиты проекта, как aggregate.Aggregator.Add
+	Add(project string, iterator repointerface.CommitIterator) error
+	// Result возвращает активность рядов, упорядоченных по ключам
+	Result() []*Activity
+}
+
+type analyzer struct {
+	spec       Spec
+	aggregator aggregate.Aggregator
+}
+
+func NewAnalyzer(spec Spec) Analyzer {
+	if spec.Calendar == nil {
+		spec.Calendar = DefaultCalendar()
+	}
+	// Коммиты группируются по дате и часу: праздники и переносы зависят от даты, а не только от дня недели
+	by := append(append([]aggregate.Dimension{}, spec.By...), aggregate.Day, aggregate.Hour)
+	return &analyzer{
+		spec:       spec,
+		aggregator: aggregate.New(aggregate.Spec{By: by, Location: spec.Calendar.Location}),
+	}
+}
+
+func (a *analyzer) Add(project string, iterator repointerface.CommitIterator) error {
+	return a.aggregator.Add(project, iterator)
+}
+
+func (a *analyzer) Result() []*Activity {
+	res := []*Activity{}
+	calendar := a.spec.Calendar
+	for _, groups := range aggregate.SplitSeries(a.aggregator.Result().Groups, len(a.spec.By)) {
+		activity := &Activity{Keys: groups.Keys}
+		for _, group := range groups.Groups {
+			n := len(group.Keys)
+			day, err := time.ParseInLocation(dateLayout, group.Keys[n-2], calendar.Location)
+			if err != nil {
+				continue
+			}
+			hour, _ := strconv.Atoi(group.Keys[n-1])
+			activity.Heatmap[(int(day.Weekday())+6)%7][hour] += group.Commits
+			activity.Commits += group.Commits
+			workingDay := calendar.WorkingDay(day)
+			if !workingDay {
+				activity.DaysOff += group.Commits
+			}
+			if !workingDay || !calendar.workingHour(hour) {
+				activity.Outside += group.Commits
+			}
+		}
+		res = append(res, activity)
+	}
+	return res
+}
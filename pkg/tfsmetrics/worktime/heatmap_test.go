@@ -0,0 +1,72 @@
+package worktime
+
+import (
+	"go-marathon-team-3/pkg/tfsmetrics/aggregate"
+	"go-marathon-team-3/pkg/tfsmetrics/combinator"
+	"go-marathon-team-3/pkg/tfsmetrics/repointerface"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzer(t *testing.T) {
+	calendar := DefaultCalendar()
+	calendar.Location = time.UTC
+	calendar.Holidays["2021-11-04"] = true
+	commits := []*repointerface.Commit{
+		// Среда, рабочее время
+		{Author: "Ivan", Date: time.Date(2021, 11, 3, 10, 30, 0, 0, time.UTC), Team: "Backend"},
+		{Author: "Ivan", Date: time.Date(2021, 11, 3, 10, 45, 0, 0, time.UTC), Team: "Backend"},
+		// Среда, ночь
+		{Author: "Ivan", Date: time.Date(2021, 11, 3, 23, 10, 0, 0, time.UTC), Team: "Backend"},
+		// Четверг, праздник
+		{Author: "Petr", Date: time.Date(2021, 11, 4, 12, 0, 0, 0, time.UTC), Team: "Backend"},
+		// Воскресенье
+		{Author: "Guest", Date: time.Date(2021, 11, 7, 15, 0, 0, 0, time.UTC)},
+	}
+	analyzer := NewAnalyzer(Spec{By: []aggregate.Dimension{aggregate.Team}, Calendar: calendar})
+	require.NoError(t, analyzer.Add("prj", combinator.FromSlice(commits)))
+	res := analyzer.Result()
+	require.Len(t, res, 2)
+
+	guest := res[0]
+	assert.Equal(t, []string{""}, guest.Keys)
+	assert.Equal(t, 1, guest.Heatmap[6][15])
+	assert.Equal(t, 1, guest.DaysOff)
+
+	backend := res[1]
+	assert.Equal(t, []string{"Backend"}, backend.Keys)
+	assert.Equal(t, 4, backend.Commits)
+	assert.Equal(t, 2, backend.Outside)
+	assert.Equal(t, 1, backend.DaysOff)
+	assert.Equal(t, 0.5, backend.OutsideShare())
+	assert.Equal(t, 2, backend.Heatmap[2][10])
+	assert.Equal(t, 1, backend.Heatmap[2][23])
+	assert.Equal(t, 1, backend.Heatmap[3][12])
+}
+
+func TestAnalyzer_location(t *testing.T) {
+	// Пятница 17:00 UTC - уже вечер пятницы в Москве, а 22:00 UTC - суббота
+	commits := []*repointerface.Commit{
+		{Author: "Ivan", Date: time.Date(2021, 11, 5, 17, 0, 0, 0, time.UTC)},
+		{Author: "Ivan", Date: time.Date(2021, 11, 5, 22, 0, 0, 0, time.UTC)},
+	}
+	calendar := DefaultCalendar()
+	calendar.Location = time.FixedZone("MSK", 3*60*60)
+	analyzer := NewAnalyzer(Spec{Calendar: calendar})
+	require.NoError(t, analyzer.Add("prj", combinator.FromSlice(commits)))
+	res := analyzer.Result()
+	require.Len(t, res, 1)
+	assert.Equal(t, []string{}, res[0].Keys)
+	assert.Equal(t, 1, res[0].Heatmap[4][20])
+	assert.Equal(t, 1, res[0].Heatmap[5][1])
+	assert.Equal(t, 2, res[0].Outside)
+	assert.Equal(t, 1, res[0].DaysOff)
+
+	// Без коммитов ряд все равно есть
+	empty := NewAnalyzer(Spec{Calendar: calendar}).Result()
+	require.Len(t, empty, 1)
+	assert.Equal(t, 0.0, empty[0].OutsideShare())
+}